@@ -0,0 +1,106 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"hireit-backend/models"
+	"hireit-backend/services"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type AccommodationController struct {
+	accommodationService services.AccommodationService
+}
+
+func NewAccommodationController(accommodationService services.AccommodationService) *AccommodationController {
+	return &AccommodationController{accommodationService: accommodationService}
+}
+
+// POST /api/accommodations
+func (ctrl *AccommodationController) CreateAccommodation(c *gin.Context) {
+	if !requireStaffRole(c) {
+		return
+	}
+
+	var req models.CreateAccommodationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, _ := c.Get("userID")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	accommodation, err := ctrl.accommodationService.CreateAccommodation(ctx, userID.(primitive.ObjectID).Hex(), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Accommodation recorded successfully", "accommodation": accommodation})
+}
+
+// GET /api/accommodations
+// Query params: candidate_id, assessment_id
+func (ctrl *AccommodationController) GetAccommodations(c *gin.Context) {
+	if !requireStaffRole(c) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	accommodations, err := ctrl.accommodationService.GetAccommodations(ctx, c.Query("candidate_id"), c.Query("assessment_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch accommodations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, accommodations)
+}
+
+// PUT /api/accommodations/:id
+func (ctrl *AccommodationController) UpdateAccommodation(c *gin.Context) {
+	if !requireStaffRole(c) {
+		return
+	}
+
+	var req models.UpdateAccommodationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := ctrl.accommodationService.UpdateAccommodation(ctx, c.Param("id"), &req); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update accommodation"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Accommodation updated successfully"})
+}
+
+// DELETE /api/accommodations/:id
+func (ctrl *AccommodationController) DeleteAccommodation(c *gin.Context) {
+	if !requireStaffRole(c) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := ctrl.accommodationService.DeleteAccommodation(ctx, c.Param("id")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete accommodation"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Accommodation deleted successfully"})
+}
@@ -2,8 +2,10 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"hireit-backend/models"
@@ -38,6 +40,10 @@ func (ctrl *AssessmentController) CreateAssessment(c *gin.Context) {
 	defer cancel()
 
 	id, err := ctrl.assessmentService.CreateAssessment(ctx, &assessment)
+	if errors.Is(err, services.ErrInvalidAdaptiveSetup) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create assessment"})
 		return
@@ -85,7 +91,11 @@ func (ctrl *AssessmentController) GetAssessmentByID(c *gin.Context) {
 			generatedQuestions, err := ctrl.submissionService.GetOrGenerateQuestions(ctx, id, candidateID)
 			if err == nil {
 				assessment.Questions = generatedQuestions
+			} else if errors.Is(err, services.ErrAssessmentClosed) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "This assessment is closed"})
+				return
 			}
+			ctrl.submissionService.ApplyCandidateTiming(ctx, assessment, candidateID)
 		}
 	}
 
@@ -104,6 +114,10 @@ func (ctrl *AssessmentController) UpdateAssessment(c *gin.Context) {
 	defer cancel()
 
 	err := ctrl.assessmentService.UpdateAssessment(ctx, id, &assessment)
+	if errors.Is(err, services.ErrInvalidAdaptiveSetup) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update assessment"})
 		return
@@ -131,7 +145,8 @@ func (ctrl *AssessmentController) GetSubmissions(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	subs, err := ctrl.submissionService.GetSubmissions(ctx, id)
+	// ?attempts=all returns every attempt instead of the one counted by the retake policy
+	subs, err := ctrl.submissionService.GetSubmissions(ctx, id, c.Query("attempts") == "all")
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch submissions"})
 		return
@@ -139,6 +154,24 @@ func (ctrl *AssessmentController) GetSubmissions(c *gin.Context) {
 	c.JSON(http.StatusOK, subs)
 }
 
+// GET /api/assessments/:id/question-times
+// Per-question time spent and answer-change stats across completed submissions.
+func (ctrl *AssessmentController) GetQuestionTimeStats(c *gin.Context) {
+	if !requireStaffRole(c) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	stats, err := ctrl.submissionService.GetQuestionTimeStats(ctx, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute question time stats"})
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
+
 // --- Candidate Methods ---
 
 func (ctrl *AssessmentController) GetCandidateResult(c *gin.Context) {
@@ -157,6 +190,51 @@ func (ctrl *AssessmentController) GetCandidateResult(c *gin.Context) {
 	c.JSON(http.StatusOK, result)
 }
 
+func (ctrl *AssessmentController) StartRetake(c *gin.Context) {
+	assessmentID := c.Param("id")
+	candidateID, _ := c.Get("userID")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	submission, err := ctrl.submissionService.StartRetake(ctx, assessmentID, candidateID.(primitive.ObjectID).Hex())
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrRetakeCooldown):
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrNoAttemptsLeft), errors.Is(err, services.ErrAttemptInProgress):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrAssessmentClosed):
+			c.JSON(http.StatusForbidden, gin.H{"error": "This assessment is closed"})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":        "New attempt started",
+		"submission_id":  submission.ID,
+		"attempt_number": submission.AttemptNumber,
+	})
+}
+
+func (ctrl *AssessmentController) GetAttemptHistory(c *gin.Context) {
+	assessmentID := c.Param("id")
+	candidateID, _ := c.Get("userID")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	history, err := ctrl.submissionService.GetAttemptHistory(ctx, assessmentID, candidateID.(primitive.ObjectID).Hex())
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Assessment not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
+
 func (ctrl *AssessmentController) SubmitAssessment(c *gin.Context) {
 	assessmentID := c.Param("id")
 	candidateID, _ := c.Get("userID")
@@ -164,6 +242,7 @@ func (ctrl *AssessmentController) SubmitAssessment(c *gin.Context) {
 	var input struct {
 		Answers       []models.Answer       `json:"answers"`
 		Violations    []models.Violation    `json:"violations"`
+		Events        []models.AnswerEvent  `json:"events"`
 		FaceSnapshots *models.FaceSnapshots `json:"face_snapshots,omitempty"`
 	}
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -174,7 +253,7 @@ func (ctrl *AssessmentController) SubmitAssessment(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	submission, err := ctrl.submissionService.SubmitAssessment(ctx, assessmentID, candidateID.(primitive.ObjectID).Hex(), input.Answers, input.Violations, input.FaceSnapshots)
+	submission, err := ctrl.submissionService.SubmitAssessment(ctx, assessmentID, candidateID.(primitive.ObjectID).Hex(), input.Answers, input.Violations, input.Events, input.FaceSnapshots)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to submit assessment"})
 		return
@@ -183,29 +262,177 @@ func (ctrl *AssessmentController) SubmitAssessment(c *gin.Context) {
 	c.JSON(http.StatusOK, submission)
 }
 
+// POST /api/assessments/:id/progress
+// Optional If-Match header (or "version" field) carrying the submission ETag; stale writes get 409.
 func (ctrl *AssessmentController) SaveAssessmentProgress(c *gin.Context) {
 	assessmentID := c.Param("id")
 	candidateID, _ := c.Get("userID")
 
 	var input struct {
-		Answers    []models.Answer    `json:"answers"`
-		Violations []models.Violation `json:"violations"`
+		Answers    []models.Answer      `json:"answers"`
+		Violations []models.Violation   `json:"violations"`
+		Events     []models.AnswerEvent `json:"events"` // Per-question seen/answered/changed events since the last save
+		Version    *int64               `json:"version"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	expectedVersion, ok := expectedSubmissionVersion(c, input.Version)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	version, err := ctrl.submissionService.SaveProgress(ctx, assessmentID, candidateID.(primitive.ObjectID).Hex(), input.Answers, input.Violations, input.Events, expectedVersion)
+	if err != nil {
+		respondSaveError(c, version, err, "Failed to save progress")
+		return
+	}
+
+	c.Header("ETag", submissionETag(version))
+	c.JSON(http.StatusOK, gin.H{"message": "Progress saved successfully", "version": version})
+}
+
+// PUT /api/assessments/:id/answers/:questionId
+// Upserts one answer. Same versioning rules as SaveAssessmentProgress.
+func (ctrl *AssessmentController) SaveAnswer(c *gin.Context) {
+	assessmentID := c.Param("id")
+	candidateID, _ := c.Get("userID")
+
+	questionID, err := primitive.ObjectIDFromHex(c.Param("questionId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid question ID"})
+		return
+	}
+
+	var input struct {
+		Value   string `json:"value"`
+		Version *int64 `json:"version"`
 	}
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	expectedVersion, ok := expectedSubmissionVersion(c, input.Version)
+	if !ok {
+		return
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	err := ctrl.submissionService.SaveProgress(ctx, assessmentID, candidateID.(primitive.ObjectID).Hex(), input.Answers, input.Violations)
+	answer := models.Answer{QuestionID: questionID, Value: input.Value}
+	version, err := ctrl.submissionService.SaveAnswer(ctx, assessmentID, candidateID.(primitive.ObjectID).Hex(), answer, expectedVersion)
+	if err != nil {
+		respondSaveError(c, version, err, "Failed to save answer")
+		return
+	}
+
+	c.Header("ETag", submissionETag(version))
+	c.JSON(http.StatusOK, gin.H{"message": "Answer saved successfully", "version": version})
+}
+
+// GET /api/assessments/:id/adaptive/next
+// Returns the item currently presented in an adaptive attempt, starting the attempt if needed.
+func (ctrl *AssessmentController) GetNextAdaptiveQuestion(c *gin.Context) {
+	assessmentID := c.Param("id")
+	candidateID, _ := c.Get("userID")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	step, err := ctrl.submissionService.NextAdaptiveQuestion(ctx, assessmentID, candidateID.(primitive.ObjectID).Hex())
+	if err != nil {
+		respondAdaptiveError(c, err, "Failed to fetch next question")
+		return
+	}
+
+	c.JSON(http.StatusOK, step)
+}
+
+// POST /api/assessments/:id/adaptive/answer
+// Grades the presented item and returns the next one, or the final score.
+func (ctrl *AssessmentController) AnswerAdaptiveQuestion(c *gin.Context) {
+	assessmentID := c.Param("id")
+	candidateID, _ := c.Get("userID")
+
+	var input struct {
+		QuestionID string `json:"question_id" binding:"required"`
+		Value      string `json:"value"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	step, err := ctrl.submissionService.AnswerAdaptiveQuestion(ctx, assessmentID, candidateID.(primitive.ObjectID).Hex(), input.QuestionID, input.Value)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save progress"})
+		respondAdaptiveError(c, err, "Failed to record answer")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Progress saved successfully"})
+	c.JSON(http.StatusOK, step)
+}
+
+func respondAdaptiveError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrNotAdaptive):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrAdaptiveItemMismatch):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrStaleSubmission):
+		c.JSON(http.StatusConflict, gin.H{"error": "Another request advanced this attempt; fetch the next question again"})
+	case errors.Is(err, services.ErrAssessmentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Assessment not found"})
+	case errors.Is(err, services.ErrInvalidQuestionID):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid question ID"})
+	default:
+		respondSaveError(c, 0, err, fallback)
+	}
+}
+
+func submissionETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// expectedSubmissionVersion reads the If-Match header, falling back to the body's version.
+// It writes a 400 and returns false when the header is not a submission ETag.
+func expectedSubmissionVersion(c *gin.Context, bodyVersion *int64) (*int64, bool) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return bodyVersion, true
+	}
+
+	version, err := strconv.ParseInt(strings.Trim(strings.TrimPrefix(header, "W/"), `"`), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid If-Match header"})
+		return nil, false
+	}
+	return &version, true
+}
+
+func respondSaveError(c *gin.Context, version int64, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrStaleSubmission):
+		c.Header("ETag", submissionETag(version))
+		c.JSON(http.StatusConflict, gin.H{"error": "Submission has changed; reload and retry", "current_version": version})
+	case errors.Is(err, services.ErrSubmissionCompleted):
+		c.JSON(http.StatusConflict, gin.H{"error": "This attempt has already been submitted"})
+	case errors.Is(err, services.ErrAdaptiveAnswerRoute):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrDeadlinePassed):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrAssessmentClosed):
+		c.JSON(http.StatusForbidden, gin.H{"error": "This assessment is closed"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
 }
 
 func (ctrl *AssessmentController) GetMySubmissions(c *gin.Context) {
@@ -0,0 +1,120 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"hireit-backend/services"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const maxAssessmentBundleSize = 200 << 20
+
+type AssessmentBundleController struct {
+	bundleService services.AssessmentBundleService
+}
+
+func NewAssessmentBundleController(bundleService services.AssessmentBundleService) *AssessmentBundleController {
+	return &AssessmentBundleController{bundleService: bundleService}
+}
+
+// GET /api/assessments/:id/bundle
+// Downloads the assessment as a zip bundle that can be imported on another server.
+// Interviewers can only export the assessments they created.
+func (ctrl *AssessmentBundleController) ExportBundle(c *gin.Context) {
+	if !requireStaffRole(c) {
+		return
+	}
+
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid assessment ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	data, _, err := ctrl.bundleService.ExportBundle(ctx, id, assessmentOwner(c))
+	if errors.Is(err, services.ErrBundleAssessmentNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, services.ErrBundleAudioMissing) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		fmt.Printf("[Assessment Bundle] Export of %s failed: %v\n", id.Hex(), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export assessment"})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "assessment-"+id.Hex()+".zip"))
+	c.Data(http.StatusOK, "application/zip", data)
+}
+
+// POST /api/assessments/bundle
+// Multipart field "file". Query params: on_title_conflict (rename|fail, default rename),
+// on_question_conflict (reuse|copy, default reuse), approve_questions (true stores the
+// created questions as approved instead of sending them to review). The report maps
+// bundle IDs to new IDs.
+func (ctrl *AssessmentBundleController) ImportBundle(c *gin.Context) {
+	if !requireStaffRole(c) {
+		return
+	}
+
+	opts := services.BundleImportOptions{
+		TitleConflict:    c.DefaultQuery("on_title_conflict", services.BundleConflictRename),
+		QuestionConflict: c.DefaultQuery("on_question_conflict", services.BundleConflictReuse),
+		Approve:          c.Query("approve_questions") == "true",
+	}
+	if opts.TitleConflict != services.BundleConflictRename && opts.TitleConflict != services.BundleConflictFail {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "on_title_conflict must be rename or fail"})
+		return
+	}
+	if opts.QuestionConflict != services.BundleConflictReuse && opts.QuestionConflict != services.BundleConflictCopy {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "on_question_conflict must be reuse or copy"})
+		return
+	}
+
+	file, _, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded"})
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxAssessmentBundleSize+1))
+	if err != nil || len(data) > maxAssessmentBundleSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("File must be at most %d MB", maxAssessmentBundleSize>>20)})
+		return
+	}
+
+	userID, _ := c.Get("userID")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	report, err := ctrl.bundleService.ImportBundle(ctx, data, opts, userID.(primitive.ObjectID))
+	switch {
+	case errors.Is(err, services.ErrInvalidBundle), errors.Is(err, services.ErrBundleChecksum), errors.Is(err, services.ErrInvalidAdaptiveSetup):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, services.ErrBundleTitleConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		fmt.Printf("[Assessment Bundle] Import failed: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import assessment"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Assessment imported successfully", "report": report})
+}
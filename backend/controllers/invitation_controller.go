@@ -0,0 +1,241 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"hireit-backend/models"
+	"hireit-backend/services"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type InvitationController struct {
+	invitationService services.InvitationService
+}
+
+func NewInvitationController(invitationService services.InvitationService) *InvitationController {
+	return &InvitationController{invitationService: invitationService}
+}
+
+// requireStaffRole aborts with 403 unless the caller is an interviewer or admin
+func requireStaffRole(c *gin.Context) bool {
+	if role, _ := c.Get("role"); role != "interviewer" && role != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only interviewers can perform this action"})
+		return false
+	}
+	return true
+}
+
+// assessmentOwner is the user whose assessments the caller may manage: the caller, or
+// nobody in particular for admins, who manage every assessment
+func assessmentOwner(c *gin.Context) primitive.ObjectID {
+	if role, _ := c.Get("role"); role == "admin" {
+		return primitive.NilObjectID
+	}
+	userID, _ := c.Get("userID")
+	ownerID, _ := userID.(primitive.ObjectID)
+	return ownerID
+}
+
+// --- Interviewer Methods ---
+
+// POST /api/assessments/:id/invitations
+func (ctrl *InvitationController) CreateInvitation(c *gin.Context) {
+	if !requireStaffRole(c) {
+		return
+	}
+
+	var req models.CreateInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, _ := c.Get("userID")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	invitation, token, err := ctrl.invitationService.CreateInvitation(ctx, c.Param("id"), userID.(primitive.ObjectID).Hex(), &req)
+	switch {
+	case errors.Is(err, services.ErrInvalidAssessmentID):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid assessment ID"})
+		return
+	case errors.Is(err, services.ErrAssessmentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Assessment not found"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invitation"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":    "Invitation created successfully",
+		"invitation": invitation,
+		"token":      token,
+		"invite_url": ctrl.invitationService.InvitationURL(invitation, token),
+	})
+}
+
+// GET /api/assessments/:id/invitations
+func (ctrl *InvitationController) GetInvitations(c *gin.Context) {
+	if !requireStaffRole(c) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	invitations, err := ctrl.invitationService.GetInvitations(ctx, c.Param("id"), assessmentOwner(c))
+	switch {
+	case errors.Is(err, services.ErrInvalidAssessmentID):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid assessment ID"})
+		return
+	case errors.Is(err, services.ErrAssessmentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Assessment not found"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch invitations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, invitations)
+}
+
+// DELETE /api/invitations/:id
+func (ctrl *InvitationController) RevokeInvitation(c *gin.Context) {
+	if !requireStaffRole(c) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := ctrl.invitationService.RevokeInvitation(ctx, c.Param("id"), assessmentOwner(c))
+	switch {
+	case errors.Is(err, services.ErrInvalidInvitationID):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invitation ID"})
+		return
+	case errors.Is(err, services.ErrInvitationNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found"})
+		return
+	case errors.Is(err, services.ErrInvitationUsed):
+		c.JSON(http.StatusConflict, gin.H{"error": "Invitation has already been used"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke invitation"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation revoked successfully"})
+}
+
+// POST /api/assessments/:id/invitations/bulk
+// Multipart "file" with name, email and phone columns. Optional query param: expires_in_hours.
+// Small files are processed inline; larger ones return 202 and can be polled by job ID.
+func (ctrl *InvitationController) BulkInvite(c *gin.Context) {
+	if !requireStaffRole(c) {
+		return
+	}
+
+	file, _, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded"})
+		return
+	}
+	defer file.Close()
+
+	expiresInHours := 0
+	if raw := c.Query("expires_in_hours"); raw != "" {
+		expiresInHours, err = strconv.Atoi(raw)
+		if err != nil || expiresInHours < 1 || expiresInHours > services.MaxInvitationTTLHours {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("expires_in_hours must be between 1 and %d", services.MaxInvitationTTLHours)})
+			return
+		}
+	}
+	userID, _ := c.Get("userID")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	job, err := ctrl.invitationService.BulkInvite(ctx, c.Param("id"), userID.(primitive.ObjectID).Hex(), file, expiresInHours)
+	if errors.Is(err, services.ErrAssessmentNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Assessment not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if job.Status != "completed" {
+		c.JSON(http.StatusAccepted, job)
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+// GET /api/invitations/bulk/:jobId
+func (ctrl *InvitationController) GetBulkInviteJob(c *gin.Context) {
+	if !requireStaffRole(c) {
+		return
+	}
+
+	job, err := ctrl.invitationService.GetBulkInviteJob(c.Param("jobId"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Bulk invitation job not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// --- Public Methods ---
+
+// POST /api/public/invitations/accept
+// Consumes the invitation link and starts a candidate session for it.
+func (ctrl *InvitationController) AcceptInvitation(c *gin.Context) {
+	var input struct {
+		Token string `json:"token" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	token, user, invitation, err := ctrl.invitationService.AcceptInvitation(ctx, input.Token)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvitationExpired):
+			c.JSON(http.StatusGone, gin.H{"error": "This invitation has expired."})
+		case errors.Is(err, services.ErrInvitationUsed), errors.Is(err, services.ErrInvitationRevoked):
+			c.JSON(http.StatusConflict, gin.H{"error": "This invitation is no longer valid."})
+		case errors.Is(err, services.ErrInvitationInvalid):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid invitation link."})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to initiate assessment session"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"token":         token,
+		"assessment_id": invitation.AssessmentID,
+		"user": gin.H{
+			"id":    user.ID,
+			"name":  user.Name,
+			"email": user.Email,
+			"role":  user.Role,
+		},
+	})
+}
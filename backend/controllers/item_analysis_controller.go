@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"hireit-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ItemAnalysisController struct {
+	itemAnalysisService services.ItemAnalysisService
+}
+
+func NewItemAnalysisController(itemAnalysisService services.ItemAnalysisService) *ItemAnalysisController {
+	return &ItemAnalysisController{itemAnalysisService: itemAnalysisService}
+}
+
+// POST /api/admin/questions/item-analysis
+// Starts a background run; results are written onto the bank entries as item_stats.
+func (ctrl *ItemAnalysisController) RunItemAnalysis(c *gin.Context) {
+	job, err := ctrl.itemAnalysisService.StartItemAnalysis()
+	if err != nil {
+		if errors.Is(err, services.ErrItemAnalysisRunning) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start item analysis"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, job)
+}
+
+// GET /api/admin/questions/item-analysis
+// Returns the latest run's status.
+func (ctrl *ItemAnalysisController) GetItemAnalysisJob(c *gin.Context) {
+	job, err := ctrl.itemAnalysisService.GetItemAnalysisJob()
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Item analysis has not been run yet"})
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// GET /api/admin/questions/calibration/suggestions
+// Query params: category. Lists entries whose calibrated difficulty disagrees with their label.
+func (ctrl *ItemAnalysisController) ListDifficultySuggestions(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	suggestions, err := ctrl.itemAnalysisService.ListDifficultySuggestions(ctx, c.Query("category"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch difficulty suggestions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"suggestions": suggestions, "total": len(suggestions)})
+}
+
+// POST /api/admin/questions/calibration/accept
+// Body: {"ids": [...]} to accept selected suggestions, or {"all": true} for every pending one.
+func (ctrl *ItemAnalysisController) AcceptDifficultySuggestions(c *gin.Context) {
+	var input struct {
+		IDs []string `json:"ids"`
+		All bool     `json:"all"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	updated, err := ctrl.itemAnalysisService.AcceptDifficultySuggestions(ctx, input.IDs, input.All)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Difficulty suggestions accepted",
+		"updated_count": updated,
+	})
+}
@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"hireit-backend/services"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type MediaController struct {
+	mediaService services.MediaService
+}
+
+func NewMediaController(mediaService services.MediaService) *MediaController {
+	return &MediaController{mediaService: mediaService}
+}
+
+// POST /api/admin/media
+// Multipart field "file": a PNG, JPEG, GIF or WebP image, or MP3, WAV, OGG, M4A or AAC
+// audio. Questions attach the returned media by its ID.
+func (ctrl *MediaController) UploadMedia(c *gin.Context) {
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Media file is required"})
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, services.MaxMediaUploadSize+1))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read media file"})
+		return
+	}
+	if len(data) > services.MaxMediaUploadSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("File must be at most %d MB", services.MaxMediaUploadSize>>20)})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	media, created, err := ctrl.mediaService.Store(ctx, header.Filename, data, questionAuthor(c))
+	switch {
+	case errors.Is(err, services.ErrUnsupportedMedia):
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, services.ErrMediaTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		fmt.Printf("[Media Upload] Upload of %s failed: %v\n", header.Filename, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save media file"})
+		return
+	}
+
+	status := http.StatusOK // Already uploaded
+	if created {
+		status = http.StatusCreated
+	}
+	c.JSON(status, media)
+}
+
+// GET /api/admin/media/:id
+func (ctrl *MediaController) GetMedia(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid media ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	media, _, err := ctrl.mediaService.Find(ctx, id)
+	if errors.Is(err, services.ErrMediaNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Media not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch media"})
+		return
+	}
+
+	c.JSON(http.StatusOK, media)
+}
+
+// GET /media/:id
+// Serves the file with its sniffed content type. Media never changes under an ID, so it
+// can be cached indefinitely.
+func (ctrl *MediaController) ServeMedia(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	media, path, err := ctrl.mediaService.Find(ctx, id)
+	if errors.Is(err, services.ErrMediaNotFound) {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	c.Header("Content-Type", media.ContentType)
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	c.File(path)
+}
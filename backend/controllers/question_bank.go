@@ -1,8 +1,11 @@
 package controllers
 
 import (
+	"bytes"
 	"context"
 	"encoding/csv"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -16,15 +19,26 @@ import (
 
 	"hireit-backend/models"
 	"hireit-backend/repositories"
+	"hireit-backend/services"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// maxQTIUploadSize bounds uploaded QTI packages; the contents of a package are bounded
+// separately. maxTextImportSize bounds GIFT and Aiken files, which are read whole.
+const (
+	maxQTIUploadSize  = 50 << 20
+	maxTextImportSize = 10 << 20
+)
+
 type QuestionBankController struct {
-	repo repositories.QuestionBankRepository
+	repo         repositories.QuestionBankRepository
+	service      services.QuestionBankService
+	mediaService services.MediaService
 }
 
 func normalizeCSVHeaderKey(value string) string {
@@ -40,25 +54,36 @@ func normalizeCSVHeaderKey(value string) string {
 	return b.String()
 }
 
-func NewQuestionBankController(repo repositories.QuestionBankRepository) *QuestionBankController {
-	return &QuestionBankController{repo: repo}
+func NewQuestionBankController(repo repositories.QuestionBankRepository, service services.QuestionBankService, mediaService services.MediaService) *QuestionBankController {
+	return &QuestionBankController{repo: repo, service: service, mediaService: mediaService}
+}
+
+// questionImportItem is a question as ImportQuestions reads it and the JSON export writes it.
+type questionImportItem struct {
+	Category      string                   `json:"category"`
+	SubCategory   string                   `json:"sub_category"`
+	Difficulty    string                   `json:"difficulty"`
+	PassageTitle  string                   `json:"passage_title"`
+	PassageText   string                   `json:"passage_text"`
+	Type          string                   `json:"type"`
+	Text          string                   `json:"text"`
+	Options       []string                 `json:"options"`
+	CorrectAnswer string                   `json:"correct_answer"`
+	AudioURL      string                   `json:"audio_url"`
+	Template      *models.QuestionTemplate `json:"template"`
+	Tags          []string                 `json:"tags,omitempty"`
+	Skills        []string                 `json:"skills,omitempty"`
+	Objectives    []string                 `json:"learning_objectives,omitempty"`
+	Media         []models.MediaAttachment `json:"media,omitempty"`
+	ReviewStatus  string                   `json:"review_status,omitempty"`
 }
 
 // POST /api/admin/questions/import
+// Imported questions wait for review, unless keep_review_status=true keeps the draft or
+// rejected review_status they were exported with. Approved questions are reviewed again.
 func (ctrl *QuestionBankController) ImportQuestions(c *gin.Context) {
 	var input struct {
-		Questions []struct {
-			Category      string   `json:"category"`
-			SubCategory   string   `json:"sub_category"`
-			Difficulty    string   `json:"difficulty"`
-			PassageTitle  string   `json:"passage_title"`
-			PassageText   string   `json:"passage_text"`
-			Type          string   `json:"type"`
-			Text          string   `json:"text"`
-			Options       []string `json:"options"`
-			CorrectAnswer string   `json:"correct_answer"`
-			AudioURL      string   `json:"audio_url"`
-		} `json:"questions"`
+		Questions []questionImportItem `json:"questions"`
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -70,7 +95,11 @@ func (ctrl *QuestionBankController) ImportQuestions(c *gin.Context) {
 	defer cancel()
 
 	importedCount := 0
-	for _, q := range input.Questions {
+	invalid := []string{}
+	templateErrors := []string{}
+	mediaErrors := []string{}
+	dedupe := ctrl.newImportDedupe()
+	for i, q := range input.Questions {
 		entry := &models.QuestionBankEntry{
 			ID:            primitive.NewObjectID(),
 			Category:      q.Category,
@@ -83,27 +112,49 @@ func (ctrl *QuestionBankController) ImportQuestions(c *gin.Context) {
 			Options:       q.Options,
 			CorrectAnswer: q.CorrectAnswer,
 			AudioURL:      q.AudioURL,
+			Template:      q.Template,
+			Tags:          q.Tags,
+			Skills:        q.Skills,
+			Objectives:    q.Objectives,
+			Media:         q.Media,
+			ReviewStatus:  importedReviewStatus(c, q.ReviewStatus),
+		}
+		if err := services.ValidateImportReviewStatus(entry.ReviewStatus); err != nil {
+			invalid = append(invalid, fmt.Sprintf("question %d: %v", i+1, err))
+			continue
+		}
+		if err := services.ValidateQuestionTemplate(entry); err != nil {
+			templateErrors = append(templateErrors, fmt.Sprintf("question %d: %v", i+1, err))
+			continue
+		}
+		if err := ctrl.mediaService.ResolveAttachments(ctx, entry); err != nil {
+			mediaErrors = append(mediaErrors, fmt.Sprintf("question %d: %v", i+1, err))
+			continue
 		}
 
-		_, err := ctrl.repo.Create(ctx, entry)
-		if err == nil {
+		if ctrl.importQuestion(ctx, c, dedupe, i+1, entry) == nil {
 			importedCount++
 		}
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message":        "Questions imported successfully",
-		"imported_count": importedCount,
-	})
+	response := gin.H{
+		"message":         "Questions imported successfully",
+		"imported_count":  importedCount,
+		"errors":          invalid,
+		"template_errors": templateErrors,
+		"media_errors":    mediaErrors,
+	}
+	dedupe.addTo(response)
+	c.JSON(http.StatusOK, response)
 }
 
 // GET /api/admin/questions/config
-// Returns distinct categories, sub_categories (grouped by category), and difficulties from the bank.
+// Returns distinct categories, sub_categories (grouped by category), difficulties and tags from the bank.
 func (ctrl *QuestionBankController) GetConfig(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	all, err := ctrl.repo.Find(ctx, bson.M{}, options.Find())
+	counts, err := ctrl.repo.Counts(ctx)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load config"})
 		return
@@ -113,14 +164,14 @@ func (ctrl *QuestionBankController) GetConfig(c *gin.Context) {
 	subCategoryMap := map[string]map[string]bool{} // category -> set of sub_categories
 	difficultySet := map[string]bool{}
 
-	for _, q := range all {
-		categorySet[q.Category] = true
-		difficultySet[q.Difficulty] = true
-		if q.SubCategory != "" {
-			if subCategoryMap[q.Category] == nil {
-				subCategoryMap[q.Category] = map[string]bool{}
+	for _, slot := range counts.Slots {
+		categorySet[slot.Category] = true
+		difficultySet[slot.Difficulty] = true
+		if slot.SubCategory != "" {
+			if subCategoryMap[slot.Category] == nil {
+				subCategoryMap[slot.Category] = map[string]bool{}
 			}
-			subCategoryMap[q.Category][q.SubCategory] = true
+			subCategoryMap[slot.Category][slot.SubCategory] = true
 		}
 	}
 
@@ -132,8 +183,13 @@ func (ctrl *QuestionBankController) GetConfig(c *gin.Context) {
 	for k := range difficultySet {
 		difficulties = append(difficulties, k)
 	}
+	tags := make([]string, len(counts.Tags))
+	for i, tag := range counts.Tags {
+		tags[i] = tag.Tag
+	}
 	sort.Strings(categories)
 	sort.Strings(difficulties)
+	sort.Strings(tags)
 
 	subCategories := map[string][]string{}
 	for cat, subs := range subCategoryMap {
@@ -151,11 +207,38 @@ func (ctrl *QuestionBankController) GetConfig(c *gin.Context) {
 		"categories":     categories,
 		"sub_categories": subCategories,
 		"difficulties":   difficulties,
+		"tags":           tags,
 		"structure":      structure,
 	})
 }
 
+// GET /api/admin/questions/stats?min_sampleable=1
+// Live and sampleable counts per category, sub-category, difficulty and type, passage
+// groups, tag usage, and coverage gaps: configured slots with fewer sampleable entries
+// than min_sampleable, and slots in use that the bank structure does not define.
+// Counts are cached until the next write to the bank.
+func (ctrl *QuestionBankController) GetStats(c *gin.Context) {
+	minSampleable := services.DefaultMinSampleable
+	if v, err := strconv.Atoi(c.Query("min_sampleable")); err == nil && v >= 0 {
+		minSampleable = v
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	stats, err := ctrl.service.BankStats(ctx, minSampleable)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute bank statistics"})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
 // POST /api/admin/questions/upload-csv
+// With dry_run=true nothing is stored: every row is validated and the report carries a
+// token for POST /api/admin/questions/upload-csv/confirm. keep_review_status=true keeps
+// the review_status column of an export, except approved, as for ImportQuestions.
 func (ctrl *QuestionBankController) UploadCSV(c *gin.Context) {
 	file, _, err := c.Request.FormFile("file")
 	if err != nil {
@@ -194,18 +277,42 @@ func (ctrl *QuestionBankController) UploadCSV(c *gin.Context) {
 	if !hasText {
 		_, hasText = colMap["questiontext"]
 	}
+	dryRun := c.Query("dry_run") == "true"
+	var rows []services.ImportRow
 	if !hasText {
 		// FALLBACK: Smart Parser for vertical list formats (single column or missing headers)
-		ctrl.handleSmartUpload(c, reader, header, defaultCat, defaultSub, defaultDiff)
+		rows = readSmartCSVRows(reader, header, defaultCat, defaultSub, defaultDiff)
+	} else {
+		rows = readCSVQuestionRows(reader, colMap, defaultCat, defaultSub, defaultDiff)
+	}
+	for _, row := range rows {
+		if row.Entry != nil {
+			row.Entry.ReviewStatus = importedReviewStatus(c, row.Entry.ReviewStatus)
+		}
+	}
+	if dryRun {
+		ctrl.stageImport(c, rows)
+		return
+	}
+	if !hasText {
+		ctrl.handleSmartUpload(c, rows)
 		return
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
 	defer cancel()
 
+	// Rows are checked as in the dry run, so both accept the same rows
+	config, err := ctrl.repo.GetBankConfig(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load the bank structure"})
+		return
+	}
+
 	importedCount := 0
 	skippedCount := 0
 	warnings := []string{}
+	dedupe := ctrl.newImportDedupe()
 
 	addWarning := func(format string, args ...any) {
 		if len(warnings) >= 5 {
@@ -214,15 +321,58 @@ func (ctrl *QuestionBankController) UploadCSV(c *gin.Context) {
 		warnings = append(warnings, fmt.Sprintf(format, args...))
 	}
 
+	for _, row := range rows {
+		if row.Error != "" {
+			skippedCount++
+			addWarning("Row %d skipped: %s", row.Row, row.Error)
+			continue
+		}
+
+		entry := row.Entry
+		if issues, _ := services.ValidateImportEntry(entry, config); len(issues) > 0 {
+			skippedCount++
+			addWarning("Row %d skipped: %s", row.Row, issues[0].Message)
+			continue
+		}
+
+		err := ctrl.importQuestion(ctx, c, dedupe, row.Row, entry)
+		switch {
+		case err == nil:
+			importedCount++
+		case errors.Is(err, errDuplicateQuestion):
+			skippedCount++
+		default:
+			skippedCount++
+			addWarning("Row %d skipped: %v", row.Row, err)
+		}
+	}
+
+	response := gin.H{
+		"message":        "CSV imported successfully",
+		"imported_count": importedCount,
+		"skipped_count":  skippedCount,
+	}
+	if len(warnings) > 0 {
+		response["warnings"] = warnings
+	}
+	dedupe.addTo(response)
+
+	c.JSON(http.StatusOK, response)
+}
+
+// readCSVQuestionRows maps every row of a CSV with a header to an entry. Query-string
+// defaults take precedence over the row's category, sub-category and difficulty.
+func readCSVQuestionRows(reader *csv.Reader, colMap map[string]int, defaultCat, defaultSub, defaultDiff string) []services.ImportRow {
 	val := func(row []string, keys ...string) string {
 		for _, key := range keys {
 			if idx, ok := colMap[normalizeCSVHeaderKey(key)]; ok && idx < len(row) {
-				return strings.TrimSpace(row[idx])
+				return unescapeCSVCell(strings.TrimSpace(row[idx]))
 			}
 		}
 		return ""
 	}
 
+	rows := []services.ImportRow{}
 	rowNumber := 1 // Header row
 	for {
 		row, err := reader.Read()
@@ -231,8 +381,7 @@ func (ctrl *QuestionBankController) UploadCSV(c *gin.Context) {
 			break
 		}
 		if err != nil {
-			skippedCount++
-			addWarning("Row %d skipped: %v", rowNumber, err)
+			rows = append(rows, services.ImportRow{Row: rowNumber, Error: err.Error()})
 			continue
 		}
 
@@ -248,18 +397,6 @@ func (ctrl *QuestionBankController) UploadCSV(c *gin.Context) {
 		if diff == "" {
 			diff = val(row, "difficulty", "level")
 		}
-		text := val(row, "text", "question", "question_text", "questiontext", "prompt")
-
-		if text == "" {
-			skippedCount++
-			addWarning("Row %d skipped: missing question text", rowNumber)
-			continue
-		}
-		if cat == "" || diff == "" {
-			skippedCount++
-			addWarning("Row %d skipped: missing category or difficulty", rowNumber)
-			continue
-		}
 
 		options := []string{}
 		if a := val(row, "option_a", "optiona", "a", "choice_a", "choicea", "option1"); a != "" {
@@ -283,35 +420,264 @@ func (ctrl *QuestionBankController) UploadCSV(c *gin.Context) {
 			PassageTitle:  val(row, "passage_title", "passagetitle", "passage_name", "passagename"),
 			PassageText:   val(row, "passage_text", "passagetext", "passage", "reading_passage", "readingpassage"),
 			Type:          models.QuestionType(strings.ToUpper(val(row, "type", "question_type", "questiontype"))),
-			Text:          text,
+			Text:          val(row, "text", "question", "question_text", "questiontext", "prompt"),
 			Options:       options,
 			CorrectAnswer: val(row, "correct_answer", "correctanswer", "answer", "answer_key", "answerkey"),
+			AudioURL:      val(row, "audio_url", "audiourl"),
+			Tags:          splitCSVList(val(row, "tags", "tag")),
+			Skills:        splitCSVList(val(row, "skills", "skill")),
+			Objectives:    splitCSVLines(val(row, "learning_objectives", "learningobjectives", "objectives")),
+			ReviewStatus:  strings.ToLower(val(row, "review_status", "reviewstatus")),
 		}
 
 		if entry.Type == "" {
 			entry.Type = models.MultipleChoice
 		}
+		rows = append(rows, services.ImportRow{Row: rowNumber, Entry: entry})
+	}
+	return rows
+}
+
+// questionCSVHeader is the column layout written by the CSV export; readCSVQuestionRows
+// reads it back.
+var questionCSVHeader = []string{
+	"category", "sub_category", "difficulty", "type", "text", "passage_title", "passage_text",
+	"option_a", "option_b", "option_c", "option_d", "correct_answer", "audio_url", "tags", "skills",
+	"learning_objectives", "review_status",
+}
+
+func questionCSVRecord(q *models.QuestionBankEntry) []string {
+	options := make([]string, 4)
+	copy(options, q.Options)
+	record := []string{
+		q.Category, q.SubCategory, q.Difficulty, string(q.Type), q.Text, q.PassageTitle, q.PassageText,
+		options[0], options[1], options[2], options[3], q.CorrectAnswer, q.AudioURL,
+		strings.Join(q.Tags, "; "), strings.Join(q.Skills, "; "), strings.Join(q.Objectives, "\n"),
+		exportedReviewStatus(q),
+	}
+	for i := range record {
+		record[i] = escapeCSVCell(record[i])
+	}
+	return record
+}
+
+// csvFormulaCell reports whether a spreadsheet would evaluate the cell as a formula, looking
+// past the quotes escapeCSVCell adds.
+func csvFormulaCell(value string) bool {
+	value = strings.TrimLeft(value, "'")
+	return value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0]))
+}
+
+// escapeCSVCell prefixes cells a spreadsheet would run as a formula with a quote, which
+// unescapeCSVCell strips again on import.
+func escapeCSVCell(value string) string {
+	if csvFormulaCell(value) {
+		return "'" + value
+	}
+	return value
+}
+
+func unescapeCSVCell(value string) string {
+	if strings.HasPrefix(value, "'") && csvFormulaCell(value[1:]) {
+		return value[1:]
+	}
+	return value
+}
+
+// exportedReviewStatus is the entry's review status, with entries from before the review
+// workflow exported as approved.
+func exportedReviewStatus(q *models.QuestionBankEntry) string {
+	if q.ReviewStatus == "" {
+		return models.ReviewStatusApproved
+	}
+	return q.ReviewStatus
+}
+
+// importedReviewStatus is the review status an imported question is stored with: the
+// exported one with keep_review_status=true, otherwise none, so it waits for review. An
+// approved status is never taken from a file, as only a review can make an entry sampleable.
+func importedReviewStatus(c *gin.Context, status string) string {
+	if c.Query("keep_review_status") != "true" || status == models.ReviewStatusApproved {
+		return ""
+	}
+	return status
+}
+
+// splitCSVLines reads the learning objectives of a CSV cell, one per line, as objectives
+// are sentences that may contain semicolons.
+func splitCSVLines(value string) []string {
+	var lines []string
+	for _, line := range strings.Split(value, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// splitCSVList reads the tags or skills of a CSV cell, separated by semicolons.
+func splitCSVList(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ";")
+}
+
+// GET /api/admin/questions/export?format=csv|json
+// Query params: the filters of ListQuestions. Streams the matching questions in the layout
+// of UploadCSV (csv, default) or ImportQuestions (json), so an export re-imports as is.
+// CSV has no room for templates, media or more than four options; such questions are left
+// out and counted in the X-Skipped-Questions header. Both formats carry the review status,
+// which the imports keep with keep_review_status=true, approved excepted. CSV cells that a
+// spreadsheet would run as a formula are prefixed with a quote, which the CSV import strips.
+func (ctrl *QuestionBankController) ExportQuestions(c *gin.Context) {
+	format := c.DefaultQuery("format", "csv")
+	if format != "csv" && format != "json" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv or json"})
+		return
+	}
+	filter := listQuestionsFilter(c)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	skipped := int64(0)
+	if format == "csv" {
+		unfit := bson.M{"$or": bson.A{
+			bson.M{"template": bson.M{"$ne": nil}},
+			bson.M{"options.4": bson.M{"$exists": true}},
+			bson.M{"media.0": bson.M{"$exists": true}},
+		}}
+		var err error
+		if skipped, err = ctrl.repo.CountByFilter(ctx, bson.M{"$and": bson.A{filter, unfit}}); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export questions"})
+			return
+		}
+		filter = bson.M{"$and": bson.A{filter, bson.M{"$nor": bson.A{unfit}}}}
+	}
+
+	opts := options.Find().SetSort(bson.D{
+		{Key: "category", Value: 1},
+		{Key: "sub_category", Value: 1},
+		{Key: "difficulty", Value: 1},
+		{Key: "passage_title", Value: 1},
+		{Key: "passage_text", Value: 1},
+		{Key: "_id", Value: 1},
+	})
 
-		_, err = ctrl.repo.Create(ctx, entry)
+	filename := fmt.Sprintf("question-bank-%s.%s", time.Now().Format("2006-01-02"), format)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Header("X-Skipped-Questions", strconv.FormatInt(skipped, 10))
+
+	var err error
+	if format == "csv" {
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Status(http.StatusOK)
+		writer := csv.NewWriter(c.Writer)
+		if err = writer.Write(questionCSVHeader); err == nil {
+			_, err = ctrl.repo.ForEach(ctx, filter, opts, func(q *models.QuestionBankEntry) error {
+				return writer.Write(questionCSVRecord(q))
+			})
+		}
+		writer.Flush()
 		if err == nil {
-			importedCount++
-		} else {
-			skippedCount++
-			addWarning("Row %d skipped: %v", rowNumber, err)
+			err = writer.Error()
 		}
+	} else {
+		c.Header("Content-Type", "application/json; charset=utf-8")
+		c.Status(http.StatusOK)
+		encoder := json.NewEncoder(c.Writer)
+		first := true
+		if _, err = io.WriteString(c.Writer, `{"questions":[`); err == nil {
+			_, err = ctrl.repo.ForEach(ctx, filter, opts, func(q *models.QuestionBankEntry) error {
+				if !first {
+					if _, err := io.WriteString(c.Writer, ","); err != nil {
+						return err
+					}
+				}
+				first = false
+				return encoder.Encode(questionImportItem{
+					Category:      q.Category,
+					SubCategory:   q.SubCategory,
+					Difficulty:    q.Difficulty,
+					PassageTitle:  q.PassageTitle,
+					PassageText:   q.PassageText,
+					Type:          string(q.Type),
+					Text:          q.Text,
+					Options:       q.Options,
+					CorrectAnswer: q.CorrectAnswer,
+					AudioURL:      q.AudioURL,
+					Template:      q.Template,
+					Tags:          q.Tags,
+					Skills:        q.Skills,
+					Objectives:    q.Objectives,
+					Media:         q.Media,
+					ReviewStatus:  exportedReviewStatus(q),
+				})
+			})
+		}
+		if err == nil {
+			_, err = io.WriteString(c.Writer, "]}\n")
+		}
+	}
+	if err != nil {
+		// The status is already sent; the truncated body is the client's signal
+		fmt.Printf("[Question Export] Export failed: %v\n", err)
+	}
+}
+
+// stageImport answers a dry run with the full validation report and the token that
+// confirms the import.
+func (ctrl *QuestionBankController) stageImport(c *gin.Context, rows []services.ImportRow) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	report, err := ctrl.service.StageImport(ctx, rows)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate import"})
+		return
 	}
 
-	response := gin.H{
-		"message":        "CSV imported successfully",
-		"imported_count": importedCount,
-		"skipped_count":  skippedCount,
+	c.JSON(http.StatusOK, report)
+}
+
+// POST /api/admin/questions/upload-csv/confirm
+// Body: {"import_token": "..."} from a dry run (upload-csv?dry_run=true). The valid rows
+// are stored in one batch.
+func (ctrl *QuestionBankController) ConfirmImport(c *gin.Context) {
+	var input struct {
+		ImportToken string `json:"import_token" binding:"required"`
 	}
-	if len(warnings) > 0 {
-		response["warnings"] = warnings
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	c.JSON(http.StatusOK, response)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	result, err := ctrl.service.ConfirmImport(ctx, input.ImportToken, questionAuthor(c))
+	if errors.Is(err, services.ErrStagedImportNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		response := gin.H{"error": "Failed to import questions"}
+		if result != nil {
+			response["imported_count"] = result.ImportedCount
+		}
+		c.JSON(http.StatusInternalServerError, response)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":         "Questions imported successfully",
+		"imported_count":  result.ImportedCount,
+		"duplicate_count": len(result.Duplicates),
+		"duplicates":      result.Duplicates,
+	})
 }
+
 func (ctrl *QuestionBankController) SaveStructure(c *gin.Context) {
 	var input models.QuestionBankConfig
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -322,6 +688,18 @@ func (ctrl *QuestionBankController) SaveStructure(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	if input.Skills == nil {
+		// Clients that only edit the categories leave the skills taxonomy as it is
+		existing, err := ctrl.repo.GetBankConfig(ctx)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save bank structure"})
+			return
+		}
+		if existing != nil {
+			input.Skills = existing.Skills
+		}
+	}
+
 	if err := ctrl.repo.SaveBankConfig(ctx, &input); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save bank structure"})
 		return
@@ -330,20 +708,100 @@ func (ctrl *QuestionBankController) SaveStructure(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Bank structure saved successfully"})
 }
 
-// GET /api/admin/questions
-// Query params: category, sub_category, difficulty, page, limit
-func (ctrl *QuestionBankController) ListQuestions(c *gin.Context) {
-	filter := bson.M{}
-	if cat := c.Query("category"); cat != "" {
-		filter["category"] = cat
+// POST /api/admin/questions/bulk-update
+// Applies the changes to the entries listed by ids or, without ids, to those matching the
+// GET /api/admin/questions filters. dry_run=true only reports how many entries match.
+func (ctrl *QuestionBankController) BulkUpdateQuestions(c *gin.Context) {
+	var input models.QuestionBulkUpdate
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
-	if sub := c.Query("sub_category"); sub != "" {
-		filter["sub_category"] = sub
+
+	var filter bson.M
+	if len(input.IDs) > 0 {
+		ids := make([]primitive.ObjectID, 0, len(input.IDs))
+		for _, idStr := range input.IDs {
+			id, err := primitive.ObjectIDFromHex(idStr)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid question ID: " + idStr})
+				return
+			}
+			ids = append(ids, id)
+		}
+		filter = bson.M{"_id": bson.M{"$in": ids}}
+	} else {
+		filter = listQuestionsFilter(c)
+		narrowed := false
+		for key := range filter {
+			narrowed = narrowed || key != "retired_at"
+		}
+		if !narrowed {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Pass ids or narrow the filter; bulk updates do not apply to the whole bank"})
+			return
+		}
 	}
-	if diff := c.Query("difficulty"); diff != "" {
-		filter["difficulty"] = diff
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	report, err := ctrl.service.BulkUpdateQuestions(ctx, filter, input.Changes, c.Query("dry_run") == "true", questionAuthor(c))
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidBulkUpdate) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		fmt.Printf("[Question Bulk Update] Update failed: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update questions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// POST /api/admin/questions/structure/operations
+// Renames, merges, moves or deletes a category or sub-category, moving the entries and the
+// assessment rules that use it in the same transaction.
+func (ctrl *QuestionBankController) ApplyStructureOperation(c *gin.Context) {
+	var input models.StructureOperation
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	report, err := ctrl.service.ApplyStructureOperation(ctx, input, questionAuthor(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidStructureOperation):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrStructureNodeNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrStructureConflict), errors.Is(err, services.ErrStructureInUse):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, repositories.ErrQuestionVersionConflict):
+			c.JSON(http.StatusConflict, gin.H{"error": "Questions were edited meanwhile; retry the operation"})
+		case errors.Is(err, repositories.ErrTransactionsUnsupported):
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		default:
+			fmt.Printf("[Question Structure] %s failed: %v\n", input.Op, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to apply structure operation"})
+		}
+		return
 	}
 
+	c.JSON(http.StatusOK, report)
+}
+
+// GET /api/admin/questions
+// Query params: category, sub_category, difficulty, review_status, page, limit, status (live (default), retired, all)
+// Tag filters: tags, exclude_tags, skills (comma-separated)
+// Item analysis filters: item_flag, min_p_value, max_p_value, max_discrimination, min_responses
+func (ctrl *QuestionBankController) ListQuestions(c *gin.Context) {
+	filter := listQuestionsFilter(c)
+
 	// Pagination
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
@@ -387,38 +845,143 @@ func (ctrl *QuestionBankController) ListQuestions(c *gin.Context) {
 	})
 }
 
-// GET /api/admin/questions/count
-// Query params: category, sub_category, difficulty — returns just the count for a slot
-func (ctrl *QuestionBankController) CountQuestions(c *gin.Context) {
+// GET /api/admin/questions/search?q=
+// Full-text search over the question, options and passage, by relevance. Quoted phrases
+// match as a whole; a leading minus excludes a word or phrase. Takes the filters and
+// paging of ListQuestions. Each hit carries HTML-escaped fragments with <mark>ed matches.
+func (ctrl *QuestionBankController) SearchQuestions(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 10
+	}
+	limit = min(limit, services.MaxSearchLimit)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	hits, total, err := ctrl.service.SearchQuestions(ctx, c.Query("q"), listQuestionsFilter(c), page, limit)
+	if errors.Is(err, services.ErrEmptySearchQuery) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		fmt.Printf("[Question Search] Search failed: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search questions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"hits":  hits,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+	})
+}
+
+// listQuestionsFilter builds the filter of ListQuestions from its query params, so the
+// exports select exactly what the list shows.
+func listQuestionsFilter(c *gin.Context) bson.M {
 	filter := bson.M{}
+	switch c.DefaultQuery("status", "live") {
+	case "retired":
+		filter["retired_at"] = bson.M{"$ne": nil}
+	case "all":
+	default:
+		repositories.LiveQuestionFilter(filter)
+	}
 	if cat := c.Query("category"); cat != "" {
 		filter["category"] = cat
 	}
 	if sub := c.Query("sub_category"); sub != "" {
 		filter["sub_category"] = sub
-	} else {
-		// Explicitly match empty (no sub_category) if not specified
-		if c.Query("category") != "" && c.Query("sub_category") == "" {
-			// Don't filter by sub_category – count all sub_categories
-		}
 	}
 	if diff := c.Query("difficulty"); diff != "" {
 		filter["difficulty"] = diff
 	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	count, err := ctrl.repo.CountByFilter(ctx, filter)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count questions"})
-		return
+	if review := c.Query("review_status"); review != "" {
+		filter["review_status"] = review
 	}
+	addTagFilters(c, filter)
+	addItemStatsFilters(c, filter)
+	return filter
+}
 
-	c.JSON(http.StatusOK, gin.H{"count": count})
+// addTagFilters narrows the filter by the comma-separated tags (all required), exclude_tags
+// and skills (any of them or a sub-skill) query params.
+func addTagFilters(c *gin.Context, filter bson.M) {
+	list := func(key string) []string {
+		if value := c.Query(key); value != "" {
+			return strings.Split(value, ",")
+		}
+		return nil
+	}
+	repositories.TaggedQuestionFilter(filter, list("tags"), list("exclude_tags"), list("skills"))
 }
 
-// DELETE /api/admin/questions/:id
+// addItemStatsFilters narrows the filter by the statistics stored by the item analysis job.
+// Malformed numbers are ignored like the other optional query params.
+func addItemStatsFilters(c *gin.Context, filter bson.M) {
+	if flag := c.Query("item_flag"); flag != "" {
+		filter["item_stats.flags"] = flag
+	}
+
+	pValue := bson.M{}
+	if v, err := strconv.ParseFloat(c.Query("min_p_value"), 64); err == nil {
+		pValue["$gte"] = v
+	}
+	if v, err := strconv.ParseFloat(c.Query("max_p_value"), 64); err == nil {
+		pValue["$lte"] = v
+	}
+	if len(pValue) > 0 {
+		filter["item_stats.p_value"] = pValue
+	}
+
+	if v, err := strconv.ParseFloat(c.Query("max_discrimination"), 64); err == nil {
+		filter["item_stats.point_biserial"] = bson.M{"$lte": v}
+	}
+	if v, err := strconv.Atoi(c.Query("min_responses")); err == nil {
+		filter["item_stats.responses"] = bson.M{"$gte": v}
+	}
+}
+
+// GET /api/admin/questions/count
+// Query params: category, sub_category, difficulty, tags, exclude_tags, skills — returns just the count of sampleable entries for a slot
+func (ctrl *QuestionBankController) CountQuestions(c *gin.Context) {
+	filter := repositories.SampleableQuestionFilter(bson.M{})
+	if cat := c.Query("category"); cat != "" {
+		filter["category"] = cat
+	}
+	if sub := c.Query("sub_category"); sub != "" {
+		filter["sub_category"] = sub
+	} else {
+		// Explicitly match empty (no sub_category) if not specified
+		if c.Query("category") != "" && c.Query("sub_category") == "" {
+			// Don't filter by sub_category – count all sub_categories
+		}
+	}
+	if diff := c.Query("difficulty"); diff != "" {
+		filter["difficulty"] = diff
+	}
+	addTagFilters(c, filter)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	count, err := ctrl.repo.CountByFilter(ctx, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count questions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"count": count})
+}
+
+// DELETE /api/admin/questions/:id?force=true
+// Retires the entry; see DeleteQuestionsByFilter for the under-stock check.
 func (ctrl *QuestionBankController) DeleteQuestion(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := primitive.ObjectIDFromHex(idStr)
@@ -427,27 +990,365 @@ func (ctrl *QuestionBankController) DeleteQuestion(c *gin.Context) {
 		return
 	}
 
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	report, err := ctrl.service.RetireQuestions(ctx, bson.M{"_id": id}, c.Query("force") == "true", questionAuthor(c))
+	if err != nil {
+		respondRetireError(c, report, err, "Failed to delete question")
+		return
+	}
+	if report.Retired == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Question not found or already retired"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Question retired successfully", "report": report})
+}
+
+// POST /api/admin/questions/:id/review
+// Body: {"action": "submit" | "approve" | "reject" | "comment", "comment": "..."}
+func (ctrl *QuestionBankController) ReviewQuestion(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid question ID"})
+		return
+	}
+
+	var input struct {
+		Action  string `json:"action" binding:"required"`
+		Comment string `json:"comment"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	if err := ctrl.repo.DeleteByID(ctx, id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete question"})
+	entry, err := ctrl.service.ReviewQuestion(ctx, id, input.Action, input.Comment, questionAuthor(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrQuestionNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Question not found"})
+		case errors.Is(err, services.ErrInvalidReviewAction), errors.Is(err, services.ErrReviewCommentRequired):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrReviewTransition):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to review question"})
+		}
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Question deleted successfully"})
+	c.JSON(http.StatusOK, entry)
 }
 
-// DELETE /api/admin/questions
-// Query params: category, sub_category, difficulty
-func (ctrl *QuestionBankController) DeleteQuestionsByFilter(c *gin.Context) {
+// GET /api/admin/questions/review-queue
+// Query params: category, sub_category, difficulty, status (default in_review), page, limit
+func (ctrl *QuestionBankController) ReviewQueue(c *gin.Context) {
+	filter := bson.M{}
+	if cat := c.Query("category"); cat != "" {
+		filter["category"] = cat
+	}
+	if sub := c.Query("sub_category"); sub != "" {
+		filter["sub_category"] = sub
+	}
+	if diff := c.Query("difficulty"); diff != "" {
+		filter["difficulty"] = diff
+	}
+	if status := c.Query("status"); status != "" {
+		filter["review_status"] = status
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 20
+	}
+	limit = min(limit, services.MaxReviewQueueLimit)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	questions, total, err := ctrl.service.ReviewQueue(ctx, filter, page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch review queue"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"questions": questions,
+		"total":     total,
+		"page":      page,
+		"limit":     limit,
+	})
+}
+
+// GET /api/admin/questions/duplicates
+// Query params: category, sub_category, difficulty, threshold (Jaccard similarity, default 0.6)
+// Scans the live bank for exact duplicates and near-duplicates within each category.
+func (ctrl *QuestionBankController) ScanDuplicates(c *gin.Context) {
+	filter := bson.M{}
+	if cat := c.Query("category"); cat != "" {
+		filter["category"] = cat
+	}
+	if sub := c.Query("sub_category"); sub != "" {
+		filter["sub_category"] = sub
+	}
+	if diff := c.Query("difficulty"); diff != "" {
+		filter["difficulty"] = diff
+	}
+
+	threshold := services.DefaultNearDuplicateThreshold
+	if raw := c.Query("threshold"); raw != "" {
+		parsed, err := strconv.ParseFloat(raw, 64)
+		if err != nil || parsed <= 0 || parsed > 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "threshold must be a number in (0, 1]"})
+			return
+		}
+		threshold = parsed
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	report, err := ctrl.service.ScanDuplicates(ctx, filter, threshold)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan for duplicates"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// POST /api/admin/questions/import/qti
+// Multipart field "file": a QTI 2.1 or 3.0 content package (zip) or a single item XML.
+// Query params: category, sub_category, difficulty (applied to every item), dry_run.
+// Items go through the same validation as a CSV dry run; without dry_run the valid items
+// are imported straight away.
+func (ctrl *QuestionBankController) ImportQTI(c *gin.Context) {
+	file, _, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded"})
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxQTIUploadSize+1))
+	if err != nil || len(data) > maxQTIUploadSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("File must be at most %d MB", maxQTIUploadSize>>20)})
+		return
+	}
+
+	rows, err := services.ReadQTIPackage(data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	for _, row := range rows {
+		if row.Entry != nil {
+			row.Entry.Category = c.Query("category")
+			row.Entry.SubCategory = c.Query("sub_category")
+			row.Entry.Difficulty = c.Query("difficulty")
+		}
+	}
+
+	if c.Query("dry_run") == "true" {
+		ctrl.stageImport(c, rows)
+		return
+	}
+	ctrl.importRows(c, rows, "QTI import completed")
+}
+
+// POST /api/admin/questions/import/text?format=gift|aiken
+// Multipart field "file". The format defaults from a .gift or .aiken file extension.
+// Query params: category, sub_category (take precedence over GIFT $CATEGORY), difficulty
+// (for questions that do not set their own), dry_run. Questions go through the same
+// validation and report as a CSV dry run; those embedding images are reported as errors.
+func (ctrl *QuestionBankController) ImportText(c *gin.Context) {
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded"})
+		return
+	}
+	defer file.Close()
+
+	format := strings.ToLower(c.Query("format"))
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(header.Filename)), ".")
+	}
+
+	data, err := io.ReadAll(io.LimitReader(file, maxTextImportSize+1))
+	if err != nil || len(data) > maxTextImportSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("File must be at most %d MB", maxTextImportSize>>20)})
+		return
+	}
+
+	rows, err := services.ReadQuestionText(format, string(data))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	for _, row := range rows {
+		if row.Entry == nil {
+			continue
+		}
+		if cat := c.Query("category"); cat != "" {
+			row.Entry.Category = cat
+			row.Entry.SubCategory = c.Query("sub_category")
+		}
+		if row.Entry.Difficulty == "" {
+			row.Entry.Difficulty = c.Query("difficulty")
+		}
+	}
+
+	if c.Query("dry_run") == "true" {
+		ctrl.stageImport(c, rows)
+		return
+	}
+	ctrl.importRows(c, rows, "Import completed")
+}
+
+// importRows validates the rows like a dry run and imports the valid ones in one batch,
+// reporting every rejected row.
+func (ctrl *QuestionBankController) importRows(c *gin.Context, rows []services.ImportRow, message string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	report, err := ctrl.service.StageImport(ctx, rows)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate import"})
+		return
+	}
+	result := &services.ImportResult{Duplicates: []models.ImportDuplicate{}}
+	if report.ImportToken != "" {
+		result, err = ctrl.service.ConfirmImport(ctx, report.ImportToken, questionAuthor(c))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import questions"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":         message,
+		"imported_count":  result.ImportedCount,
+		"skipped_count":   report.InvalidRows + len(result.Duplicates),
+		"duplicate_count": len(result.Duplicates),
+		"duplicates":      result.Duplicates,
+		"errors":          report.Errors,
+		"warnings":        report.Warnings,
+	})
+}
+
+// GET /api/admin/questions/export/qti
+// Query params: version (2.1 or 3.0, default 3.0) and the filters of ListQuestions.
+// Returns a QTI content package. Templated questions and questions with media are left out
+// and counted in the X-Skipped-Questions header.
+func (ctrl *QuestionBankController) ExportQTI(c *gin.Context) {
+	version := c.DefaultQuery("version", services.QTIVersion30)
+	filter := listQuestionsFilter(c)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{
+		{Key: "category", Value: 1},
+		{Key: "sub_category", Value: 1},
+		{Key: "difficulty", Value: 1},
+		{Key: "_id", Value: 1},
+	})
+	questions, err := ctrl.repo.Find(ctx, filter, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch questions"})
+		return
+	}
+
+	var buf bytes.Buffer
+	skipped, err := services.WriteQTIPackage(&buf, questions, version)
+	if errors.Is(err, services.ErrUnsupportedQTIVersion) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build QTI package"})
+		return
+	}
+
+	filename := fmt.Sprintf("question-bank-qti%s.zip", strings.ReplaceAll(version, ".", ""))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Header("X-Skipped-Questions", strconv.Itoa(skipped))
+	c.Data(http.StatusOK, "application/zip", buf.Bytes())
+}
+
+// POST /api/admin/questions/:id/restore
+func (ctrl *QuestionBankController) RestoreQuestion(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid question ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	restored, err := ctrl.service.RestoreQuestions(ctx, bson.M{"_id": id}, questionAuthor(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore question"})
+		return
+	}
+	if restored == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Question not found or not retired"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Question restored successfully"})
+}
+
+// POST /api/admin/questions/restore
+// Query params: category, sub_category, difficulty — restores the retired entries of a slot
+func (ctrl *QuestionBankController) RestoreQuestionsByFilter(c *gin.Context) {
+	filter, ok := slotFilter(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	restored, err := ctrl.service.RestoreQuestions(ctx, filter, questionAuthor(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore questions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Questions restored successfully", "restored_count": restored})
+}
+
+func respondRetireError(c *gin.Context, report *models.RetirementReport, err error, fallback string) {
+	if errors.Is(err, services.ErrRetirementUnderstocks) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":  "Retiring would leave live assessments short of questions; repeat with force=true to go ahead",
+			"report": report,
+		})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+}
+
+// slotFilter builds the category/difficulty filter shared by the bulk endpoints.
+func slotFilter(c *gin.Context) (bson.M, bool) {
 	category := strings.TrimSpace(c.Query("category"))
 	difficulty := strings.TrimSpace(c.Query("difficulty"))
 	subCategory := strings.TrimSpace(c.Query("sub_category"))
 
 	if category == "" || difficulty == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "category and difficulty are required"})
-		return
+		return nil, false
 	}
 
 	filter := bson.M{
@@ -457,23 +1358,38 @@ func (ctrl *QuestionBankController) DeleteQuestionsByFilter(c *gin.Context) {
 	if subCategory != "" {
 		filter["sub_category"] = subCategory
 	}
+	return filter, true
+}
+
+// DELETE /api/admin/questions
+// Query params: category, sub_category, difficulty, force
+// Entries are retired, not removed. If that would leave an open assessment's rule with
+// fewer live entries than it samples, nothing is retired and 409 reports the affected
+// rules; force=true retires anyway.
+func (ctrl *QuestionBankController) DeleteQuestionsByFilter(c *gin.Context) {
+	filter, ok := slotFilter(c)
+	if !ok {
+		return
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	deletedCount, err := ctrl.repo.DeleteByFilter(ctx, filter)
+	report, err := ctrl.service.RetireQuestions(ctx, filter, c.Query("force") == "true", questionAuthor(c))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete questions"})
+		respondRetireError(c, report, err, "Failed to delete questions")
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message":       "Questions deleted successfully",
-		"deleted_count": deletedCount,
+		"message":       "Questions retired successfully",
+		"deleted_count": report.Retired,
+		"report":        report,
 	})
 }
 
 // PUT /api/admin/questions/:id
+// A content change sends an approved question back to the review queue.
 func (ctrl *QuestionBankController) UpdateQuestion(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := primitive.ObjectIDFromHex(idStr)
@@ -488,16 +1404,246 @@ func (ctrl *QuestionBankController) UpdateQuestion(c *gin.Context) {
 		return
 	}
 	entry.ID = id // Ensure ID matches the URL
+	if err := services.ValidateQuestionTemplate(&entry); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	entry.UpdatedBy = questionAuthor(c)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	if err := ctrl.repo.Update(ctx, id, &entry); err != nil {
+	if err := ctrl.mediaService.ResolveAttachments(ctx, &entry); err != nil {
+		if errors.Is(err, services.ErrInvalidAttachment) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update question"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Question updated successfully"})
+	version, err := ctrl.repo.Update(ctx, id, &entry)
+	if err != nil {
+		respondQuestionVersionError(c, err, "Failed to update question")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Question updated successfully", "version": version})
+}
+
+// GET /api/admin/questions/:id/versions
+func (ctrl *QuestionBankController) ListQuestionVersions(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid question ID"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	versions, err := ctrl.repo.ListVersions(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch versions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"versions": versions})
+}
+
+// GET /api/admin/questions/:id/versions/:version
+func (ctrl *QuestionBankController) GetQuestionVersion(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid question ID"})
+		return
+	}
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid version"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	v, err := ctrl.repo.FindVersion(ctx, id, version)
+	if err != nil {
+		respondQuestionVersionError(c, err, "Failed to fetch version")
+		return
+	}
+
+	c.JSON(http.StatusOK, v)
+}
+
+// GET /api/admin/questions/:id/diff?from=1&to=3
+// Compares two versions; "to" defaults to the latest version.
+func (ctrl *QuestionBankController) DiffQuestionVersions(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid question ID"})
+		return
+	}
+	from, err := strconv.Atoi(c.Query("from"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be a version number"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	to := 0
+	if toParam := c.Query("to"); toParam != "" {
+		if to, err = strconv.Atoi(toParam); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be a version number"})
+			return
+		}
+	} else {
+		entry, err := ctrl.repo.FindByID(ctx, id)
+		if err != nil {
+			respondQuestionVersionError(c, err, "Failed to fetch question")
+			return
+		}
+		to = max(entry.Version, 1)
+	}
+
+	fromVersion, err := ctrl.repo.FindVersion(ctx, id, from)
+	if err != nil {
+		respondQuestionVersionError(c, err, "Failed to fetch version")
+		return
+	}
+	toVersion, err := ctrl.repo.FindVersion(ctx, id, to)
+	if err != nil {
+		respondQuestionVersionError(c, err, "Failed to fetch version")
+		return
+	}
+
+	diff, err := services.DiffQuestionVersions(fromVersion, toVersion)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compare versions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, diff)
+}
+
+// POST /api/admin/questions/:id/versions/:version/revert
+// Restores an earlier version's content as a new version; history is never rewritten.
+func (ctrl *QuestionBankController) RevertQuestion(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid question ID"})
+		return
+	}
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid version"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	newVersion, err := ctrl.repo.Revert(ctx, id, version, questionAuthor(c))
+	if err != nil {
+		respondQuestionVersionError(c, err, "Failed to revert question")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Question reverted successfully", "version": newVersion})
+}
+
+// questionAuthor is the signed-in user making an admin edit, if any.
+func questionAuthor(c *gin.Context) primitive.ObjectID {
+	if userID, ok := c.Get("userID"); ok {
+		return userID.(primitive.ObjectID)
+	}
+	return primitive.NilObjectID
+}
+
+// errDuplicateQuestion marks an imported question skipped as an exact duplicate.
+var errDuplicateQuestion = errors.New("exact duplicate of an existing question")
+
+// importDedupe collects the duplicate findings of one import request.
+type importDedupe struct {
+	deduper        *services.ImportDeduper
+	duplicates     []models.ImportDuplicate
+	nearDuplicates []models.ImportDuplicate
+}
+
+func (ctrl *QuestionBankController) newImportDedupe() *importDedupe {
+	return &importDedupe{
+		deduper:        ctrl.service.NewImportDeduper(),
+		duplicates:     []models.ImportDuplicate{},
+		nearDuplicates: []models.ImportDuplicate{},
+	}
+}
+
+// addTo reports the skipped exact duplicates and the near-duplicates that were imported.
+func (d *importDedupe) addTo(response gin.H) {
+	response["duplicate_count"] = len(d.duplicates)
+	response["duplicates"] = d.duplicates
+	response["near_duplicates"] = d.nearDuplicates
+}
+
+// importQuestion creates an imported entry unless it exactly duplicates a live entry or an
+// earlier question of the same import, in which case errDuplicateQuestion is returned.
+// Near-duplicates are imported and flagged for review.
+func (ctrl *QuestionBankController) importQuestion(ctx context.Context, c *gin.Context, dedupe *importDedupe, row int, entry *models.QuestionBankEntry) error {
+	exact, near, err := dedupe.deduper.Check(ctx, entry)
+	if err != nil {
+		return err
+	}
+	if exact != nil {
+		dedupe.duplicates = append(dedupe.duplicates, models.ImportDuplicate{Row: row, Text: entry.Text, Matches: []models.DuplicateMatch{*exact}})
+		return errDuplicateQuestion
+	}
+
+	entry.UpdatedBy = questionAuthor(c)
+	if _, err := ctrl.repo.Create(ctx, entry); err != nil {
+		return err
+	}
+	dedupe.deduper.Add(entry)
+	if len(near) > 0 {
+		dedupe.nearDuplicates = append(dedupe.nearDuplicates, models.ImportDuplicate{Row: row, Text: entry.Text, Matches: near})
+	}
+	return nil
+}
+
+func respondQuestionVersionError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Question or version not found"})
+	case errors.Is(err, repositories.ErrQuestionVersionConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": "Question has changed; reload and retry"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+// POST /api/admin/questions/template-preview?count=3
+// Returns example instances of a templated entry without saving it.
+func (ctrl *QuestionBankController) PreviewTemplate(c *gin.Context) {
+	var entry models.QuestionBankEntry
+	if err := c.ShouldBindJSON(&entry); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	count, _ := strconv.Atoi(c.DefaultQuery("count", "3"))
+	if count < 1 || count > 10 {
+		count = 3
+	}
+
+	questions, err := services.PreviewQuestionTemplate(&entry, count)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"questions": questions})
 }
 
 // POST /api/admin/audio-upload
@@ -541,16 +1687,54 @@ func (ctrl *QuestionBankController) UploadAudio(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"url": publicURL})
 }
 
-func (ctrl *QuestionBankController) handleSmartUpload(c *gin.Context, reader *csv.Reader, firstRow []string, defCat, defSub, defDiff string) {
+func (ctrl *QuestionBankController) handleSmartUpload(c *gin.Context, rows []services.ImportRow) {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
+	config, err := ctrl.repo.GetBankConfig(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load the bank structure"})
+		return
+	}
+
 	var (
+		importedCount int
+		skippedCount  int
+	)
+	dedupe := ctrl.newImportDedupe()
+
+	for _, row := range rows {
+		entry := row.Entry
+		if issues, _ := services.ValidateImportEntry(entry, config); len(issues) > 0 {
+			skippedCount++
+			continue
+		}
+		if err := ctrl.importQuestion(ctx, c, dedupe, row.Row, entry); err == nil {
+			importedCount++
+		} else {
+			skippedCount++
+		}
+	}
+
+	response := gin.H{
+		"message":        "Smart CSV import completed",
+		"imported_count": importedCount,
+		"skipped_count":  skippedCount,
+	}
+	dedupe.addTo(response)
+	c.JSON(http.StatusOK, response)
+}
+
+// readSmartCSVRows parses a vertical list: question lines, then "A) ..." option lines, then
+// an "Answer: B" line. Rows are numbered by the line the question starts on.
+func readSmartCSVRows(reader *csv.Reader, firstRow []string, defCat, defSub, defDiff string) []services.ImportRow {
+	var (
+		rows           []services.ImportRow
 		currentText    []string
 		currentOptions []string
 		correctAnswer  string
-		importedCount  int
-		skippedCount   int
+		lineNumber     int
+		startLine      int
 	)
 
 	finalizeCurrent := func() {
@@ -558,15 +1742,7 @@ func (ctrl *QuestionBankController) handleSmartUpload(c *gin.Context, reader *cs
 			return
 		}
 
-		if defCat == "" || defDiff == "" {
-			skippedCount++
-			currentText = nil
-			currentOptions = nil
-			correctAnswer = ""
-			return
-		}
-
-		entry := &models.QuestionBankEntry{
+		rows = append(rows, services.ImportRow{Row: startLine, Entry: &models.QuestionBankEntry{
 			ID:            primitive.NewObjectID(),
 			Category:      defCat,
 			SubCategory:   defSub,
@@ -575,12 +1751,7 @@ func (ctrl *QuestionBankController) handleSmartUpload(c *gin.Context, reader *cs
 			Text:          strings.Join(currentText, "\n"),
 			Options:       currentOptions,
 			CorrectAnswer: correctAnswer,
-		}
-		if _, err := ctrl.repo.Create(ctx, entry); err == nil {
-			importedCount++
-		} else {
-			skippedCount++
-		}
+		}})
 
 		currentText = nil
 		currentOptions = nil
@@ -626,16 +1797,21 @@ func (ctrl *QuestionBankController) handleSmartUpload(c *gin.Context, reader *cs
 		}
 
 		if !isOption {
+			if len(currentText) == 0 {
+				startLine = lineNumber
+			}
 			currentText = append(currentText, text)
 		}
 	}
 
 	// Process the "header" row because it's actually the first piece of data
+	lineNumber = 1
 	processRow(firstRow)
 
 	// Process remainder
 	for {
 		row, err := reader.Read()
+		lineNumber++
 		if err == io.EOF {
 			break
 		}
@@ -647,10 +1823,5 @@ func (ctrl *QuestionBankController) handleSmartUpload(c *gin.Context, reader *cs
 
 	// Flush the last pending question if the file doesn't end with an explicit answer row.
 	finalizeCurrent()
-
-	c.JSON(http.StatusOK, gin.H{
-		"message":        "Smart CSV import completed",
-		"imported_count": importedCount,
-		"skipped_count":  skippedCount,
-	})
+	return rows
 }
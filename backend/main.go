@@ -22,7 +22,6 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -89,6 +88,9 @@ func main() {
 	if err := os.MkdirAll("./public/audio", 0755); err != nil {
 		logger.Errorf("Failed to create public/audio directory: %v", err)
 	}
+	if err := os.MkdirAll("./public/media", 0755); err != nil {
+		logger.Errorf("Failed to create public/media directory: %v", err)
+	}
 
 	// Set Gin mode
 	gin.SetMode(gin.ReleaseMode)
@@ -143,28 +145,35 @@ func main() {
 	interviewCollection := client.Database("broassess").Collection("interviews")
 	questionBankCollection := client.Database("broassess").Collection("question_bank")
 	questionBankConfigCollection := client.Database("broassess").Collection("question_bank_config")
+	questionBankVersionCollection := client.Database("broassess").Collection("question_bank_versions")
 	auditLogCollection := client.Database("broassess").Collection("audit_logs")
-
-	// Create Indexes
-	_, _ = submissionCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
-		Keys:    bson.D{{Key: "assessment_id", Value: 1}, {Key: "candidate_id", Value: 1}},
-		Options: options.Index().SetUnique(true),
-	})
+	invitationCollection := client.Database("broassess").Collection("invitations")
+	accommodationCollection := client.Database("broassess").Collection("accommodations")
+	mediaCollection := client.Database("broassess").Collection("media")
 
 	// Initialize Repositories
 	userRepo := repositories.NewUserRepository(userCollection)
 	assessRepo := repositories.NewAssessmentRepository(assessmentCollection)
 	subRepo := repositories.NewSubmissionRepository(submissionCollection)
 	interviewRepo := repositories.NewInterviewRepository(interviewCollection)
-	qbRepo := repositories.NewQuestionBankRepository(questionBankCollection, questionBankConfigCollection)
+	qbRepo := repositories.NewQuestionBankRepository(questionBankCollection, questionBankConfigCollection, questionBankVersionCollection)
 	auditLogRepo := repositories.NewAuditLogRepository(auditLogCollection)
+	invitationRepo := repositories.NewInvitationRepository(invitationCollection)
+	accommodationRepo := repositories.NewAccommodationRepository(accommodationCollection)
+	mediaRepo := repositories.NewMediaRepository(mediaCollection)
 
 	// Initialize Services
 	authService := services.NewAuthService(userRepo)
 	assessService := services.NewAssessmentService(assessRepo, qbRepo)
 	auditLogService := services.NewAuditLogService(auditLogRepo)
-	submissionService := services.NewSubmissionService(subRepo, assessRepo, userRepo, qbRepo, auditLogService)
+	submissionService := services.NewSubmissionService(subRepo, assessRepo, userRepo, qbRepo, invitationRepo, accommodationRepo, auditLogService)
+	accommodationService := services.NewAccommodationService(accommodationRepo, userRepo)
+	invitationService := services.NewInvitationService(invitationRepo, assessRepo, userRepo, authService)
 	interviewService := services.NewInterviewService(interviewRepo)
+	itemAnalysisService := services.NewItemAnalysisService(subRepo, qbRepo)
+	questionBankService := services.NewQuestionBankService(qbRepo, assessRepo, auditLogService)
+	mediaService := services.NewMediaService(mediaRepo, "./public/media")
+	bundleService := services.NewAssessmentBundleService(assessService, assessRepo, qbRepo, mediaService, "./public/audio")
 	candidateConsumer := services.NewCandidateDetailsConsumer(userRepo)
 
 	// Initialize Controllers
@@ -175,7 +184,12 @@ func main() {
 	assessCtrl := controllers.NewAssessmentController(assessService, submissionService)
 	interviewCtrl := controllers.NewInterviewController(interviewService)
 	teleProxyCtrl := controllers.NewTelegramProxyController()
-	questionBankController := controllers.NewQuestionBankController(qbRepo) // Initialize QuestionBankController
+	invitationCtrl := controllers.NewInvitationController(invitationService)
+	accommodationCtrl := controllers.NewAccommodationController(accommodationService)
+	questionBankController := controllers.NewQuestionBankController(qbRepo, questionBankService, mediaService) // Initialize QuestionBankController
+	itemAnalysisCtrl := controllers.NewItemAnalysisController(itemAnalysisService)
+	bundleCtrl := controllers.NewAssessmentBundleController(bundleService)
+	mediaCtrl := controllers.NewMediaController(mediaService)
 
 	// Initialize Router with custom middleware for better performance
 	router := gin.New()
@@ -201,8 +215,8 @@ func main() {
 			return isAllowedOrigin(origin, frontendURL)
 		},
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Requested-With"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Requested-With", "If-Match"},
+		ExposeHeaders:    []string{"Content-Length", "ETag"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
@@ -219,22 +233,49 @@ func main() {
 	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
 
 	// Setup Routes
-	routes.SetupRoutes(router, authCtrl, googleCtrl, youtubeCtrl, publicCtrl, assessCtrl, interviewCtrl)
+	routes.SetupRoutes(router, authCtrl, googleCtrl, youtubeCtrl, publicCtrl, assessCtrl, interviewCtrl, invitationCtrl, accommodationCtrl, bundleCtrl)
 
 	// Admin Question Bank routes
-	router.POST("/api/admin/questions/import", questionBankController.ImportQuestions)
+	router.POST("/api/admin/questions/import", middleware.OptionalAuthMiddleware(), questionBankController.ImportQuestions)
+	router.POST("/api/admin/questions/import/qti", middleware.OptionalAuthMiddleware(), questionBankController.ImportQTI)
+	router.POST("/api/admin/questions/import/text", middleware.OptionalAuthMiddleware(), questionBankController.ImportText)
+	router.GET("/api/admin/questions/export", questionBankController.ExportQuestions)
+	router.GET("/api/admin/questions/export/qti", questionBankController.ExportQTI)
 	router.POST("/api/admin/questions/structure", questionBankController.SaveStructure)
+	router.POST("/api/admin/questions/structure/operations", middleware.OptionalAuthMiddleware(), questionBankController.ApplyStructureOperation)
 	router.GET("/api/admin/questions/config", questionBankController.GetConfig)
+	router.GET("/api/admin/questions/stats", questionBankController.GetStats)
 	router.GET("/api/admin/questions", questionBankController.ListQuestions)
-	router.DELETE("/api/admin/questions", questionBankController.DeleteQuestionsByFilter)
+	router.DELETE("/api/admin/questions", middleware.OptionalAuthMiddleware(), questionBankController.DeleteQuestionsByFilter)
+	router.POST("/api/admin/questions/restore", middleware.OptionalAuthMiddleware(), questionBankController.RestoreQuestionsByFilter)
+	router.POST("/api/admin/questions/bulk-update", middleware.OptionalAuthMiddleware(), questionBankController.BulkUpdateQuestions)
 	router.GET("/api/admin/questions/count", questionBankController.CountQuestions)
-	router.POST("/api/admin/questions/upload-csv", questionBankController.UploadCSV)
-	router.PUT("/api/admin/questions/:id", questionBankController.UpdateQuestion)
-	router.DELETE("/api/admin/questions/:id", questionBankController.DeleteQuestion)
+	router.GET("/api/admin/questions/review-queue", questionBankController.ReviewQueue)
+	router.GET("/api/admin/questions/duplicates", questionBankController.ScanDuplicates)
+	router.GET("/api/admin/questions/search", questionBankController.SearchQuestions)
+	router.POST("/api/admin/questions/item-analysis", itemAnalysisCtrl.RunItemAnalysis)
+	router.GET("/api/admin/questions/item-analysis", itemAnalysisCtrl.GetItemAnalysisJob)
+	router.GET("/api/admin/questions/calibration/suggestions", itemAnalysisCtrl.ListDifficultySuggestions)
+	router.POST("/api/admin/questions/calibration/accept", itemAnalysisCtrl.AcceptDifficultySuggestions)
+	router.POST("/api/admin/questions/upload-csv", middleware.OptionalAuthMiddleware(), questionBankController.UploadCSV)
+	router.POST("/api/admin/questions/upload-csv/confirm", middleware.OptionalAuthMiddleware(), questionBankController.ConfirmImport)
+	router.POST("/api/admin/questions/template-preview", questionBankController.PreviewTemplate)
+	router.PUT("/api/admin/questions/:id", middleware.OptionalAuthMiddleware(), questionBankController.UpdateQuestion)
+	router.GET("/api/admin/questions/:id/versions", questionBankController.ListQuestionVersions)
+	router.GET("/api/admin/questions/:id/versions/:version", questionBankController.GetQuestionVersion)
+	router.POST("/api/admin/questions/:id/versions/:version/revert", middleware.OptionalAuthMiddleware(), questionBankController.RevertQuestion)
+	router.GET("/api/admin/questions/:id/diff", questionBankController.DiffQuestionVersions)
+	router.DELETE("/api/admin/questions/:id", middleware.OptionalAuthMiddleware(), questionBankController.DeleteQuestion)
+	router.POST("/api/admin/questions/:id/restore", middleware.OptionalAuthMiddleware(), questionBankController.RestoreQuestion)
+	router.POST("/api/admin/questions/:id/review", middleware.OptionalAuthMiddleware(), questionBankController.ReviewQuestion)
 	// Audio upload for Listening questions
 	router.POST("/api/admin/audio-upload", questionBankController.UploadAudio)
 	// Serve uploaded audio files
 	router.Static("/audio", "./public/audio")
+	// Images and audio attached to questions, referenced by media ID
+	router.POST("/api/admin/media", middleware.OptionalAuthMiddleware(), mediaCtrl.UploadMedia)
+	router.GET("/api/admin/media/:id", mediaCtrl.GetMedia)
+	router.GET("/media/:id", mediaCtrl.ServeMedia)
 
 	router.GET("/api/telegram/image/:fileId", teleProxyCtrl.GetTelegramImage)
 
@@ -264,6 +305,8 @@ func main() {
 	}
 	defer candidateConsumer.Close()
 
+	itemAnalysisService.StartSchedule(consumerCtx)
+
 	// Start Server in a goroutine for graceful shutdown
 	go func() {
 		logger.Infof("Server running on port %s", port)
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
@@ -34,38 +35,60 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// Remove "Bearer " prefix - optimized string operation
-		if strings.HasPrefix(tokenString, "Bearer ") || strings.HasPrefix(tokenString, "bearer ") {
-			tokenString = tokenString[7:]
+		claims, err := parseBearerToken(tokenString)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			c.Abort()
+			return
 		}
+		setUserClaims(c, claims)
 
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return getJWTSecret(), nil
-		})
+		c.Next()
+	}
+}
 
-		if err != nil || !token.Valid {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
-			c.Abort()
-			return
+// OptionalAuthMiddleware sets userID and role when a valid token is sent but lets
+// anonymous requests through. It is used to attribute admin edits to their author.
+func OptionalAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if tokenString := c.GetHeader("Authorization"); tokenString != "" {
+			if claims, err := parseBearerToken(tokenString); err == nil {
+				setUserClaims(c, claims)
+			}
 		}
+		c.Next()
+	}
+}
 
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if ok && token.Valid {
-			// Convert sub (string) to ObjectID for easier use in controllers
-			userIDStr, _ := claims["sub"].(string)
-			userID, _ := primitive.ObjectIDFromHex(userIDStr)
+func parseBearerToken(tokenString string) (jwt.MapClaims, error) {
+	// Remove "Bearer " prefix - optimized string operation
+	if strings.HasPrefix(tokenString, "Bearer ") || strings.HasPrefix(tokenString, "bearer ") {
+		tokenString = tokenString[7:]
+	}
 
-			c.Set("userID", userID)
-			c.Set("role", claims["role"])
-		} else {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
-			c.Abort()
-			return
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
+		return getJWTSecret(), nil
+	})
 
-		c.Next()
+	if err != nil || !token.Valid {
+		return nil, errors.New("Invalid token")
 	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, errors.New("Invalid token claims")
+	}
+	return claims, nil
+}
+
+func setUserClaims(c *gin.Context, claims jwt.MapClaims) {
+	// Convert sub (string) to ObjectID for easier use in controllers
+	userIDStr, _ := claims["sub"].(string)
+	userID, _ := primitive.ObjectIDFromHex(userIDStr)
+
+	c.Set("userID", userID)
+	c.Set("role", claims["role"])
 }
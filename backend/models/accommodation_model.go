@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Accommodation records documented adjustments for a candidate. When AssessmentID is
+// nil it applies to every assessment the candidate takes; an assessment-specific
+// record takes precedence over a general one.
+type Accommodation struct {
+	ID                       primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	CandidateID              primitive.ObjectID  `bson:"candidate_id" json:"candidate_id"`
+	AssessmentID             *primitive.ObjectID `bson:"assessment_id,omitempty" json:"assessment_id,omitempty"`
+	TimeMultiplier           float64             `bson:"time_multiplier,omitempty" json:"time_multiplier,omitempty"` // e.g. 1.5 for time and a half
+	ExtraMinutes             int                 `bson:"extra_minutes,omitempty" json:"extra_minutes,omitempty"`
+	ExtendedUntil            *time.Time          `bson:"extended_until,omitempty" json:"extended_until,omitempty"`                         // Overrides Assessment.ClosesAt for this candidate
+	DisabledProctoringChecks []string            `bson:"disabled_proctoring_checks,omitempty" json:"disabled_proctoring_checks,omitempty"` // Violation types, e.g. "tab_switch"
+	Reason                   string              `bson:"reason" json:"reason"`
+	CreatedBy                primitive.ObjectID  `bson:"created_by" json:"created_by"`
+	CreatedAt                time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt                time.Time           `bson:"updated_at" json:"updated_at"`
+	DeletedAt                *time.Time          `bson:"deleted_at,omitempty" json:"-"`
+}
+
+// AppliedAccommodation is the snapshot stored on a submission so auditors can see
+// exactly which adjustments were in force for that attempt.
+type AppliedAccommodation struct {
+	AccommodationID          primitive.ObjectID `bson:"accommodation_id" json:"accommodation_id"`
+	TimeMultiplier           float64            `bson:"time_multiplier,omitempty" json:"time_multiplier,omitempty"`
+	ExtraMinutes             int                `bson:"extra_minutes,omitempty" json:"extra_minutes,omitempty"`
+	BaseDuration             int                `bson:"base_duration" json:"base_duration"`           // Assessment duration in minutes
+	EffectiveDuration        int                `bson:"effective_duration" json:"effective_duration"` // After accommodation, in minutes
+	ExtendedUntil            *time.Time         `bson:"extended_until,omitempty" json:"extended_until,omitempty"`
+	DisabledProctoringChecks []string           `bson:"disabled_proctoring_checks,omitempty" json:"disabled_proctoring_checks,omitempty"`
+	Reason                   string             `bson:"reason" json:"reason"`
+	AppliedAt                time.Time          `bson:"applied_at" json:"applied_at"`
+}
+
+// CreateAccommodationRequest represents the request to record an accommodation for a candidate
+type CreateAccommodationRequest struct {
+	CandidateID  string `json:"candidate_id" binding:"required"`
+	AssessmentID string `json:"assessment_id"` // Empty applies to all assessments
+	UpdateAccommodationRequest
+}
+
+// UpdateAccommodationRequest represents the adjustable parts of an accommodation
+type UpdateAccommodationRequest struct {
+	TimeMultiplier           float64    `json:"time_multiplier" binding:"omitempty,min=1,max=4"`
+	ExtraMinutes             int        `json:"extra_minutes" binding:"omitempty,min=0,max=600"`
+	ExtendedUntil            *time.Time `json:"extended_until"`
+	DisabledProctoringChecks []string   `json:"disabled_proctoring_checks"`
+	Reason                   string     `json:"reason" binding:"required"`
+}
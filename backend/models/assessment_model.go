@@ -23,7 +23,10 @@ type Question struct {
 	Options       []string           `bson:"options,omitempty" json:"options,omitempty"` // For MCQ
 	CorrectAnswer string             `bson:"correct_answer,omitempty" json:"correct_answer,omitempty"`
 	Points        int                `bson:"points" json:"points" binding:"required"`
-	AudioURL      string             `bson:"audio_url,omitempty" json:"audio_url,omitempty"` // For Listening questions
+	AudioURL      string             `bson:"audio_url,omitempty" json:"audio_url,omitempty"`           // For Listening questions
+	Media         []MediaAttachment  `bson:"media,omitempty" json:"media,omitempty"`                   // Copied from the bank entry
+	Parameters    map[string]float64 `bson:"parameters,omitempty" json:"parameters,omitempty"`         // Values drawn for a templated entry
+	SourceVersion int                `bson:"source_version,omitempty" json:"source_version,omitempty"` // Bank entry version the question was sampled from
 }
 
 type QuestionRule struct {
@@ -34,20 +37,98 @@ type QuestionRule struct {
 	PointsPerQuestion int    `bson:"points_per_question" json:"points_per_question" binding:"required"`
 	DisplayOrder      int    `bson:"display_order,omitempty" json:"display_order,omitempty"`
 	AudioURL          string `bson:"audio_url,omitempty" json:"audio_url,omitempty"`
+
+	// Narrow the slot further: entries need every included tag, none of the excluded ones
+	// and, when skills are given, one of them or a sub-skill
+	IncludeTags []string `bson:"include_tags,omitempty" json:"include_tags,omitempty"`
+	ExcludeTags []string `bson:"exclude_tags,omitempty" json:"exclude_tags,omitempty"`
+	Skills      []string `bson:"skills,omitempty" json:"skills,omitempty"`
+}
+
+const (
+	ScoringPolicyBest   = "best"
+	ScoringPolicyLatest = "latest"
+)
+
+// RetakePolicy controls how many times a candidate may attempt an assessment
+// and which attempt is reported to interviewers.
+type RetakePolicy struct {
+	MaxAttempts     int    `bson:"max_attempts" json:"max_attempts"`                             // 0 or 1 means a single attempt
+	CooldownMinutes int    `bson:"cooldown_minutes,omitempty" json:"cooldown_minutes,omitempty"` // Wait after a completed attempt
+	ScoringPolicy   string `bson:"scoring_policy,omitempty" json:"scoring_policy,omitempty"`     // "best" or "latest" (default)
+}
+
+const (
+	AssessmentModeFixed    = "fixed"
+	AssessmentModeAdaptive = "adaptive"
+)
+
+// AdaptiveConfig drives computerized adaptive testing: items are drawn one at a time from
+// a single bank slot, each chosen to match the candidate's current ability estimate.
+type AdaptiveConfig struct {
+	Category    string  `bson:"category" json:"category" binding:"required"`
+	SubCategory string  `bson:"sub_category,omitempty" json:"sub_category,omitempty"`
+	MinItems    int     `bson:"min_items,omitempty" json:"min_items,omitempty"` // Default 5
+	MaxItems    int     `bson:"max_items,omitempty" json:"max_items,omitempty"` // Default 20
+	TargetSE    float64 `bson:"target_se,omitempty" json:"target_se,omitempty"` // Stop once the ability standard error reaches this; default 0.45
 }
 
 type Assessment struct {
 	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	Title         string             `bson:"title" json:"title" binding:"required"`
 	Description   string             `bson:"description" json:"description"`
-	Duration      int                `bson:"duration" json:"duration"` // In minutes
+	Duration      int                `bson:"duration" json:"duration"`                       // In minutes
+	ClosesAt      *time.Time         `bson:"closes_at,omitempty" json:"closes_at,omitempty"` // No new attempts after this, unless accommodated
 	QuestionRules []QuestionRule     `bson:"question_rules" json:"question_rules"`
-	Questions     []Question         `json:"questions,omitempty" bson:"-"` // Virtual field for API response
+	Mode          string             `bson:"mode,omitempty" json:"mode,omitempty"`         // "fixed" (default) or "adaptive"
+	Adaptive      *AdaptiveConfig    `bson:"adaptive,omitempty" json:"adaptive,omitempty"` // Required in adaptive mode instead of question rules
+	RetakePolicy  *RetakePolicy      `bson:"retake_policy,omitempty" json:"retake_policy,omitempty"`
+	Questions     []Question         `json:"questions,omitempty" bson:"-"`                  // Virtual field for API response
+	DeadlineAt    *time.Time         `json:"deadline_at,omitempty" bson:"-"`                // Virtual: candidate's attempt deadline
+	WaivedChecks  []string           `json:"disabled_proctoring_checks,omitempty" bson:"-"` // Virtual: checks waived for the candidate
 	CreatedBy     primitive.ObjectID `bson:"created_by" json:"created_by"`
 	CreatedAt     time.Time          `bson:"created_at" json:"created_at"`
 	UpdatedAt     time.Time          `bson:"updated_at" json:"updated_at"`
 
-	PassingScore int        `bson:"passing_score" json:"passing_score"` // Minimum score to pass
+	PassingScore int        `bson:"passing_score" json:"passing_score"` // Minimum score to pass; on the scaled score in adaptive mode
 	TotalMarks   int        `bson:"total_marks" json:"total_marks"`     // Sum of all question points
 	DeletedAt    *time.Time `bson:"deleted_at,omitempty" json:"-"`      // For soft delete
 }
+
+// BundleFile is a file of an assessment bundle with its size and SHA-256 checksum
+type BundleFile struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// BundleManifest describes an assessment bundle. Every other file in the archive is listed
+// with its checksum.
+type BundleManifest struct {
+	Format             string             `json:"format"`
+	Version            int                `json:"version"`
+	ExportedAt         time.Time          `json:"exported_at"`
+	SourceAssessmentID primitive.ObjectID `json:"source_assessment_id"`
+	Title              string             `json:"title"`
+	QuestionCount      int                `json:"question_count"`
+	AudioCount         int                `json:"audio_count"`
+	MediaCount         int                `json:"media_count,omitempty"`
+	Files              []BundleFile       `json:"files"`
+}
+
+// BundleImportReport is the outcome of importing an assessment bundle
+type BundleImportReport struct {
+	AssessmentID      primitive.ObjectID `json:"assessment_id"`
+	Title             string             `json:"title"`
+	Renamed           bool               `json:"renamed"` // The bundle's title was taken
+	QuestionsCreated  int                `json:"questions_created"`
+	QuestionsReused   int                `json:"questions_reused"` // Identical entries already in the bank
+	AudioFilesWritten int                `json:"audio_files_written"`
+	AudioFilesReused  int                `json:"audio_files_reused"`
+	MediaFilesWritten int                `json:"media_files_written"`
+	MediaFilesReused  int                `json:"media_files_reused"`
+	StructureAdded    []string           `json:"structure_added"` // Categories, sub-categories and difficulties added to the bank structure
+	IDMap             map[string]string  `json:"id_map"`          // Bundle ID to ID on this server, for the assessment and its questions
+	AudioMap          map[string]string  `json:"audio_map"`       // Bundle audio URL to URL on this server
+	MediaMap          map[string]string  `json:"media_map"`       // Bundle media ID to ID on this server
+}
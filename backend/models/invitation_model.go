@@ -0,0 +1,78 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	InvitationStatusSent      = "sent"
+	InvitationStatusOpened    = "opened"
+	InvitationStatusStarted   = "started"
+	InvitationStatusCompleted = "completed"
+	InvitationStatusRevoked   = "revoked"
+)
+
+// Invitation ties a candidate to an assessment through a signed, single-use link
+type Invitation struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	AssessmentID   primitive.ObjectID `bson:"assessment_id" json:"assessment_id"`
+	CandidateID    primitive.ObjectID `bson:"candidate_id" json:"candidate_id"`
+	CandidateName  string             `bson:"candidate_name" json:"candidate_name"`
+	CandidateEmail string             `bson:"candidate_email" json:"candidate_email"`
+	CandidatePhone string             `bson:"candidate_phone,omitempty" json:"candidate_phone,omitempty"`
+	TokenID        string             `bson:"token_id" json:"-"`    // Matches the "jti" claim of the signed link
+	Status         string             `bson:"status" json:"status"` // sent, opened, started, completed, revoked
+	ExpiresAt      time.Time          `bson:"expires_at" json:"expires_at"`
+	SentAt         time.Time          `bson:"sent_at" json:"sent_at"`
+	OpenedAt       *time.Time         `bson:"opened_at,omitempty" json:"opened_at,omitempty"` // Set once the link is consumed
+	StartedAt      *time.Time         `bson:"started_at,omitempty" json:"started_at,omitempty"`
+	CompletedAt    *time.Time         `bson:"completed_at,omitempty" json:"completed_at,omitempty"`
+	CreatedBy      primitive.ObjectID `bson:"created_by" json:"created_by"`
+	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time          `bson:"updated_at" json:"updated_at"`
+	DeletedAt      *time.Time         `bson:"deleted_at,omitempty" json:"-"`
+}
+
+// CreateInvitationRequest represents the request to invite a candidate to an assessment
+type CreateInvitationRequest struct {
+	Name           string `json:"name" binding:"required"`
+	Email          string `json:"email" binding:"required,email"`
+	Phone          string `json:"phone"`
+	ExpiresInHours int    `json:"expires_in_hours" binding:"omitempty,min=1,max=720"`
+}
+
+const (
+	BulkRowCreated      = "created"
+	BulkRowExisting     = "existing"
+	BulkRowInvalidPhone = "invalid_phone"
+	BulkRowDuplicate    = "duplicate"
+	BulkRowInvalid      = "invalid"
+	BulkRowFailed       = "failed"
+)
+
+// BulkInvitationRow is the per-row outcome of a CSV invitation upload
+type BulkInvitationRow struct {
+	Row          int                `json:"row"`
+	Name         string             `json:"name"`
+	Email        string             `json:"email"`
+	Phone        string             `json:"phone,omitempty"`
+	Status       string             `json:"status"` // created, existing, invalid_phone, duplicate, invalid, failed
+	InvitationID primitive.ObjectID `json:"invitation_id,omitempty"`
+	InviteURL    string             `json:"invite_url,omitempty"`
+	Error        string             `json:"error,omitempty"`
+}
+
+// BulkInvitationJob tracks a CSV invitation upload, which runs in the background for large files
+type BulkInvitationJob struct {
+	ID            string              `json:"id"`
+	AssessmentID  primitive.ObjectID  `json:"assessment_id"`
+	Status        string              `json:"status"` // processing, completed
+	TotalRows     int                 `json:"total_rows"`
+	ProcessedRows int                 `json:"processed_rows"`
+	Summary       map[string]int      `json:"summary"`
+	Rows          []BulkInvitationRow `json:"rows"`
+	CreatedAt     time.Time           `json:"created_at"`
+	CompletedAt   *time.Time          `json:"completed_at,omitempty"`
+}
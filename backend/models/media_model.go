@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	MediaKindImage = "image"
+	MediaKindAudio = "audio"
+)
+
+// Media is an uploaded image or audio file. Uploads are content-addressed: the same file
+// uploaded twice is stored once. The content type is sniffed from the file itself.
+type Media struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Kind         string             `bson:"kind" json:"kind"` // "image" or "audio"
+	ContentType  string             `bson:"content_type" json:"content_type"`
+	FileName     string             `bson:"file_name" json:"-"` // Name in the media directory
+	OriginalName string             `bson:"original_name,omitempty" json:"original_name,omitempty"`
+	Size         int64              `bson:"size" json:"size"`
+	SHA256       string             `bson:"sha256" json:"sha256"`
+	Width        int                `bson:"width,omitempty" json:"width,omitempty"` // Images other than WebP
+	Height       int                `bson:"height,omitempty" json:"height,omitempty"`
+	URL          string             `bson:"-" json:"url"`
+	UploadedBy   primitive.ObjectID `bson:"uploaded_by,omitempty" json:"uploaded_by,omitempty"`
+	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// MediaAttachment attaches uploaded media to a question's stem or, when Option is set, to
+// one of its options. Kind and URL are filled in from the media when the entry is saved.
+type MediaAttachment struct {
+	MediaID primitive.ObjectID `bson:"media_id" json:"media_id"`
+	Option  *int               `bson:"option,omitempty" json:"option,omitempty"` // Index into the options
+	AltText string             `bson:"alt_text,omitempty" json:"alt_text,omitempty"`
+	Caption string             `bson:"caption,omitempty" json:"caption,omitempty"`
+	Kind    string             `bson:"kind,omitempty" json:"kind,omitempty"`
+	URL     string             `bson:"url,omitempty" json:"url,omitempty"`
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"time"
+
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -15,7 +17,386 @@ type QuestionBankEntry struct {
 	Type          QuestionType       `bson:"type" json:"type"` // "MCQ", "CODING", "SUBJECTIVE"
 	Options       []string           `bson:"options,omitempty" json:"options,omitempty"`
 	CorrectAnswer string             `bson:"correct_answer,omitempty" json:"correct_answer,omitempty"`
-	AudioURL      string             `bson:"audio_url,omitempty" json:"audio_url,omitempty"` // For Listening questions
+	AudioURL      string             `bson:"audio_url,omitempty" json:"audio_url,omitempty"`                     // For Listening questions
+	ItemStats     *ItemStatistics    `bson:"item_stats,omitempty" json:"item_stats,omitempty"`                   // Written by the item analysis job
+	Calibration   *ItemCalibration   `bson:"calibration,omitempty" json:"calibration,omitempty"`                 // Written by the item analysis job
+	Template      *QuestionTemplate  `bson:"template,omitempty" json:"template,omitempty"`                       // Set on parameterised entries
+	Tags          []string           `bson:"tags,omitempty" json:"tags,omitempty"`                               // Free-form labels, stored lowercase
+	Skills        []string           `bson:"skills,omitempty" json:"skills,omitempty"`                           // Paths into the bank's skills taxonomy, e.g. "go/concurrency"
+	Objectives    []string           `bson:"learning_objectives,omitempty" json:"learning_objectives,omitempty"` // What answering the entry demonstrates
+	Media         []MediaAttachment  `bson:"media,omitempty" json:"media,omitempty"`                             // Images and audio on the stem and options
+	Version       int                `bson:"version,omitempty" json:"version"`                                   // Latest entry in question_bank_versions
+	UpdatedAt     time.Time          `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
+	UpdatedBy     primitive.ObjectID `bson:"updated_by,omitempty" json:"updated_by,omitempty"`
+	RetiredAt     *time.Time         `bson:"retired_at,omitempty" json:"retired_at,omitempty"` // Retired entries are kept but never sampled
+	RetiredBy     primitive.ObjectID `bson:"retired_by,omitempty" json:"retired_by,omitempty"`
+	ReviewStatus  string             `bson:"review_status,omitempty" json:"review_status,omitempty"` // Only approved entries are sampled; empty means approved before reviews existed
+	Reviews       []ReviewComment    `bson:"reviews,omitempty" json:"reviews,omitempty"`
+	ContentHash   string             `bson:"content_hash,omitempty" json:"content_hash,omitempty"` // Hash of the normalised text, options and media, for duplicate detection
+}
+
+const (
+	ReviewStatusDraft    = "draft"
+	ReviewStatusInReview = "in_review"
+	ReviewStatusApproved = "approved"
+	ReviewStatusRejected = "rejected"
+)
+
+const (
+	ReviewActionSubmit  = "submit"
+	ReviewActionApprove = "approve"
+	ReviewActionReject  = "reject"
+	ReviewActionComment = "comment"
+)
+
+// ReviewComment records a review action and the reviewer's note on an entry
+type ReviewComment struct {
+	Action    string             `bson:"action" json:"action"`
+	Comment   string             `bson:"comment,omitempty" json:"comment,omitempty"`
+	Status    string             `bson:"status" json:"status"` // Review status after the action
+	AuthorID  primitive.ObjectID `bson:"author_id,omitempty" json:"author_id,omitempty"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
+
+const (
+	QuestionVersionCreate = "create"
+	QuestionVersionUpdate = "update"
+	QuestionVersionRevert = "revert"
+)
+
+// QuestionBankVersion is an immutable snapshot of an entry's authored fields. Derived
+// fields (item statistics, calibration) are not part of the history.
+type QuestionBankVersion struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	EntryID      primitive.ObjectID `bson:"entry_id" json:"entry_id"`
+	Version      int                `bson:"version" json:"version"`
+	Action       string             `bson:"action" json:"action"`
+	RevertedFrom int                `bson:"reverted_from,omitempty" json:"reverted_from,omitempty"` // Version restored by a revert
+	Content      QuestionBankEntry  `bson:"content" json:"content"`
+	AuthorID     primitive.ObjectID `bson:"author_id,omitempty" json:"author_id,omitempty"`
+	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// UnderstockedRule is a rule of a live assessment that would sample from fewer
+// sampleable entries than it needs once a retirement goes ahead
+type UnderstockedRule struct {
+	AssessmentID    primitive.ObjectID `json:"assessment_id"`
+	AssessmentTitle string             `json:"assessment_title"`
+	Category        string             `json:"category"`
+	SubCategory     string             `json:"sub_category,omitempty"`
+	Difficulty      string             `json:"difficulty,omitempty"` // Empty for an adaptive assessment's pool
+	Required        int                `json:"required"`
+	Available       int64              `json:"available"` // Sampleable entries left afterwards
+}
+
+// RetirementReport describes the effect of retiring a set of entries
+type RetirementReport struct {
+	Matched                 int64              `json:"matched"`
+	Retired                 int64              `json:"retired"`
+	UnderstockedAssessments int                `json:"understocked_assessments"`
+	UnderstockedRules       []UnderstockedRule `json:"understocked_rules"`
+}
+
+// DuplicateMatch is a bank entry that a question duplicates or nearly duplicates
+type DuplicateMatch struct {
+	ID         primitive.ObjectID `json:"id"`
+	Category   string             `json:"category"`
+	Text       string             `json:"text"`
+	Similarity float64            `json:"similarity,omitempty"` // Jaccard similarity of the character shingles; 1 for an exact duplicate
+}
+
+// ImportDuplicate is an imported question that matched entries already in the bank or
+// earlier in the same import
+type ImportDuplicate struct {
+	Row     int              `json:"row"`
+	Text    string           `json:"text"`
+	Matches []DuplicateMatch `json:"matches"`
+}
+
+// QuestionSlotCount counts the live entries sharing a category, sub-category, difficulty
+// and type
+type QuestionSlotCount struct {
+	Category    string       `json:"category"`
+	SubCategory string       `json:"sub_category,omitempty"`
+	Difficulty  string       `json:"difficulty"`
+	Type        QuestionType `json:"type"`
+	Live        int64        `json:"live"`
+	Sampleable  int64        `json:"sampleable"` // Approved, so rules can draw them
+}
+
+// PassageGroupCount is the number of distinct passages in a category or sub-category
+type PassageGroupCount struct {
+	Category    string `json:"category"`
+	SubCategory string `json:"sub_category,omitempty"`
+	Groups      int64  `json:"groups"`
+}
+
+// TagCount is the number of live entries carrying a tag
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int64  `json:"count"`
+}
+
+// QuestionBankCounts is the aggregated content of the bank that statistics are built from
+type QuestionBankCounts struct {
+	Slots         []QuestionSlotCount `json:"slots"`
+	PassageGroups []PassageGroupCount `json:"passage_groups"`
+	Tags          []TagCount          `json:"tags"`
+	Retired       int64               `json:"retired"`
+	ComputedAt    time.Time           `json:"computed_at"`
+}
+
+// CountBreakdown holds live and sampleable counts per name, e.g. per difficulty
+type CountBreakdown struct {
+	Name       string `json:"name"`
+	Live       int64  `json:"live"`
+	Sampleable int64  `json:"sampleable"`
+}
+
+// SubCategoryStats summarises the live entries of a sub-category
+type SubCategoryStats struct {
+	Name          string           `json:"name"`
+	Live          int64            `json:"live"`
+	Sampleable    int64            `json:"sampleable"`
+	PassageGroups int64            `json:"passage_groups"`
+	ByDifficulty  []CountBreakdown `json:"by_difficulty"`
+	ByType        []CountBreakdown `json:"by_type"`
+}
+
+// CategoryStats summarises the live entries of a category
+type CategoryStats struct {
+	Name          string             `json:"name"`
+	Live          int64              `json:"live"`
+	Sampleable    int64              `json:"sampleable"`
+	PassageGroups int64              `json:"passage_groups"`
+	ByDifficulty  []CountBreakdown   `json:"by_difficulty"`
+	ByType        []CountBreakdown   `json:"by_type"`
+	SubCategories []SubCategoryStats `json:"sub_categories"`
+}
+
+const (
+	CoverageGapUnderstocked = "understocked" // A configured slot with fewer sampleable entries than the minimum
+	CoverageGapUnconfigured = "unconfigured" // Entries in a slot the bank structure does not define
+)
+
+// CoverageGap is a slot where the bank and its configured structure disagree
+type CoverageGap struct {
+	Kind        string `json:"kind"`
+	Category    string `json:"category"`
+	SubCategory string `json:"sub_category,omitempty"`
+	Difficulty  string `json:"difficulty"`
+	Live        int64  `json:"live"`
+	Sampleable  int64  `json:"sampleable"`
+}
+
+// QuestionBankStats describes the live content of the bank
+type QuestionBankStats struct {
+	Live          int64            `json:"live"`
+	Sampleable    int64            `json:"sampleable"`
+	Retired       int64            `json:"retired"`
+	PassageGroups int64            `json:"passage_groups"`
+	ByDifficulty  []CountBreakdown `json:"by_difficulty"`
+	ByType        []CountBreakdown `json:"by_type"`
+	Categories    []CategoryStats  `json:"categories"`
+	Tags          []TagCount       `json:"tags"`
+	MinSampleable int              `json:"min_sampleable"` // Threshold for understocked slots
+	CoverageGaps  []CoverageGap    `json:"coverage_gaps"`
+	ComputedAt    time.Time        `json:"computed_at"`
+}
+
+const (
+	StructureOpRename = "rename"
+	StructureOpMerge  = "merge"
+	StructureOpMove   = "move"
+	StructureOpDelete = "delete"
+)
+
+// StructureOperation changes the bank structure together with the entries and assessment
+// rules that use it. It applies to a category, or to one of its sub-categories when
+// SubCategory is set.
+type StructureOperation struct {
+	Op              string `json:"op" binding:"required"` // rename, merge, move or delete
+	Category        string `json:"category" binding:"required"`
+	SubCategory     string `json:"sub_category,omitempty"`
+	NewName         string `json:"new_name,omitempty"`          // rename
+	IntoCategory    string `json:"into_category,omitempty"`     // merge, move, delete: where the entries go
+	IntoSubCategory string `json:"into_sub_category,omitempty"` // merge, delete
+}
+
+// StructureOperationReport is the outcome of a structure operation
+type StructureOperationReport struct {
+	Op                 string              `json:"op"`
+	EntriesUpdated     int64               `json:"entries_updated"` // Retired entries included
+	AssessmentsUpdated int                 `json:"assessments_updated"`
+	RulesUpdated       int                 `json:"rules_updated"` // Adaptive configurations count as one rule
+	Structure          *QuestionBankConfig `json:"structure"`
+}
+
+// QuestionBulkChanges lists the fields a bulk edit sets on every entry it matches. Nil
+// fields are left as they are; an empty sub-category or audio URL clears it.
+type QuestionBulkChanges struct {
+	Category    *string       `json:"category,omitempty"`
+	SubCategory *string       `json:"sub_category,omitempty"`
+	Difficulty  *string       `json:"difficulty,omitempty"`
+	Type        *QuestionType `json:"type,omitempty"`
+	AudioURL    *string       `json:"audio_url,omitempty"`
+	Tags        []string      `json:"tags,omitempty"` // Replaces the tags; an empty list clears them
+	AddTags     []string      `json:"add_tags,omitempty"`
+	RemoveTags  []string      `json:"remove_tags,omitempty"`
+}
+
+// QuestionBulkUpdate is a bulk edit of the given entries, or of those matching the list
+// filters when no IDs are given
+type QuestionBulkUpdate struct {
+	IDs     []string            `json:"ids,omitempty"`
+	Changes QuestionBulkChanges `json:"changes"`
+}
+
+// QuestionBulkUpdateReport is the outcome, or with DryRun the preview, of a bulk edit
+type QuestionBulkUpdateReport struct {
+	Matched  int64 `json:"matched"`
+	Modified int64 `json:"modified"`
+	DryRun   bool  `json:"dry_run"`
+}
+
+// SearchHighlight is a field of a search hit with the matched words marked. Fragment is
+// HTML-escaped, with matches wrapped in <mark> tags.
+type SearchHighlight struct {
+	Field    string `json:"field"` // "text", "passage_title", "passage_text" or "options.<index>"
+	Fragment string `json:"fragment"`
+}
+
+// QuestionSearchHit is a bank entry matching a full-text search
+type QuestionSearchHit struct {
+	Question   QuestionBankEntry `json:"question"`
+	Score      float64           `json:"score"` // Text search relevance
+	Highlights []SearchHighlight `json:"highlights"`
+}
+
+// ImportIssue is a problem found with one row of an import
+type ImportIssue struct {
+	Row     int    `json:"row"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// ImportValidationReport is the outcome of a dry-run import. Nothing is stored until the
+// import is confirmed with the token.
+type ImportValidationReport struct {
+	ImportToken string              `json:"import_token,omitempty"` // Empty when no row is valid
+	ExpiresAt   *time.Time          `json:"expires_at,omitempty"`
+	TotalRows   int                 `json:"total_rows"`
+	ValidRows   int                 `json:"valid_rows"`
+	InvalidRows int                 `json:"invalid_rows"`
+	Errors      []ImportIssue       `json:"errors"`   // Rows with errors are left out of the import
+	Warnings    []ImportIssue       `json:"warnings"` // Near-duplicates and other rows worth a second look
+	Preview     []QuestionBankEntry `json:"preview"`  // The first valid rows as they will be stored
+}
+
+// NearDuplicatePair is two live entries of a category whose wording is nearly the same
+type NearDuplicatePair struct {
+	Similarity float64        `json:"similarity"`
+	First      DuplicateMatch `json:"first"`
+	Second     DuplicateMatch `json:"second"`
+}
+
+// DuplicateScanReport lists the duplicates found across the live bank
+type DuplicateScanReport struct {
+	Scanned        int                 `json:"scanned"`
+	Threshold      float64             `json:"threshold"`
+	ExactGroups    [][]DuplicateMatch  `json:"exact_groups"` // Entries sharing a content hash
+	NearDuplicates []NearDuplicatePair `json:"near_duplicates"`
+}
+
+// QuestionFieldChange is one field that differs between two versions
+type QuestionFieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// QuestionVersionDiff compares two versions of the same entry
+type QuestionVersionDiff struct {
+	EntryID     primitive.ObjectID    `json:"entry_id"`
+	FromVersion int                   `json:"from_version"`
+	ToVersion   int                   `json:"to_version"`
+	Changes     []QuestionFieldChange `json:"changes"`
+}
+
+// QuestionTemplate makes an entry a family of questions. Text and passage use {{name}}
+// placeholders that are filled with values drawn for each candidate, and the options are
+// built from the answer and distractor formulas.
+type QuestionTemplate struct {
+	Variables     []TemplateVariable `bson:"variables" json:"variables" binding:"required"`
+	AnswerFormula string             `bson:"answer_formula" json:"answer_formula" binding:"required"`
+	Distractors   []string           `bson:"distractors,omitempty" json:"distractors,omitempty"` // Formulas for wrong options; generated when too few
+	Constraints   []string           `bson:"constraints,omitempty" json:"constraints,omitempty"` // Formulas that must be non-zero, e.g. "a % b == 0"
+	Decimals      int                `bson:"decimals,omitempty" json:"decimals,omitempty"`       // Rounding of the answer and options
+}
+
+// TemplateVariable is drawn uniformly from Min, Min+Step, ... up to Max
+type TemplateVariable struct {
+	Name string  `bson:"name" json:"name" binding:"required"`
+	Min  float64 `bson:"min" json:"min"`
+	Max  float64 `bson:"max" json:"max"`
+	Step float64 `bson:"step,omitempty" json:"step,omitempty"` // Default 1
+}
+
+const (
+	ItemFlagTooEasy                = "too_easy"
+	ItemFlagTooHard                = "too_hard"
+	ItemFlagLowDiscrimination      = "low_discrimination"
+	ItemFlagNegativeDiscrimination = "negative_discrimination"
+	ItemFlagMisleadingDistractor   = "misleading_distractor"
+)
+
+// OptionStatistics describes how often an MCQ option was chosen and by whom
+type OptionStatistics struct {
+	Option        string  `bson:"option" json:"option"`
+	IsCorrect     bool    `bson:"is_correct" json:"is_correct"`
+	Count         int     `bson:"count" json:"count"`
+	Rate          float64 `bson:"rate" json:"rate"`
+	MeanRestScore float64 `bson:"mean_rest_score" json:"mean_rest_score"` // Choosers' mean score excluding this item
+}
+
+// ItemStatistics is the classical item analysis of a bank entry over graded submissions
+type ItemStatistics struct {
+	Responses     int                `bson:"responses" json:"responses"`
+	PValue        float64            `bson:"p_value" json:"p_value"`                                   // Difficulty index: share answering correctly
+	PointBiserial *float64           `bson:"point_biserial,omitempty" json:"point_biserial,omitempty"` // Corrected item-rest correlation; nil when undefined
+	Omitted       int                `bson:"omitted" json:"omitted"`
+	Options       []OptionStatistics `bson:"options,omitempty" json:"options,omitempty"`
+	Flags         []string           `bson:"flags,omitempty" json:"flags,omitempty"`
+	ComputedAt    time.Time          `bson:"computed_at" json:"computed_at"`
+}
+
+const (
+	DifficultyEasy   = "Easy"
+	DifficultyMedium = "Medium"
+	DifficultyHard   = "Hard"
+)
+
+// ItemCalibration is the entry's Rasch difficulty estimated from response data
+type ItemCalibration struct {
+	Difficulty          float64   `bson:"difficulty" json:"difficulty"` // Logits, centred on the mean calibrated item
+	StandardError       float64   `bson:"standard_error" json:"standard_error"`
+	Responses           int       `bson:"responses" json:"responses"`
+	SuggestedDifficulty string    `bson:"suggested_difficulty,omitempty" json:"suggested_difficulty,omitempty"` // Set when the label clearly disagrees with the estimate
+	ComputedAt          time.Time `bson:"computed_at" json:"computed_at"`
+}
+
+// ItemAnalysisJob reports the progress of an item analysis run
+type ItemAnalysisJob struct {
+	ID                 string     `json:"id"`
+	Status             string     `json:"status"`  // "processing", "completed", "failed"
+	Trigger            string     `json:"trigger"` // "manual" or "scheduled"
+	SubmissionsScanned int        `json:"submissions_scanned"`
+	ItemsUpdated       int64      `json:"items_updated"`
+	ItemsCalibrated    int        `json:"items_calibrated"`
+	Suggestions        int        `json:"suggestions"` // Entries whose difficulty label should change
+	Error              string     `json:"error,omitempty"`
+	StartedAt          time.Time  `json:"started_at"`
+	CompletedAt        *time.Time `json:"completed_at,omitempty"`
 }
 
 type DifficultyConfig struct {
@@ -39,7 +420,16 @@ type CategoryConfig struct {
 	Expanded         bool                `bson:"expanded" json:"expanded"`
 }
 
+// SkillConfig is a node of the skills taxonomy. Entries refer to a skill by its path: the
+// normalised names from the root down, joined with "/".
+type SkillConfig struct {
+	Name        string        `bson:"name" json:"name"`
+	Description string        `bson:"description,omitempty" json:"description,omitempty"`
+	Children    []SkillConfig `bson:"children,omitempty" json:"children,omitempty"`
+}
+
 type QuestionBankConfig struct {
 	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	Categories []CategoryConfig   `bson:"categories" json:"categories"`
+	Skills     []SkillConfig      `bson:"skills,omitempty" json:"skills,omitempty"`
 }
@@ -18,6 +18,81 @@ type Violation struct {
 	Type      string    `bson:"type" json:"type"` // "multiple_people", "audio_anomaly", "tab_switch", etc.
 	Reason    string    `bson:"reason" json:"reason"`
 	Evidence  string    `bson:"evidence,omitempty" json:"evidence,omitempty"` // Optional base64 image or audio snippet
+	Waived    bool      `bson:"waived,omitempty" json:"waived,omitempty"`     // Check disabled by the candidate's accommodation
+}
+
+const (
+	AnswerEventSeen     = "seen"
+	AnswerEventAnswered = "answered"
+	AnswerEventChanged  = "changed"
+
+	TimingFlagFastAnswerRun = "fast_answer_run"
+)
+
+// AnswerEvent is reported by the client as the candidate moves between questions.
+// Events are folded into the submission's timeline and not stored individually.
+type AnswerEvent struct {
+	QuestionID  primitive.ObjectID `json:"question_id"`
+	Type        string             `json:"type"` // "seen", "answered" or "changed"
+	At          time.Time          `json:"at"`
+	TimeSpentMs int64              `json:"time_spent_ms,omitempty"` // Time on the question since it was last shown
+}
+
+// QuestionTiming is the compact per-question timeline kept on a submission
+type QuestionTiming struct {
+	QuestionID      primitive.ObjectID `bson:"question_id" json:"question_id"`
+	FirstSeenAt     *time.Time         `bson:"first_seen_at,omitempty" json:"first_seen_at,omitempty"`
+	FirstAnsweredAt *time.Time         `bson:"first_answered_at,omitempty" json:"first_answered_at,omitempty"`
+	LastAnsweredAt  *time.Time         `bson:"last_answered_at,omitempty" json:"last_answered_at,omitempty"`
+	LastEventAt     time.Time          `bson:"last_event_at" json:"-"` // Events at or before this were already applied
+	Visits          int                `bson:"visits" json:"visits"`
+	Changes         int                `bson:"changes" json:"changes"`
+	TimeSpentMs     int64              `bson:"time_spent_ms" json:"time_spent_ms"`
+}
+
+// TimingFlag marks a stretch of answers given faster than a person could read them
+type TimingFlag struct {
+	Type        string               `bson:"type" json:"type"`
+	QuestionIDs []primitive.ObjectID `bson:"question_ids" json:"question_ids"`
+	StartedAt   time.Time            `bson:"started_at" json:"started_at"`
+	EndedAt     time.Time            `bson:"ended_at" json:"ended_at"`
+	AvgTimeMs   int64                `bson:"avg_time_ms" json:"avg_time_ms"`
+}
+
+const (
+	AdaptiveStopMaxItems      = "max_items"
+	AdaptiveStopPrecision     = "precision"
+	AdaptiveStopPoolExhausted = "pool_exhausted"
+	AdaptiveStopSubmitted     = "submitted"
+)
+
+// AdaptiveItem is one item delivered in an adaptive attempt
+type AdaptiveItem struct {
+	QuestionID  primitive.ObjectID `bson:"question_id" json:"question_id"`
+	Difficulty  float64            `bson:"difficulty" json:"difficulty"` // Logit used when selecting the item
+	Correct     *bool              `bson:"correct,omitempty" json:"correct,omitempty"`
+	PresentedAt time.Time          `bson:"presented_at" json:"presented_at"`
+	AnsweredAt  *time.Time         `bson:"answered_at,omitempty" json:"answered_at,omitempty"`
+}
+
+// AdaptiveState tracks the ability estimate of an adaptive attempt
+type AdaptiveState struct {
+	Theta         float64        `bson:"theta" json:"theta"`
+	StandardError float64        `bson:"standard_error" json:"standard_error"`
+	Items         []AdaptiveItem `bson:"items" json:"items"`
+	StopReason    string         `bson:"stop_reason,omitempty" json:"stop_reason,omitempty"`
+	ScaledScore   int            `bson:"scaled_score,omitempty" json:"scaled_score,omitempty"` // Common scale shared by all adaptive assessments
+}
+
+// AdaptiveStep is what the candidate sees after each adaptive request
+type AdaptiveStep struct {
+	Question   *Question `json:"question,omitempty"` // Next item, without its answer key
+	ItemNumber int       `json:"item_number"`
+	MaxItems   int       `json:"max_items"`
+	Finished   bool      `json:"finished"`
+	StopReason string    `json:"stop_reason,omitempty"`
+	Score      int       `json:"score,omitempty"`
+	Passed     bool      `json:"passed,omitempty"`
 }
 
 type FaceSnapshots struct {
@@ -35,15 +110,21 @@ type Submission struct {
 	CandidateName  string             `bson:"candidate_name" json:"candidate_name"`
 	CandidateEmail string             `bson:"candidate_email" json:"candidate_email"`
 	CandidatePhone string             `bson:"candidate_phone" json:"candidate_phone"`
+	AttemptNumber  int                `bson:"attempt_number" json:"attempt_number"` // 1-based; 0 on submissions created before retakes
+	AttemptCount   int                `bson:"-" json:"attempt_count,omitempty"`     // Virtual field for interviewer listings
 	Answers        []Answer           `bson:"answers" json:"answers"`
 	Violations     []Violation        `bson:"violations,omitempty" json:"violations,omitempty"`
 	FaceSnapshots  *FaceSnapshots     `bson:"face_snapshots,omitempty" json:"face_snapshots,omitempty"`
-	Score          int                `bson:"score" json:"score"`   // Total score
-	Status         string             `bson:"status" json:"status"` // "in_progress", "submitted", "graded"
+	Score          int                `bson:"score" json:"score"`     // Total score
+	Status         string             `bson:"status" json:"status"`   // "in_progress", "submitted", "graded"
+	Version        int64              `bson:"version" json:"version"` // Incremented on every write; exposed as the ETag
 	CreatedBy      primitive.ObjectID `bson:"created_by" json:"created_by"`
 	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
 	StartedAt      time.Time          `bson:"started_at" json:"started_at"`
 	SubmittedAt    time.Time          `bson:"submitted_at,omitempty" json:"submitted_at,omitempty"`
+	DeadlineAt     *time.Time         `bson:"deadline_at,omitempty" json:"deadline_at,omitempty"` // StartedAt + effective duration
+	AutoSubmitted  bool               `bson:"auto_submitted,omitempty" json:"auto_submitted,omitempty"`
+	SubmittedLate  bool               `bson:"submitted_late,omitempty" json:"submitted_late,omitempty"`
 	UpdatedAt      time.Time          `bson:"updated_at" json:"updated_at"`
 	DeletedAt      *time.Time         `bson:"deleted_at,omitempty" json:"-"`
 
@@ -58,4 +139,43 @@ type Submission struct {
 	NextPhaseUnlocked bool                `bson:"next_phase_unlocked" json:"next_phase_unlocked"`
 	ShuffledOptions   map[string][]string `bson:"shuffled_options,omitempty" json:"shuffled_options,omitempty"` // question_id -> shuffled options
 	MinPassingScore   int                 `bson:"min_passing_score" json:"min_passing_score"`
+
+	// Accommodation in force for this attempt, kept for auditors
+	Accommodation *AppliedAccommodation `bson:"accommodation,omitempty" json:"accommodation,omitempty"`
+
+	// Per-question timing built from client answer events
+	Timeline    []QuestionTiming `bson:"timeline,omitempty" json:"timeline,omitempty"`
+	TimingFlags []TimingFlag     `bson:"timing_flags,omitempty" json:"timing_flags,omitempty"`
+
+	// Set on attempts of adaptive assessments
+	Adaptive *AdaptiveState `bson:"adaptive,omitempty" json:"adaptive,omitempty"`
+}
+
+// AttemptHistory lists a candidate's attempts at one assessment together with retake eligibility
+type AttemptHistory struct {
+	Policy            RetakePolicy `json:"policy"`
+	Attempts          []Submission `json:"attempts"`
+	CountedAttempt    int          `json:"counted_attempt,omitempty"` // Attempt number that counts under the scoring policy
+	AttemptsRemaining int          `json:"attempts_remaining"`
+	CanRetake         bool         `json:"can_retake"`
+	NextAttemptAt     *time.Time   `json:"next_attempt_at,omitempty"` // Set while a retake cooldown is running
+}
+
+// QuestionTimeStats aggregates answer timelines for one question across an assessment's submissions
+type QuestionTimeStats struct {
+	QuestionID   primitive.ObjectID `json:"question_id"`
+	QuestionText string             `json:"question_text,omitempty"`
+	Responses    int                `json:"responses"`
+	MeanTimeMs   int64              `json:"mean_time_ms"`
+	MedianTimeMs int64              `json:"median_time_ms"`
+	P90TimeMs    int64              `json:"p90_time_ms"`
+	MeanChanges  float64            `json:"mean_changes"`
+	ChangedRate  float64            `json:"changed_rate"` // Share of responses changed at least once
+	FastAnswers  int                `json:"fast_answers"` // Responses below the minimum plausible answer time
+}
+
+type AssessmentTimeStats struct {
+	Submissions        int                 `json:"submissions"`
+	FlaggedSubmissions int                 `json:"flagged_submissions"`
+	Questions          []QuestionTimeStats `json:"questions"`
 }
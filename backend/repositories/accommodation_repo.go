@@ -0,0 +1,79 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"hireit-backend/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type AccommodationRepository interface {
+	Create(ctx context.Context, accommodation *models.Accommodation) (primitive.ObjectID, error)
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.Accommodation, error)
+	FindAll(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]models.Accommodation, error)
+	Update(ctx context.Context, id primitive.ObjectID, update bson.M) error
+}
+
+type mongoAccommodationRepo struct {
+	collection *mongo.Collection
+}
+
+func NewAccommodationRepository(collection *mongo.Collection) AccommodationRepository {
+	repo := &mongoAccommodationRepo{collection: collection}
+	repo.EnsureIndexes()
+	return repo
+}
+
+func (r *mongoAccommodationRepo) EnsureIndexes() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "candidate_id", Value: 1}, {Key: "assessment_id", Value: 1}},
+	})
+	if err != nil {
+		fmt.Printf("Warning: Failed to create indexes for accommodations: %v\n", err)
+	}
+}
+
+func (r *mongoAccommodationRepo) Create(ctx context.Context, accommodation *models.Accommodation) (primitive.ObjectID, error) {
+	accommodation.ID = primitive.NewObjectID()
+	res, err := r.collection.InsertOne(ctx, accommodation)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	return res.InsertedID.(primitive.ObjectID), nil
+}
+
+func (r *mongoAccommodationRepo) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Accommodation, error) {
+	var accommodation models.Accommodation
+	err := r.collection.FindOne(ctx, bson.M{"_id": id, "deleted_at": nil}).Decode(&accommodation)
+	if err != nil {
+		return nil, err
+	}
+	return &accommodation, nil
+}
+
+func (r *mongoAccommodationRepo) FindAll(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]models.Accommodation, error) {
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var accommodations []models.Accommodation
+	if err := cursor.All(ctx, &accommodations); err != nil {
+		return nil, err
+	}
+	return accommodations, nil
+}
+
+func (r *mongoAccommodationRepo) Update(ctx context.Context, id primitive.ObjectID, update bson.M) error {
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	return err
+}
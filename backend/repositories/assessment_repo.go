@@ -15,6 +15,7 @@ type AssessmentRepository interface {
 	FindAll(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]models.Assessment, error)
 	FindByID(ctx context.Context, id primitive.ObjectID) (*models.Assessment, error)
 	Update(ctx context.Context, id primitive.ObjectID, assessment *models.Assessment) error
+	UpdateFields(ctx context.Context, id primitive.ObjectID, set bson.M) error
 	Delete(ctx context.Context, id primitive.ObjectID) error
 }
 
@@ -62,6 +63,12 @@ func (r *mongoAssessmentRepo) Update(ctx context.Context, id primitive.ObjectID,
 	return err
 }
 
+// UpdateFields sets only the given fields, unlike Update, which sets the whole document.
+func (r *mongoAssessmentRepo) UpdateFields(ctx context.Context, id primitive.ObjectID, set bson.M) error {
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": set})
+	return err
+}
+
 func (r *mongoAssessmentRepo) Delete(ctx context.Context, id primitive.ObjectID) error {
 	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
 	return err
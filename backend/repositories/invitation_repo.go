@@ -0,0 +1,114 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"hireit-backend/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type InvitationRepository interface {
+	Create(ctx context.Context, invitation *models.Invitation) (primitive.ObjectID, error)
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.Invitation, error)
+	FindAll(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]models.Invitation, error)
+	Update(ctx context.Context, id primitive.ObjectID, update bson.M) error
+	UpdateMany(ctx context.Context, filter bson.M, update bson.M) (int64, error)
+	FindOneAndUpdate(ctx context.Context, filter bson.M, update bson.M) (*models.Invitation, error)
+}
+
+type mongoInvitationRepo struct {
+	collection *mongo.Collection
+}
+
+func NewInvitationRepository(collection *mongo.Collection) InvitationRepository {
+	repo := &mongoInvitationRepo{collection: collection}
+	repo.EnsureIndexes()
+	return repo
+}
+
+func (r *mongoInvitationRepo) EnsureIndexes() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	indexModels := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "token_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "assessment_id", Value: 1}, {Key: "candidate_id", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "status", Value: 1}},
+		},
+	}
+
+	_, err := r.collection.Indexes().CreateMany(ctx, indexModels)
+	if err != nil {
+		fmt.Printf("Warning: Failed to create indexes for invitations: %v\n", err)
+	}
+}
+
+func (r *mongoInvitationRepo) Create(ctx context.Context, invitation *models.Invitation) (primitive.ObjectID, error) {
+	if invitation.ID.IsZero() {
+		invitation.ID = primitive.NewObjectID()
+	}
+	res, err := r.collection.InsertOne(ctx, invitation)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	return res.InsertedID.(primitive.ObjectID), nil
+}
+
+func (r *mongoInvitationRepo) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Invitation, error) {
+	var invitation models.Invitation
+	err := r.collection.FindOne(ctx, bson.M{"_id": id, "deleted_at": nil}).Decode(&invitation)
+	if err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+func (r *mongoInvitationRepo) FindAll(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]models.Invitation, error) {
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var invitations []models.Invitation
+	if err := cursor.All(ctx, &invitations); err != nil {
+		return nil, err
+	}
+	return invitations, nil
+}
+
+func (r *mongoInvitationRepo) Update(ctx context.Context, id primitive.ObjectID, update bson.M) error {
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	return err
+}
+
+func (r *mongoInvitationRepo) UpdateMany(ctx context.Context, filter bson.M, update bson.M) (int64, error) {
+	res, err := r.collection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		return 0, err
+	}
+	return res.ModifiedCount, nil
+}
+
+// FindOneAndUpdate applies the update atomically and returns the updated document.
+// It returns mongo.ErrNoDocuments when nothing matched the filter.
+func (r *mongoInvitationRepo) FindOneAndUpdate(ctx context.Context, filter bson.M, update bson.M) (*models.Invitation, error) {
+	var invitation models.Invitation
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&invitation)
+	if err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
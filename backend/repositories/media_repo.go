@@ -0,0 +1,79 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"hireit-backend/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MediaRepository interface {
+	Create(ctx context.Context, media *models.Media) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.Media, error)
+	FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.Media, error)
+	FindBySHA256(ctx context.Context, sum string) (*models.Media, error)
+}
+
+type mongoMediaRepo struct {
+	collection *mongo.Collection
+}
+
+func NewMediaRepository(collection *mongo.Collection) MediaRepository {
+	repo := &mongoMediaRepo{collection: collection}
+	repo.EnsureIndexes()
+	return repo
+}
+
+func (r *mongoMediaRepo) EnsureIndexes() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// One document per distinct file
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "sha256", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		fmt.Printf("Warning: Failed to create indexes for media: %v\n", err)
+	}
+}
+
+func (r *mongoMediaRepo) Create(ctx context.Context, media *models.Media) error {
+	_, err := r.collection.InsertOne(ctx, media)
+	return err
+}
+
+func (r *mongoMediaRepo) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Media, error) {
+	var media models.Media
+	if err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&media); err != nil {
+		return nil, err
+	}
+	return &media, nil
+}
+
+func (r *mongoMediaRepo) FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.Media, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	media := []models.Media{}
+	if err := cursor.All(ctx, &media); err != nil {
+		return nil, err
+	}
+	return media, nil
+}
+
+func (r *mongoMediaRepo) FindBySHA256(ctx context.Context, sum string) (*models.Media, error) {
+	var media models.Media
+	if err := r.collection.FindOne(ctx, bson.M{"sha256": sum}).Decode(&media); err != nil {
+		return nil, err
+	}
+	return &media, nil
+}
@@ -2,7 +2,20 @@ package repositories
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"hireit-backend/models"
+	"hireit-backend/utils"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -12,36 +25,321 @@ import (
 
 type QuestionBankRepository interface {
 	Create(ctx context.Context, question *models.QuestionBankEntry) (primitive.ObjectID, error)
+	CreateMany(ctx context.Context, questions []*models.QuestionBankEntry) (int, error)
 	Find(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]models.QuestionBankEntry, error)
+	ForEach(ctx context.Context, filter bson.M, opts *options.FindOptions, fn func(*models.QuestionBankEntry) error) (int, error)
+	Search(ctx context.Context, filter bson.M, search string, skip, limit int64) ([]models.QuestionSearchHit, int64, error)
 	Sample(ctx context.Context, filter bson.M, size int) ([]models.QuestionBankEntry, error)
-	DeleteByID(ctx context.Context, id primitive.ObjectID) error
-	DeleteByFilter(ctx context.Context, filter bson.M) (int64, error)
-	Update(ctx context.Context, id primitive.ObjectID, question *models.QuestionBankEntry) error
+	Retire(ctx context.Context, filter bson.M, retiredBy primitive.ObjectID) (int64, error)
+	Restore(ctx context.Context, filter bson.M) (int64, error)
+	DeleteMany(ctx context.Context, ids []primitive.ObjectID) error
+	Review(ctx context.Context, id primitive.ObjectID, fromStatuses []string, review models.ReviewComment) (bool, error)
+	FindByID(ctx context.Context, id primitive.ObjectID) (*models.QuestionBankEntry, error)
+	Update(ctx context.Context, id primitive.ObjectID, question *models.QuestionBankEntry) (int, error)
+	ListVersions(ctx context.Context, id primitive.ObjectID) ([]models.QuestionBankVersion, error)
+	FindVersion(ctx context.Context, id primitive.ObjectID, version int) (*models.QuestionBankVersion, error)
+	Revert(ctx context.Context, id primitive.ObjectID, version int, authorID primitive.ObjectID) (int, error)
 	CountByFilter(ctx context.Context, filter bson.M) (int64, error)
+	BulkSet(ctx context.Context, updates map[primitive.ObjectID]bson.M) (int64, error)
+	UpdateVersioned(ctx context.Context, filter bson.M, pipeline bson.A, authorID primitive.ObjectID) (matched int64, modified int64, err error)
+	AcceptDifficultySuggestions(ctx context.Context, filter bson.M) (int64, error)
+	Relabel(ctx context.Context, filter bson.M, relabel func(category, subCategory string) (string, string), authorID primitive.ObjectID) (int64, error)
+	Counts(ctx context.Context) (*models.QuestionBankCounts, error)
 
 	SaveBankConfig(ctx context.Context, config *models.QuestionBankConfig) error
 	GetBankConfig(ctx context.Context) (*models.QuestionBankConfig, error)
+
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
 }
 
+var (
+	// ErrQuestionVersionConflict is returned when the entry changed between reading and writing it.
+	ErrQuestionVersionConflict = errors.New("question was modified by another request")
+	// ErrTransactionsUnsupported is returned when MongoDB runs standalone, without a replica set.
+	ErrTransactionsUnsupported = errors.New("this operation needs MongoDB transactions, which require a replica set")
+)
+
+const (
+	questionBankCountsCacheKey = "question_bank_counts"
+	// Writes invalidate the cached counts, but only in this process
+	questionBankCountsTTL = 5 * time.Minute
+)
+
 type mongoQuestionBankRepo struct {
-	collection       *mongo.Collection
-	configCollection *mongo.Collection
+	collection        *mongo.Collection
+	configCollection  *mongo.Collection
+	versionCollection *mongo.Collection
+
+	// countsMu orders invalidations against storing freshly computed counts, so counts
+	// computed across a write are not cached
+	countsMu         sync.Mutex
+	countsGeneration int64
+
+	// Set once the server turns out to be standalone, which has no transactions
+	noTransactions atomic.Bool
+}
+
+func NewQuestionBankRepository(collection *mongo.Collection, configCollection *mongo.Collection, versionCollection *mongo.Collection) QuestionBankRepository {
+	repo := &mongoQuestionBankRepo{
+		collection:        collection,
+		configCollection:  configCollection,
+		versionCollection: versionCollection,
+	}
+	repo.EnsureIndexes()
+	repo.backfillContentHashes()
+	return repo
+}
+
+func (r *mongoQuestionBankRepo) EnsureIndexes() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.versionCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "entry_id", Value: 1}, {Key: "version", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		fmt.Printf("Warning: Failed to create indexes for question_bank_versions: %v\n", err)
+	}
+
+	_, err = r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "content_hash", Value: 1}}},
+		{Keys: bson.D{{Key: "tags", Value: 1}}},
+		{Keys: bson.D{{Key: "skills", Value: 1}}},
+		{
+			Keys: bson.D{
+				{Key: "text", Value: "text"},
+				{Key: "options", Value: "text"},
+				{Key: "passage_title", Value: "text"},
+				{Key: "passage_text", Value: "text"},
+			},
+			// A match in the question itself ranks above one in the shared passage
+			Options: options.Index().SetName("question_search").SetWeights(bson.D{
+				{Key: "text", Value: 10},
+				{Key: "options", Value: 4},
+				{Key: "passage_title", Value: 2},
+				{Key: "passage_text", Value: 1},
+			}),
+		},
+	})
+	if err != nil {
+		fmt.Printf("Warning: Failed to create indexes for question_bank: %v\n", err)
+	}
 }
 
-func NewQuestionBankRepository(collection *mongo.Collection, configCollection *mongo.Collection) QuestionBankRepository {
-	return &mongoQuestionBankRepo{
-		collection:       collection,
-		configCollection: configCollection,
+// backfillContentHashes hashes entries stored before duplicate detection existed, and
+// rehashes entries with media, whose attachments were not always part of the hash.
+func (r *mongoQuestionBankRepo) backfillContentHashes() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	entries, err := r.Find(ctx, bson.M{"$or": bson.A{
+		bson.M{"content_hash": bson.M{"$exists": false}},
+		bson.M{"media.0": bson.M{"$exists": true}},
+	}}, options.Find().SetProjection(bson.M{"text": 1, "options": 1, "passage_text": 1, "media": 1, "content_hash": 1}))
+	if err != nil {
+		fmt.Printf("Warning: Failed to load questions to hash: %v\n", err)
+		return
+	}
+
+	updates := make(map[primitive.ObjectID]bson.M, len(entries))
+	for _, entry := range entries {
+		if hash := QuestionContentHash(&entry); hash != entry.ContentHash {
+			updates[entry.ID] = bson.M{"content_hash": hash}
+		}
+	}
+	if len(updates) == 0 {
+		return
+	}
+	if _, err := r.BulkSet(ctx, updates); err != nil {
+		fmt.Printf("Warning: Failed to backfill question content hashes: %v\n", err)
 	}
 }
 
+// QuestionContentHash identifies an entry's wording regardless of case, punctuation,
+// whitespace and option order. The passage is included so generic stems such as "What is
+// the main idea?" on different passages are not taken for duplicates, and so is the media,
+// each attachment as media ID and option, so "Which chart shows growth?" over different
+// charts is not either.
+func QuestionContentHash(question *models.QuestionBankEntry) string {
+	options := make([]string, len(question.Options))
+	for i, option := range question.Options {
+		options[i] = utils.NormalizeText(option)
+	}
+	sort.Strings(options)
+
+	parts := append([]string{utils.NormalizeText(question.PassageText), utils.NormalizeText(question.Text)}, options...)
+	if len(question.Media) > 0 {
+		// Options are named by their text, which unlike their index survives reordering
+		media := make([]string, len(question.Media))
+		for i, attachment := range question.Media {
+			option := ""
+			if index := attachment.Option; index != nil {
+				option = strconv.Itoa(*index)
+				if *index >= 0 && *index < len(question.Options) {
+					option = utils.NormalizeText(question.Options[*index])
+				}
+			}
+			media[i] = attachment.MediaID.Hex() + "@" + option
+		}
+		sort.Strings(media)
+		parts = append(parts, media...)
+	}
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x1f")))
+	return hex.EncodeToString(sum[:])
+}
+
+// normalizeLabels stores tags and skill paths in the form the tag filters match.
+func normalizeLabels(question *models.QuestionBankEntry) {
+	question.Tags = utils.NormalizeTags(question.Tags)
+	question.Skills = utils.NormalizeSkillPaths(question.Skills)
+}
+
+// TaggedQuestionFilter narrows a filter to entries carrying every tag of include and none
+// of exclude and, if skills are given, at least one of them or one of their sub-skills.
+func TaggedQuestionFilter(filter bson.M, include, exclude, skills []string) bson.M {
+	tags := bson.M{}
+	if include = utils.NormalizeTags(include); len(include) > 0 {
+		tags["$all"] = include
+	}
+	if exclude = utils.NormalizeTags(exclude); len(exclude) > 0 {
+		tags["$nin"] = exclude
+	}
+	if len(tags) > 0 {
+		filter["tags"] = tags
+	}
+	if skills = utils.NormalizeSkillPaths(skills); len(skills) > 0 {
+		matches := bson.A{}
+		for _, skill := range skills {
+			matches = append(matches, skill, primitive.Regex{Pattern: "^" + regexp.QuoteMeta(skill+"/")})
+		}
+		filter["skills"] = bson.M{"$in": matches}
+	}
+	return filter
+}
+
+// Create inserts the entry as version 1. The author is taken from question.UpdatedBy.
+// New entries wait in the review queue unless a review status is given.
 func (r *mongoQuestionBankRepo) Create(ctx context.Context, question *models.QuestionBankEntry) (primitive.ObjectID, error) {
+	defer r.invalidateCounts()
 	question.ID = primitive.NewObjectID()
-	res, err := r.collection.InsertOne(ctx, question)
+	if question.ReviewStatus == "" {
+		question.ReviewStatus = models.ReviewStatusInReview
+	}
+	question.Version = 1
+	question.UpdatedAt = time.Now()
+	normalizeLabels(question)
+	question.ContentHash = QuestionContentHash(question)
+	err := r.inTransaction(ctx, func(ctx context.Context) error {
+		if _, err := r.collection.InsertOne(ctx, question); err != nil {
+			return err
+		}
+		return r.insertVersion(ctx, question, models.QuestionVersionCreate, 0)
+	})
 	if err != nil {
 		return primitive.NilObjectID, err
 	}
-	return res.InsertedID.(primitive.ObjectID), nil
+	return question.ID, nil
+}
+
+// CreateMany inserts the entries as version 1 in a single transaction, keeping IDs that
+// are already set, so a failed batch stores nothing. A standalone server has no
+// transactions: there the ordered batch is not atomic, and the entries inserted before a
+// failure stay, get their version snapshot and their count is returned with the error.
+func (r *mongoQuestionBankRepo) CreateMany(ctx context.Context, questions []*models.QuestionBankEntry) (int, error) {
+	if len(questions) == 0 {
+		return 0, nil
+	}
+	defer r.invalidateCounts()
+
+	now := time.Now()
+	for _, question := range questions {
+		if question.ID.IsZero() {
+			question.ID = primitive.NewObjectID()
+		}
+		if question.ReviewStatus == "" {
+			question.ReviewStatus = models.ReviewStatusInReview
+		}
+		question.Version = 1
+		question.UpdatedAt = now
+		normalizeLabels(question)
+		question.ContentHash = QuestionContentHash(question)
+	}
+
+	inserted := 0
+	err := r.inTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		inserted, err = r.insertEntries(ctx, questions, now)
+		return err
+	})
+	if err != nil && (mongo.SessionFromContext(ctx) != nil || !r.noTransactions.Load()) {
+		// The transaction was rolled back
+		return 0, err
+	}
+	return inserted, err
+}
+
+// insertEntries inserts the prepared entries in one ordered batch along with their version
+// snapshots. Outside a transaction, the entries stored before a failure still get their
+// snapshot and are counted.
+func (r *mongoQuestionBankRepo) insertEntries(ctx context.Context, questions []*models.QuestionBankEntry, now time.Time) (int, error) {
+	docs := make([]interface{}, len(questions))
+	ids := make(bson.A, len(questions))
+	for i, question := range questions {
+		docs[i] = question
+		ids[i] = question.ID
+	}
+
+	_, insertErr := r.collection.InsertMany(ctx, docs, options.InsertMany().SetOrdered(true))
+	inserted := questions
+	if insertErr != nil {
+		if mongo.SessionFromContext(ctx) != nil {
+			return 0, insertErr
+		}
+		stored, err := r.collection.Distinct(ctx, "_id", bson.M{"_id": bson.M{"$in": ids}})
+		if err != nil {
+			return 0, insertErr
+		}
+		storedIDs := make(map[primitive.ObjectID]bool, len(stored))
+		for _, id := range stored {
+			if oid, ok := id.(primitive.ObjectID); ok {
+				storedIDs[oid] = true
+			}
+		}
+		inserted = nil
+		for _, question := range questions {
+			if storedIDs[question.ID] {
+				inserted = append(inserted, question)
+			}
+		}
+	}
+
+	if len(inserted) > 0 {
+		versions := make([]interface{}, len(inserted))
+		for i, question := range inserted {
+			versions[i] = models.QuestionBankVersion{
+				ID:        primitive.NewObjectID(),
+				EntryID:   question.ID,
+				Version:   question.Version,
+				Action:    models.QuestionVersionCreate,
+				Content:   QuestionContent(*question),
+				AuthorID:  question.UpdatedBy,
+				CreatedAt: now,
+			}
+		}
+		if _, err := r.versionCollection.InsertMany(ctx, versions); err != nil && insertErr == nil {
+			return len(inserted), err
+		}
+	}
+	return len(inserted), insertErr
+}
+
+func (r *mongoQuestionBankRepo) FindByID(ctx context.Context, id primitive.ObjectID) (*models.QuestionBankEntry, error) {
+	var question models.QuestionBankEntry
+	if err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&question); err != nil {
+		return nil, err
+	}
+	return &question, nil
 }
 
 func (r *mongoQuestionBankRepo) Find(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]models.QuestionBankEntry, error) {
@@ -58,9 +356,68 @@ func (r *mongoQuestionBankRepo) Find(ctx context.Context, filter bson.M, opts *o
 	return questions, nil
 }
 
+// ForEach streams matching entries to fn without loading them all into memory.
+// It stops at the first error from fn and returns how many entries were visited.
+func (r *mongoQuestionBankRepo) ForEach(ctx context.Context, filter bson.M, opts *options.FindOptions, fn func(*models.QuestionBankEntry) error) (int, error) {
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	visited := 0
+	for cursor.Next(ctx) {
+		var question models.QuestionBankEntry
+		if err := cursor.Decode(&question); err != nil {
+			return visited, err
+		}
+		visited++
+		if err := fn(&question); err != nil {
+			return visited, err
+		}
+	}
+	return visited, cursor.Err()
+}
+
+// Search runs a text search over the question, options and passage, restricted by filter,
+// and returns a page of matches by relevance along with the total number of matches.
+// search uses the $text syntax: quoted phrases and words negated with a minus sign.
+func (r *mongoQuestionBankRepo) Search(ctx context.Context, filter bson.M, search string, skip, limit int64) ([]models.QuestionSearchHit, int64, error) {
+	filter["$text"] = bson.M{"$search": search}
+	total, err := r.collection.CountDocuments(ctx, filter)
+	if err != nil || total == 0 {
+		return []models.QuestionSearchHit{}, total, err
+	}
+
+	score := bson.M{"$meta": "textScore"}
+	cursor, err := r.collection.Find(ctx, filter, options.Find().
+		SetProjection(bson.M{"score": score}).
+		SetSort(bson.D{{Key: "score", Value: score}, {Key: "_id", Value: 1}}).
+		SetSkip(skip).
+		SetLimit(limit))
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var docs []struct {
+		models.QuestionBankEntry `bson:",inline"`
+		Score                    float64 `bson:"score"`
+	}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, 0, err
+	}
+	hits := make([]models.QuestionSearchHit, len(docs))
+	for i, doc := range docs {
+		hits[i] = models.QuestionSearchHit{Question: doc.QuestionBankEntry, Score: doc.Score}
+	}
+	return hits, total, nil
+}
+
+// Sample draws up to size random entries among the sampleable ones matching filter.
 func (r *mongoQuestionBankRepo) Sample(ctx context.Context, filter bson.M, size int) ([]models.QuestionBankEntry, error) {
 	pipeline := mongo.Pipeline{
-		{{Key: "$match", Value: filter}},
+		{{Key: "$match", Value: SampleableQuestionFilter(filter)}},
 		{{Key: "$sample", Value: bson.M{"size": size}}},
 	}
 
@@ -77,28 +434,656 @@ func (r *mongoQuestionBankRepo) Sample(ctx context.Context, filter bson.M, size
 	return questions, nil
 }
 
-func (r *mongoQuestionBankRepo) DeleteByID(ctx context.Context, id primitive.ObjectID) error {
-	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+// LiveQuestionFilter restricts a filter to entries that have not been retired.
+func LiveQuestionFilter(filter bson.M) bson.M {
+	filter["retired_at"] = nil
+	return filter
+}
+
+// SampleableQuestionFilter restricts a filter to live, approved entries. Entries from
+// before the review workflow have no status and count as approved.
+func SampleableQuestionFilter(filter bson.M) bson.M {
+	filter = LiveQuestionFilter(filter)
+	filter["review_status"] = bson.M{"$in": bson.A{models.ReviewStatusApproved, nil}}
+	return filter
+}
+
+// Review moves the entry to review.Status, provided its current status is one of
+// fromStatuses, and appends the review to its history. A comment with an unchanged
+// status only appends.
+func (r *mongoQuestionBankRepo) Review(ctx context.Context, id primitive.ObjectID, fromStatuses []string, review models.ReviewComment) (bool, error) {
+	defer r.invalidateCounts()
+	from := bson.A{}
+	for _, status := range fromStatuses {
+		from = append(from, status)
+		if status == models.ReviewStatusApproved {
+			from = append(from, nil)
+		}
+	}
+
+	res, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": id, "review_status": bson.M{"$in": from}},
+		bson.M{
+			"$set":  bson.M{"review_status": review.Status},
+			"$push": bson.M{"reviews": review},
+		})
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount > 0, nil
+}
+
+// Retire hides matching live entries from sampling. They stay in the bank so item
+// analysis and submissions that reference them keep working.
+func (r *mongoQuestionBankRepo) Retire(ctx context.Context, filter bson.M, retiredBy primitive.ObjectID) (int64, error) {
+	defer r.invalidateCounts()
+	set := bson.M{"retired_at": time.Now()}
+	if !retiredBy.IsZero() {
+		set["retired_by"] = retiredBy
+	}
+	res, err := r.collection.UpdateMany(ctx, LiveQuestionFilter(filter), bson.M{"$set": set})
+	if err != nil {
+		return 0, err
+	}
+	return res.ModifiedCount, nil
+}
+
+func (r *mongoQuestionBankRepo) Restore(ctx context.Context, filter bson.M) (int64, error) {
+	defer r.invalidateCounts()
+	filter["retired_at"] = bson.M{"$ne": nil}
+	res, err := r.collection.UpdateMany(ctx, filter, bson.M{"$unset": bson.M{"retired_at": "", "retired_by": ""}})
+	if err != nil {
+		return 0, err
+	}
+	return res.ModifiedCount, nil
+}
+
+// DeleteMany removes entries along with their version history. Entries are otherwise only
+// ever retired; this undoes writes that must not stay, such as those of a failed import.
+func (r *mongoQuestionBankRepo) DeleteMany(ctx context.Context, ids []primitive.ObjectID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	defer r.invalidateCounts()
+	if _, err := r.collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}}); err != nil {
+		return err
+	}
+	_, err := r.versionCollection.DeleteMany(ctx, bson.M{"entry_id": bson.M{"$in": ids}})
 	return err
 }
 
-func (r *mongoQuestionBankRepo) DeleteByFilter(ctx context.Context, filter bson.M) (int64, error) {
-	res, err := r.collection.DeleteMany(ctx, filter)
+// Update stores the entry's authored fields as a new version and returns its number.
+// Saving unchanged content creates no version. A non-zero question.Version must match the
+// stored one. The author is taken from question.UpdatedBy. An approved entry goes back to
+// review, as its content is no longer what was approved.
+func (r *mongoQuestionBankRepo) Update(ctx context.Context, id primitive.ObjectID, question *models.QuestionBankEntry) (int, error) {
+	current, err := r.FindByID(ctx, id)
 	if err != nil {
 		return 0, err
 	}
-	return res.DeletedCount, nil
+	if question.Version != 0 && question.Version != max(current.Version, 1) {
+		// The caller edited an older version
+		return 0, ErrQuestionVersionConflict
+	}
+	return r.replaceVersioned(ctx, current, question, models.QuestionVersionUpdate, 0, true)
+}
+
+// Revert makes the content of an earlier version current again, as a new version. Like an
+// update, it sends an approved entry back to review.
+func (r *mongoQuestionBankRepo) Revert(ctx context.Context, id primitive.ObjectID, version int, authorID primitive.ObjectID) (int, error) {
+	current, err := r.FindByID(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	target, err := r.FindVersion(ctx, id, version)
+	if err != nil {
+		return 0, err
+	}
+
+	next := target.Content
+	next.UpdatedBy = authorID
+	return r.replaceVersioned(ctx, current, &next, models.QuestionVersionRevert, version, true)
+}
+
+func (r *mongoQuestionBankRepo) ListVersions(ctx context.Context, id primitive.ObjectID) ([]models.QuestionBankVersion, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "version", Value: -1}})
+	cursor, err := r.versionCollection.Find(ctx, bson.M{"entry_id": id}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	versions := []models.QuestionBankVersion{}
+	if err := cursor.All(ctx, &versions); err != nil {
+		return nil, err
+	}
+	return versions, nil
 }
 
-func (r *mongoQuestionBankRepo) Update(ctx context.Context, id primitive.ObjectID, question *models.QuestionBankEntry) error {
-	_, err := r.collection.ReplaceOne(ctx, bson.M{"_id": id}, question)
+func (r *mongoQuestionBankRepo) FindVersion(ctx context.Context, id primitive.ObjectID, version int) (*models.QuestionBankVersion, error) {
+	var v models.QuestionBankVersion
+	if err := r.versionCollection.FindOne(ctx, bson.M{"entry_id": id, "version": version}).Decode(&v); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// replaceVersioned writes next over current, guarded by current's version, and records
+// the snapshot in the same transaction. Derived fields, retirement and review state are
+// carried over from current, except that with resetReview an approved entry goes back to
+// review. Relabels that leave the question itself as approved keep their status.
+func (r *mongoQuestionBankRepo) replaceVersioned(ctx context.Context, current, next *models.QuestionBankEntry, action string, revertedFrom int, resetReview bool) (int, error) {
+	defer r.invalidateCounts()
+	normalizeLabels(next)
+	if reflect.DeepEqual(QuestionContent(*current), QuestionContent(*next)) {
+		if current.Version == 0 {
+			err := r.inTransaction(ctx, func(ctx context.Context) error {
+				if _, err := r.collection.UpdateOne(ctx, bson.M{"_id": current.ID, "version": bson.M{"$exists": false}}, bson.M{"$set": bson.M{"version": 1}}); err != nil {
+					return err
+				}
+				return r.insertFirstVersion(ctx, current)
+			})
+			return 1, err
+		}
+		return current.Version, nil
+	}
+
+	next.ID = current.ID
+	next.ItemStats = current.ItemStats
+	next.Calibration = current.Calibration
+	next.RetiredAt = current.RetiredAt
+	next.RetiredBy = current.RetiredBy
+	next.ReviewStatus = current.ReviewStatus
+	if resetReview && (next.ReviewStatus == "" || next.ReviewStatus == models.ReviewStatusApproved) {
+		next.ReviewStatus = models.ReviewStatusInReview
+	}
+	next.Reviews = current.Reviews
+	next.Version = max(current.Version, 1) + 1
+	next.UpdatedAt = time.Now()
+	next.ContentHash = QuestionContentHash(next)
+
+	filter := bson.M{"_id": current.ID, "version": current.Version}
+	if current.Version == 0 {
+		filter["version"] = bson.M{"$exists": false}
+	}
+	err := r.inTransaction(ctx, func(ctx context.Context) error {
+		res, err := r.collection.ReplaceOne(ctx, filter, next)
+		if err != nil {
+			return err
+		}
+		if res.MatchedCount == 0 {
+			return ErrQuestionVersionConflict
+		}
+		if current.Version == 0 {
+			if err := r.insertFirstVersion(ctx, current); err != nil {
+				return err
+			}
+		}
+		return r.insertVersion(ctx, next, action, revertedFrom)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return next.Version, nil
+}
+
+// insertFirstVersion keeps the original content of an entry that predates versioning as
+// version 1, unless an earlier write already stored it.
+func (r *mongoQuestionBankRepo) insertFirstVersion(ctx context.Context, entry *models.QuestionBankEntry) error {
+	first, err := r.firstVersions(ctx, []models.QuestionBankEntry{*entry}, time.Now())
+	if err != nil || len(first) == 0 {
+		return err
+	}
+	_, err = r.versionCollection.InsertOne(ctx, first[0])
 	return err
 }
 
+func (r *mongoQuestionBankRepo) insertVersion(ctx context.Context, question *models.QuestionBankEntry, action string, revertedFrom int) error {
+	createdAt := question.UpdatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+	_, err := r.versionCollection.InsertOne(ctx, models.QuestionBankVersion{
+		ID:           primitive.NewObjectID(),
+		EntryID:      question.ID,
+		Version:      question.Version,
+		Action:       action,
+		RevertedFrom: revertedFrom,
+		Content:      QuestionContent(*question),
+		AuthorID:     question.UpdatedBy,
+		CreatedAt:    createdAt,
+	})
+	return err
+}
+
+// QuestionContent strips an entry down to its authored fields.
+func QuestionContent(question models.QuestionBankEntry) models.QuestionBankEntry {
+	question.ItemStats = nil
+	question.Calibration = nil
+	question.Version = 0
+	question.UpdatedAt = time.Time{}
+	question.UpdatedBy = primitive.NilObjectID
+	question.RetiredAt = nil
+	question.RetiredBy = primitive.NilObjectID
+	question.ReviewStatus = ""
+	question.Reviews = nil
+	question.ContentHash = ""
+	return question
+}
+
 func (r *mongoQuestionBankRepo) CountByFilter(ctx context.Context, filter bson.M) (int64, error) {
 	return r.collection.CountDocuments(ctx, filter)
 }
 
+// BulkSet applies a $set per entry in one unordered bulk write and returns how many entries matched.
+func (r *mongoQuestionBankRepo) BulkSet(ctx context.Context, updates map[primitive.ObjectID]bson.M) (int64, error) {
+	if len(updates) == 0 {
+		return 0, nil
+	}
+	defer r.invalidateCounts()
+
+	writes := make([]mongo.WriteModel, 0, len(updates))
+	for id, set := range updates {
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": id}).
+			SetUpdate(bson.M{"$set": set}))
+	}
+
+	res, err := r.collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	if err != nil {
+		return 0, err
+	}
+	return res.MatchedCount, nil
+}
+
+// UpdateVersioned applies an update pipeline to every entry matching filter and records
+// each entry whose content it changed as a new version by authorID, all in one transaction.
+// Entries the pipeline leaves as they were keep their version and last update, and only the
+// changed ones are counted as modified. The version number is bumped here, so the pipeline
+// must not set it.
+func (r *mongoQuestionBankRepo) UpdateVersioned(ctx context.Context, filter bson.M, pipeline bson.A, authorID primitive.ObjectID) (int64, int64, error) {
+	var matched, modified int64
+	err := r.inTransaction(ctx, func(ctx context.Context) error {
+		before, err := r.Find(ctx, filter, options.Find())
+		if err != nil {
+			return err
+		}
+		matched, modified = int64(len(before)), 0
+		if len(before) == 0 {
+			return nil
+		}
+		ids := make(bson.A, len(before))
+		byIDBefore := make(map[primitive.ObjectID]*models.QuestionBankEntry, len(before))
+		for i := range before {
+			ids[i] = before[i].ID
+			byIDBefore[before[i].ID] = &before[i]
+		}
+
+		byID := bson.M{"_id": bson.M{"$in": ids}}
+		if _, _, err := r.updateMany(ctx, byID, pipeline); err != nil {
+			return err
+		}
+		after, err := r.Find(ctx, byID, options.Find())
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		var changed []models.QuestionBankEntry
+		var changedIDs bson.A
+		var unchanged []mongo.WriteModel
+		for i := range after {
+			previous := byIDBefore[after[i].ID]
+			// Tags are compared as sets, as $setUnion does not keep their order
+			was, is := QuestionContent(*previous), QuestionContent(after[i])
+			was.Tags, is.Tags = utils.NormalizeTags(was.Tags), utils.NormalizeTags(is.Tags)
+			sort.Strings(was.Tags)
+			sort.Strings(is.Tags)
+			if !reflect.DeepEqual(was, is) {
+				changed = append(changed, *previous)
+				changedIDs = append(changedIDs, previous.ID)
+				continue
+			}
+			// Put back the last update the pipeline stamped on an entry it did not change
+			restore := bson.M{}
+			unset := bson.M{}
+			if previous.UpdatedAt.IsZero() {
+				unset["updated_at"] = ""
+			} else {
+				restore["updated_at"] = previous.UpdatedAt
+			}
+			if previous.UpdatedBy.IsZero() {
+				unset["updated_by"] = ""
+			} else {
+				restore["updated_by"] = previous.UpdatedBy
+			}
+			update := bson.M{}
+			if len(restore) > 0 {
+				update["$set"] = restore
+			}
+			if len(unset) > 0 {
+				update["$unset"] = unset
+			}
+			unchanged = append(unchanged, mongo.NewUpdateOneModel().SetFilter(bson.M{"_id": previous.ID}).SetUpdate(update))
+		}
+		if len(unchanged) > 0 {
+			if _, err := r.collection.BulkWrite(ctx, unchanged, options.BulkWrite().SetOrdered(false)); err != nil {
+				return err
+			}
+		}
+		if len(changed) == 0 {
+			return nil
+		}
+
+		versions, err := r.firstVersions(ctx, changed, now)
+		if err != nil {
+			return err
+		}
+		changedByID := bson.M{"_id": bson.M{"$in": changedIDs}}
+		if _, modified, err = r.updateMany(ctx, changedByID, bson.A{bson.M{"$set": bson.M{"version": nextVersion}}}); err != nil {
+			return err
+		}
+		updated, err := r.Find(ctx, changedByID, options.Find())
+		if err != nil {
+			return err
+		}
+		for i := range updated {
+			versions = append(versions, models.QuestionBankVersion{
+				ID:        primitive.NewObjectID(),
+				EntryID:   updated[i].ID,
+				Version:   updated[i].Version,
+				Action:    models.QuestionVersionUpdate,
+				Content:   QuestionContent(updated[i]),
+				AuthorID:  authorID,
+				CreatedAt: now,
+			})
+		}
+		_, err = r.versionCollection.InsertMany(ctx, versions)
+		return err
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return matched, modified, nil
+}
+
+// nextVersion is the pipeline expression of an entry's next version number. Entries that
+// predate versioning are at version 1.
+var nextVersion = bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$version", 1}}, 1}}
+
+func (r *mongoQuestionBankRepo) updateMany(ctx context.Context, filter bson.M, update interface{}) (int64, int64, error) {
+	defer r.invalidateCounts()
+	res, err := r.collection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		return 0, 0, err
+	}
+	return res.MatchedCount, res.ModifiedCount, nil
+}
+
+// AcceptDifficultySuggestions relabels matching entries with their suggested difficulty
+// and clears the suggestion. Each relabel is recorded as a new version of the entry.
+func (r *mongoQuestionBankRepo) AcceptDifficultySuggestions(ctx context.Context, filter bson.M) (int64, error) {
+	filter["calibration.suggested_difficulty"] = bson.M{"$exists": true, "$ne": ""}
+
+	entries, err := r.Find(ctx, filter, options.Find())
+	if err != nil {
+		return 0, err
+	}
+
+	var accepted int64
+	for i := range entries {
+		current := &entries[i]
+		next := *current
+		next.Difficulty = current.Calibration.SuggestedDifficulty
+		next.UpdatedBy = primitive.NilObjectID
+
+		calibration := *current.Calibration
+		calibration.SuggestedDifficulty = ""
+		current.Calibration = &calibration
+
+		if _, err := r.replaceVersioned(ctx, current, &next, models.QuestionVersionUpdate, 0, false); err != nil {
+			if errors.Is(err, ErrQuestionVersionConflict) {
+				// Edited meanwhile; the suggestion is reconsidered on the next analysis run
+				continue
+			}
+			return accepted, err
+		}
+		accepted++
+	}
+	return accepted, nil
+}
+
+// Relabel moves every entry matching filter, retired ones included, to the category and
+// sub-category relabel maps it to. Each move is recorded as a new version by authorID. The
+// entries moving between the same labels are updated together, and all moves and their
+// snapshots are written in one transaction.
+func (r *mongoQuestionBankRepo) Relabel(ctx context.Context, filter bson.M, relabel func(category, subCategory string) (string, string), authorID primitive.ObjectID) (int64, error) {
+	type labels struct{ category, subCategory string }
+	var moved int64
+	err := r.inTransaction(ctx, func(ctx context.Context) error {
+		entries, err := r.Find(ctx, filter, options.Find())
+		if err != nil {
+			return err
+		}
+
+		moves := map[labels][]primitive.ObjectID{}
+		var moving, next []models.QuestionBankEntry
+		for _, entry := range entries {
+			var to labels
+			to.category, to.subCategory = relabel(entry.Category, entry.SubCategory)
+			if to.category == entry.Category && to.subCategory == entry.SubCategory {
+				continue
+			}
+			moves[to] = append(moves[to], entry.ID)
+			moving = append(moving, entry)
+			entry.Category, entry.SubCategory = to.category, to.subCategory
+			next = append(next, entry)
+		}
+		if len(moving) == 0 {
+			return nil
+		}
+
+		now := time.Now()
+		versions, err := r.firstVersions(ctx, moving, now)
+		if err != nil {
+			return err
+		}
+		for i := range next {
+			versions = append(versions, models.QuestionBankVersion{
+				ID:        primitive.NewObjectID(),
+				EntryID:   next[i].ID,
+				Version:   max(next[i].Version, 1) + 1,
+				Action:    models.QuestionVersionUpdate,
+				Content:   QuestionContent(next[i]),
+				AuthorID:  authorID,
+				CreatedAt: now,
+			})
+		}
+
+		moved = 0
+		for to, ids := range moves {
+			_, modified, err := r.updateMany(ctx, bson.M{"_id": bson.M{"$in": ids}}, bson.A{bson.M{"$set": bson.M{
+				"category":     to.category,
+				"sub_category": to.subCategory,
+				"version":      nextVersion,
+				"updated_at":   now,
+				"updated_by":   authorID,
+			}}})
+			if err != nil {
+				return err
+			}
+			moved += modified
+		}
+		_, err = r.versionCollection.InsertMany(ctx, versions)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return moved, nil
+}
+
+// firstVersions returns the version 1 snapshots of the entries that predate versioning and
+// do not have one yet, so that their original content is kept when they are changed.
+func (r *mongoQuestionBankRepo) firstVersions(ctx context.Context, entries []models.QuestionBankEntry, now time.Time) ([]interface{}, error) {
+	var legacy bson.A
+	for i := range entries {
+		if entries[i].Version == 0 {
+			legacy = append(legacy, entries[i].ID)
+		}
+	}
+	versions := []interface{}{}
+	if len(legacy) == 0 {
+		return versions, nil
+	}
+	stored, err := r.versionCollection.Distinct(ctx, "entry_id", bson.M{"entry_id": bson.M{"$in": legacy}, "version": 1})
+	if err != nil {
+		return nil, err
+	}
+	hasFirstVersion := make(map[primitive.ObjectID]bool, len(stored))
+	for _, id := range stored {
+		if oid, ok := id.(primitive.ObjectID); ok {
+			hasFirstVersion[oid] = true
+		}
+	}
+
+	for i := range entries {
+		entry := &entries[i]
+		if entry.Version != 0 || hasFirstVersion[entry.ID] {
+			continue
+		}
+		createdAt := entry.UpdatedAt
+		if createdAt.IsZero() {
+			createdAt = now
+		}
+		versions = append(versions, models.QuestionBankVersion{
+			ID:        primitive.NewObjectID(),
+			EntryID:   entry.ID,
+			Version:   1,
+			Action:    models.QuestionVersionCreate,
+			Content:   QuestionContent(*entry),
+			AuthorID:  entry.UpdatedBy,
+			CreatedAt: createdAt,
+		})
+	}
+	return versions, nil
+}
+
+// Counts aggregates the live entries per slot and type, the passage groups per category
+// and sub-category, and the tags. The result is cached until the next write to the bank.
+func (r *mongoQuestionBankRepo) Counts(ctx context.Context) (*models.QuestionBankCounts, error) {
+	if cached, ok := utils.GetCache().Get(questionBankCountsCacheKey); ok {
+		return cached.(*models.QuestionBankCounts), nil
+	}
+	r.countsMu.Lock()
+	generation := r.countsGeneration
+	r.countsMu.Unlock()
+
+	live := bson.M{"$match": bson.M{"retired_at": nil}}
+	approved := bson.M{"$eq": bson.A{bson.M{"$ifNull": bson.A{"$review_status", models.ReviewStatusApproved}}, models.ReviewStatusApproved}}
+	pipeline := mongo.Pipeline{{{Key: "$facet", Value: bson.M{
+		"slots": bson.A{
+			live,
+			bson.M{"$group": bson.M{
+				"_id":        bson.M{"category": "$category", "sub_category": "$sub_category", "difficulty": "$difficulty", "type": "$type"},
+				"live":       bson.M{"$sum": 1},
+				"sampleable": bson.M{"$sum": bson.M{"$cond": bson.A{approved, 1, 0}}},
+			}},
+			bson.M{"$sort": bson.D{{Key: "_id.category", Value: 1}, {Key: "_id.sub_category", Value: 1}, {Key: "_id.difficulty", Value: 1}, {Key: "_id.type", Value: 1}}},
+		},
+		"passages": bson.A{
+			bson.M{"$match": bson.M{"retired_at": nil, "passage_text": bson.M{"$nin": bson.A{nil, ""}}}},
+			bson.M{"$group": bson.M{"_id": bson.M{"category": "$category", "sub_category": "$sub_category", "title": "$passage_title", "text": "$passage_text"}}},
+			bson.M{"$group": bson.M{"_id": bson.M{"category": "$_id.category", "sub_category": "$_id.sub_category"}, "groups": bson.M{"$sum": 1}}},
+			bson.M{"$sort": bson.D{{Key: "_id.category", Value: 1}, {Key: "_id.sub_category", Value: 1}}},
+		},
+		"tags": bson.A{
+			live,
+			bson.M{"$unwind": "$tags"},
+			bson.M{"$group": bson.M{"_id": "$tags", "count": bson.M{"$sum": 1}}},
+			bson.M{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}},
+		},
+		"retired": bson.A{
+			bson.M{"$match": bson.M{"retired_at": bson.M{"$ne": nil}}},
+			bson.M{"$count": "count"},
+		},
+	}}}}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	type slotKey struct {
+		Category    string `bson:"category"`
+		SubCategory string `bson:"sub_category"`
+		Difficulty  string `bson:"difficulty"`
+		Type        string `bson:"type"`
+	}
+	var facets []struct {
+		Slots []struct {
+			ID         slotKey `bson:"_id"`
+			Live       int64   `bson:"live"`
+			Sampleable int64   `bson:"sampleable"`
+		} `bson:"slots"`
+		Passages []struct {
+			ID     slotKey `bson:"_id"`
+			Groups int64   `bson:"groups"`
+		} `bson:"passages"`
+		Tags []struct {
+			Tag   string `bson:"_id"`
+			Count int64  `bson:"count"`
+		} `bson:"tags"`
+		Retired []struct {
+			Count int64 `bson:"count"`
+		} `bson:"retired"`
+	}
+	if err := cursor.All(ctx, &facets); err != nil {
+		return nil, err
+	}
+
+	counts := &models.QuestionBankCounts{
+		Slots:         []models.QuestionSlotCount{},
+		PassageGroups: []models.PassageGroupCount{},
+		Tags:          []models.TagCount{},
+		ComputedAt:    time.Now(),
+	}
+	if len(facets) > 0 {
+		f := facets[0]
+		for _, slot := range f.Slots {
+			counts.Slots = append(counts.Slots, models.QuestionSlotCount{
+				Category: slot.ID.Category, SubCategory: slot.ID.SubCategory, Difficulty: slot.ID.Difficulty,
+				Type: models.QuestionType(slot.ID.Type), Live: slot.Live, Sampleable: slot.Sampleable,
+			})
+		}
+		for _, passage := range f.Passages {
+			counts.PassageGroups = append(counts.PassageGroups, models.PassageGroupCount{Category: passage.ID.Category, SubCategory: passage.ID.SubCategory, Groups: passage.Groups})
+		}
+		for _, tag := range f.Tags {
+			counts.Tags = append(counts.Tags, models.TagCount{Tag: tag.Tag, Count: tag.Count})
+		}
+		if len(f.Retired) > 0 {
+			counts.Retired = f.Retired[0].Count
+		}
+	}
+
+	r.countsMu.Lock()
+	if r.countsGeneration == generation {
+		utils.GetCache().Set(questionBankCountsCacheKey, counts, questionBankCountsTTL)
+	}
+	r.countsMu.Unlock()
+	return counts, nil
+}
+
+// invalidateCounts drops the cached counts after a write to the bank.
+func (r *mongoQuestionBankRepo) invalidateCounts() {
+	r.countsMu.Lock()
+	r.countsGeneration++
+	utils.GetCache().Delete(questionBankCountsCacheKey)
+	r.countsMu.Unlock()
+}
+
 func (r *mongoQuestionBankRepo) SaveBankConfig(ctx context.Context, config *models.QuestionBankConfig) error {
 	// We only keep one config document. Use a fixed ID or just ReplaceOne with upsert.
 	opts := options.Replace().SetUpsert(true)
@@ -123,3 +1108,39 @@ func (r *mongoQuestionBankRepo) GetBankConfig(ctx context.Context) (*models.Ques
 	}
 	return &config, nil
 }
+
+// WithTransaction runs fn in a MongoDB transaction. Repository calls made with the context
+// passed to fn, on this repository or others sharing the client, are part of it.
+func (r *mongoQuestionBankRepo) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	// Entries written in the transaction may have been counted before it committed
+	defer r.invalidateCounts()
+
+	err := r.collection.Database().Client().UseSession(ctx, func(sessionCtx mongo.SessionContext) error {
+		_, err := sessionCtx.WithTransaction(sessionCtx, func(txCtx mongo.SessionContext) (interface{}, error) {
+			return nil, fn(txCtx)
+		})
+		return err
+	})
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) && cmdErr.Code == 20 {
+		// IllegalOperation: transaction numbers are only allowed on a replica set member or mongos
+		return ErrTransactionsUnsupported
+	}
+	return err
+}
+
+// inTransaction runs fn in a transaction, joining the caller's if there is one. On a
+// standalone server, which has no transactions, fn runs without one, as the bank did
+// before transactions were used.
+func (r *mongoQuestionBankRepo) inTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if mongo.SessionFromContext(ctx) != nil || r.noTransactions.Load() {
+		return fn(ctx)
+	}
+	err := r.WithTransaction(ctx, fn)
+	if errors.Is(err, ErrTransactionsUnsupported) {
+		// The server rejects the first operation, so nothing was written yet
+		r.noTransactions.Store(true)
+		return fn(ctx)
+	}
+	return err
+}
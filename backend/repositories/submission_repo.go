@@ -19,6 +19,12 @@ type SubmissionRepository interface {
 	FindByID(ctx context.Context, id primitive.ObjectID) (*models.Submission, error)
 	FindOne(ctx context.Context, filter bson.M) (*models.Submission, error)
 	FindAll(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]models.Submission, error)
+	ForEach(ctx context.Context, filter bson.M, opts *options.FindOptions, fn func(*models.Submission) error) (int, error)
+	FindLatestAttempt(ctx context.Context, assessmentID, candidateID primitive.ObjectID) (*models.Submission, error)
+	CountAttempts(ctx context.Context, assessmentID, candidateID primitive.ObjectID) (int, error)
+	NextAttemptNumber(ctx context.Context, assessmentID, candidateID primitive.ObjectID) (int, error)
+	UpdateIfVersion(ctx context.Context, submission *models.Submission, expectedVersion int64) (bool, error)
+	UpsertAnswer(ctx context.Context, id primitive.ObjectID, expectedVersion int64, answer models.Answer) (bool, error)
 	AddVideoEvidence(ctx context.Context, candidateID, assessmentID primitive.ObjectID, timestamp string, videoURL string) error
 }
 
@@ -36,9 +42,15 @@ func (r *mongoSubmissionRepo) EnsureIndexes() {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	// Candidates may now hold several attempts per assessment, so the old
+	// one-submission-per-candidate unique index is replaced by a per-attempt one.
+	if _, err := r.collection.Indexes().DropOne(ctx, "assessment_id_1_candidate_id_1"); err != nil {
+		fmt.Printf("Note: legacy submissions index not dropped: %v\n", err)
+	}
+
 	indexModels := []mongo.IndexModel{
 		{
-			Keys: bson.D{{Key: "assessment_id", Value: 1}, {Key: "candidate_id", Value: 1}},
+			Keys:    bson.D{{Key: "assessment_id", Value: 1}, {Key: "candidate_id", Value: 1}, {Key: "attempt_number", Value: 1}},
 			Options: options.Index().SetUnique(true),
 		},
 		{
@@ -106,19 +118,156 @@ func (r *mongoSubmissionRepo) FindAll(ctx context.Context, filter bson.M, opts *
 	return subs, nil
 }
 
+// FindLatestAttempt returns the candidate's most recent attempt at the assessment.
+// Deleted attempts are ignored.
+func (r *mongoSubmissionRepo) FindLatestAttempt(ctx context.Context, assessmentID, candidateID primitive.ObjectID) (*models.Submission, error) {
+	var sub models.Submission
+	opts := options.FindOne().SetSort(bson.D{{Key: "attempt_number", Value: -1}, {Key: "created_at", Value: -1}})
+	filter := bson.M{"assessment_id": assessmentID, "candidate_id": candidateID, "deleted_at": nil}
+	err := r.collection.FindOne(ctx, filter, opts).Decode(&sub)
+	if err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+// CountAttempts returns how many attempts the candidate has made at the assessment,
+// not counting deleted ones.
+func (r *mongoSubmissionRepo) CountAttempts(ctx context.Context, assessmentID, candidateID primitive.ObjectID) (int, error) {
+	count, err := r.collection.CountDocuments(ctx, bson.M{"assessment_id": assessmentID, "candidate_id": candidateID, "deleted_at": nil})
+	if err != nil {
+		return 0, err
+	}
+	return int(count), nil
+}
+
+// NextAttemptNumber returns the number for the candidate's next attempt at the assessment.
+// Deleted attempts keep their numbers, so they are included to stay clear of the unique
+// (assessment, candidate, attempt) index.
+func (r *mongoSubmissionRepo) NextAttemptNumber(ctx context.Context, assessmentID, candidateID primitive.ObjectID) (int, error) {
+	var sub models.Submission
+	opts := options.FindOne().
+		SetSort(bson.D{{Key: "attempt_number", Value: -1}}).
+		SetProjection(bson.M{"attempt_number": 1})
+	err := r.collection.FindOne(ctx, bson.M{"assessment_id": assessmentID, "candidate_id": candidateID}, opts).Decode(&sub)
+	if err == mongo.ErrNoDocuments {
+		return 1, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	// Submissions created before retakes existed count as the first attempt
+	if sub.AttemptNumber < 1 {
+		return 2, nil
+	}
+	return sub.AttemptNumber + 1, nil
+}
+
+// ForEach streams matching submissions to fn without loading them all into memory.
+// It stops at the first error from fn and returns how many submissions were visited.
+func (r *mongoSubmissionRepo) ForEach(ctx context.Context, filter bson.M, opts *options.FindOptions, fn func(*models.Submission) error) (int, error) {
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	visited := 0
+	for cursor.Next(ctx) {
+		var submission models.Submission
+		if err := cursor.Decode(&submission); err != nil {
+			return visited, err
+		}
+		visited++
+		if err := fn(&submission); err != nil {
+			return visited, err
+		}
+	}
+	return visited, cursor.Err()
+}
+
+// versionFilter matches the expected version; submissions written before versioning count as version 0.
+func versionFilter(expectedVersion int64) bson.M {
+	if expectedVersion == 0 {
+		return bson.M{"$or": bson.A{bson.M{"version": 0}, bson.M{"version": bson.M{"$exists": false}}}}
+	}
+	return bson.M{"version": expectedVersion}
+}
+
+// UpdateIfVersion writes the submission only if it is still in progress and at the
+// expected version, bumping the version on success. It reports whether a write happened.
+func (r *mongoSubmissionRepo) UpdateIfVersion(ctx context.Context, submission *models.Submission, expectedVersion int64) (bool, error) {
+	filter := versionFilter(expectedVersion)
+	filter["_id"] = submission.ID
+	filter["status"] = "in_progress"
+
+	submission.Version = expectedVersion + 1
+	res, err := r.collection.UpdateOne(ctx, filter, bson.M{"$set": submission})
+	if err != nil {
+		submission.Version = expectedVersion
+		return false, err
+	}
+	if res.MatchedCount == 0 {
+		submission.Version = expectedVersion
+		return false, nil
+	}
+	return true, nil
+}
+
+// UpsertAnswer replaces or appends a single answer without touching the rest of the
+// submission. Like UpdateIfVersion it only applies to in-progress submissions at the
+// expected version.
+func (r *mongoSubmissionRepo) UpsertAnswer(ctx context.Context, id primitive.ObjectID, expectedVersion int64, answer models.Answer) (bool, error) {
+	now := time.Now()
+
+	filter := versionFilter(expectedVersion)
+	filter["_id"] = id
+	filter["status"] = "in_progress"
+	filter["answers.question_id"] = answer.QuestionID
+
+	res, err := r.collection.UpdateOne(ctx, filter, bson.M{
+		"$set": bson.M{
+			"answers.$.value":      answer.Value,
+			"answers.$.is_correct": false,
+			"answers.$.points":     0,
+			"updated_at":           now,
+		},
+		"$inc": bson.M{"version": 1},
+	})
+	if err != nil {
+		return false, err
+	}
+	if res.MatchedCount > 0 {
+		return true, nil
+	}
+
+	// Not answered yet: append. A pipeline update copes with a null answers field,
+	// and $literal keeps values such as "$100" from being read as field paths.
+	filter["answers.question_id"] = bson.M{"$ne": answer.QuestionID}
+	res, err = r.collection.UpdateOne(ctx, filter, mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"answers": bson.M{"$concatArrays": bson.A{
+				bson.M{"$ifNull": bson.A{"$answers", bson.A{}}},
+				bson.A{bson.M{"$literal": answer}},
+			}},
+			"version":    bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$version", 0}}, 1}},
+			"updated_at": now,
+		}}},
+	})
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount > 0, nil
+}
+
 func (r *mongoSubmissionRepo) AddVideoEvidence(ctx context.Context, candidateID, assessmentID primitive.ObjectID, timestamp string, videoURL string) error {
 	logger := utils.GetLogger()
 	logger.Infof("Attempt TS: %s, URL: %s, CID: %s, AID: %s", timestamp, videoURL, candidateID.Hex(), assessmentID.Hex())
 	fmt.Printf("[DB Update] Attempting to link video for Candidate: %s, Assessment: %s, TS: %s\n", candidateID.Hex(), assessmentID.Hex(), timestamp)
-	
-	filter := bson.M{
-		"candidate_id":  candidateID,
-		"assessment_id": assessmentID,
-	}
 
 	maxRetries := 3
 	for attempt := 1; attempt <= maxRetries; attempt++ {
-		submission, err := r.FindOne(ctx, filter)
+		submission, err := r.FindLatestAttempt(ctx, assessmentID, candidateID)
 		if err != nil {
 			logger.Errorf("Submission not found (attempt %d): %v", attempt, err)
 			if attempt == maxRetries {
@@ -146,7 +295,7 @@ func (r *mongoSubmissionRepo) AddVideoEvidence(ctx context.Context, candidateID,
 			if diff < 0 {
 				diff = -diff
 			}
-			
+
 			if diff < 2*time.Second {
 				logger.Infof("Match! Index %d, DB TS: %s (attempt %d)", i, v.Timestamp.Format(time.RFC3339), attempt)
 				fmt.Printf("[DB Update] Match found! Updating violation %d with evidence %s\n", i, videoURL)
@@ -157,7 +306,7 @@ func (r *mongoSubmissionRepo) AddVideoEvidence(ctx context.Context, candidateID,
 		}
 
 		if found {
-			err = r.Update(ctx, submission.ID, submission)
+			err = r.setViolationEvidence(ctx, submission, videoURL)
 			if err != nil {
 				logger.Errorf("Update failed: %v", err)
 			} else {
@@ -187,3 +336,18 @@ func (r *mongoSubmissionRepo) AddVideoEvidence(ctx context.Context, candidateID,
 
 	return fmt.Errorf("no matching violation found for timestamp %s after retries", timestamp)
 }
+
+// setViolationEvidence updates only the evidence of matching violations, so a concurrent
+// progress save is not overwritten with a stale document. The version is left alone since
+// the candidate's client did not make this change.
+func (r *mongoSubmissionRepo) setViolationEvidence(ctx context.Context, submission *models.Submission, videoURL string) error {
+	set := bson.M{"updated_at": time.Now()}
+	for i, v := range submission.Violations {
+		if v.Evidence == videoURL {
+			set[fmt.Sprintf("violations.%d.evidence", i)] = videoURL
+		}
+	}
+
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": submission.ID}, bson.M{"$set": set})
+	return err
+}
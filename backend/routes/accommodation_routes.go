@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"hireit-backend/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func AccommodationRoutes(r *gin.RouterGroup, accommodationCtrl *controllers.AccommodationController) {
+	accommodations := r.Group("/accommodations")
+	{
+		accommodations.GET("", accommodationCtrl.GetAccommodations)
+		accommodations.POST("", accommodationCtrl.CreateAccommodation)
+		accommodations.PUT("/:id", accommodationCtrl.UpdateAccommodation)
+		accommodations.DELETE("/:id", accommodationCtrl.DeleteAccommodation)
+	}
+}
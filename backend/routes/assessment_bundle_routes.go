@@ -0,0 +1,12 @@
+package routes
+
+import (
+	"hireit-backend/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func AssessmentBundleRoutes(r *gin.RouterGroup, bundleCtrl *controllers.AssessmentBundleController) {
+	r.GET("/assessments/:id/bundle", bundleCtrl.ExportBundle)
+	r.POST("/assessments/bundle", bundleCtrl.ImportBundle)
+}
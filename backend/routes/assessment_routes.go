@@ -21,9 +21,15 @@ func AssessmentRoutes(r *gin.RouterGroup, assessCtrl *controllers.AssessmentCont
 		assessments.GET("/:id", assessCtrl.GetAssessmentByID)
 		assessments.POST("/:id/submit", assessCtrl.SubmitAssessment)
 		assessments.POST("/:id/progress", assessCtrl.SaveAssessmentProgress)
+		assessments.PUT("/:id/answers/:questionId", assessCtrl.SaveAnswer)
+		assessments.GET("/:id/adaptive/next", assessCtrl.GetNextAdaptiveQuestion)
+		assessments.POST("/:id/adaptive/answer", assessCtrl.AnswerAdaptiveQuestion)
 		assessments.GET("/:id/result", assessCtrl.GetCandidateResult)
+		assessments.GET("/:id/attempts", assessCtrl.GetAttemptHistory)
+		assessments.POST("/:id/retake", assessCtrl.StartRetake)
 		assessments.PUT("/:id", assessCtrl.UpdateAssessment)
 		assessments.DELETE("/:id", assessCtrl.DeleteAssessment)
 		assessments.GET("/:id/submissions", assessCtrl.GetSubmissions)
+		assessments.GET("/:id/question-times", assessCtrl.GetQuestionTimeStats)
 	}
 }
@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"hireit-backend/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func InvitationRoutes(r *gin.RouterGroup, invitationCtrl *controllers.InvitationController) {
+	r.POST("/assessments/:id/invitations", invitationCtrl.CreateInvitation)
+	r.GET("/assessments/:id/invitations", invitationCtrl.GetInvitations)
+	r.POST("/assessments/:id/invitations/bulk", invitationCtrl.BulkInvite)
+	r.GET("/invitations/bulk/:jobId", invitationCtrl.GetBulkInviteJob)
+	r.DELETE("/invitations/:id", invitationCtrl.RevokeInvitation)
+}
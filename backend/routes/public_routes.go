@@ -6,11 +6,12 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func SetupPublicRoutes(router *gin.Engine, publicCtrl *controllers.PublicController) {
+func SetupPublicRoutes(router *gin.Engine, publicCtrl *controllers.PublicController, invitationCtrl *controllers.InvitationController) {
 	public := router.Group("/api/public")
 	{
 		public.POST("/start", publicCtrl.StartPublicAssessment)
 		public.POST("/demo", publicCtrl.StartDemoAssessment)
 		public.POST("/start-otp", publicCtrl.StartAssessmentOTP)
+		public.POST("/invitations/accept", invitationCtrl.AcceptInvitation)
 	}
 }
@@ -14,11 +14,14 @@ func SetupRoutes(r *gin.Engine,
 	publicCtrl *controllers.PublicController,
 	assessCtrl *controllers.AssessmentController,
 	interviewCtrl *controllers.InterviewController,
+	invitationCtrl *controllers.InvitationController,
+	accommodationCtrl *controllers.AccommodationController,
+	bundleCtrl *controllers.AssessmentBundleController,
 ) {
 	// Public Routes
 	AuthRoutes(r, authCtrl, googleCtrl)
 	YouTubeRoutes(r, youtubeCtrl)
-	SetupPublicRoutes(r, publicCtrl)
+	SetupPublicRoutes(r, publicCtrl, invitationCtrl)
 
 	// Protected Routes
 	protected := r.Group("/api")
@@ -27,6 +30,9 @@ func SetupRoutes(r *gin.Engine,
 		UserRoutes(protected)
 		AssessmentRoutes(protected, assessCtrl)
 		InterviewRoutes(protected, interviewCtrl)
+		InvitationRoutes(protected, invitationCtrl)
+		AccommodationRoutes(protected, accommodationCtrl)
+		AssessmentBundleRoutes(protected, bundleCtrl)
 
 		// YouTube Evidence Route
 		protected.POST("/assessments/:id/upload-evidence", youtubeCtrl.UploadEvidence)
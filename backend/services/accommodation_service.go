@@ -0,0 +1,231 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"hireit-backend/models"
+	"hireit-backend/repositories"
+	"hireit-backend/utils"
+	"math"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Allowance for the final auto-submit request to arrive after the timer hits zero.
+const deadlineGracePeriod = 2 * time.Minute
+
+var (
+	ErrAssessmentClosed = errors.New("assessment window has closed")
+	ErrDeadlinePassed   = errors.New("time limit exceeded; attempt was auto-submitted")
+)
+
+type AccommodationService interface {
+	CreateAccommodation(ctx context.Context, createdBy string, req *models.CreateAccommodationRequest) (*models.Accommodation, error)
+	GetAccommodations(ctx context.Context, candidateID, assessmentID string) ([]models.Accommodation, error)
+	UpdateAccommodation(ctx context.Context, id string, req *models.UpdateAccommodationRequest) error
+	DeleteAccommodation(ctx context.Context, id string) error
+}
+
+type accommodationService struct {
+	repo     repositories.AccommodationRepository
+	userRepo repositories.UserRepository
+}
+
+func NewAccommodationService(repo repositories.AccommodationRepository, userRepo repositories.UserRepository) AccommodationService {
+	return &accommodationService{repo: repo, userRepo: userRepo}
+}
+
+func (s *accommodationService) CreateAccommodation(ctx context.Context, createdBy string, req *models.CreateAccommodationRequest) (*models.Accommodation, error) {
+	cID, err := utils.ToObjectID(req.CandidateID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.userRepo.FindByID(ctx, cID); err != nil {
+		return nil, errors.New("candidate not found")
+	}
+	creatorID, _ := utils.ToObjectID(createdBy)
+
+	accommodation := &models.Accommodation{
+		CandidateID:              cID,
+		TimeMultiplier:           req.TimeMultiplier,
+		ExtraMinutes:             req.ExtraMinutes,
+		ExtendedUntil:            req.ExtendedUntil,
+		DisabledProctoringChecks: req.DisabledProctoringChecks,
+		Reason:                   utils.SanitizeStrict(req.Reason),
+		CreatedBy:                creatorID,
+		CreatedAt:                time.Now(),
+		UpdatedAt:                time.Now(),
+	}
+	if req.AssessmentID != "" {
+		aID, err := utils.ToObjectID(req.AssessmentID)
+		if err != nil {
+			return nil, err
+		}
+		accommodation.AssessmentID = &aID
+	}
+
+	if _, err := s.repo.Create(ctx, accommodation); err != nil {
+		return nil, err
+	}
+	return accommodation, nil
+}
+
+func (s *accommodationService) GetAccommodations(ctx context.Context, candidateID, assessmentID string) ([]models.Accommodation, error) {
+	filter := bson.M{"deleted_at": nil}
+	if candidateID != "" {
+		cID, err := utils.ToObjectID(candidateID)
+		if err != nil {
+			return nil, err
+		}
+		filter["candidate_id"] = cID
+	}
+	if assessmentID != "" {
+		aID, err := utils.ToObjectID(assessmentID)
+		if err != nil {
+			return nil, err
+		}
+		filter["assessment_id"] = aID
+	}
+
+	return s.repo.FindAll(ctx, filter, options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+}
+
+func (s *accommodationService) UpdateAccommodation(ctx context.Context, idStr string, req *models.UpdateAccommodationRequest) error {
+	id, err := utils.ToObjectID(idStr)
+	if err != nil {
+		return err
+	}
+	if _, err := s.repo.FindByID(ctx, id); err != nil {
+		return errors.New("accommodation not found or deleted")
+	}
+
+	return s.repo.Update(ctx, id, bson.M{
+		"$set": bson.M{
+			"time_multiplier":            req.TimeMultiplier,
+			"extra_minutes":              req.ExtraMinutes,
+			"extended_until":             req.ExtendedUntil,
+			"disabled_proctoring_checks": req.DisabledProctoringChecks,
+			"reason":                     utils.SanitizeStrict(req.Reason),
+			"updated_at":                 time.Now(),
+		},
+	})
+}
+
+func (s *accommodationService) DeleteAccommodation(ctx context.Context, idStr string) error {
+	id, err := utils.ToObjectID(idStr)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	return s.repo.Update(ctx, id, bson.M{"$set": bson.M{"deleted_at": &now}})
+}
+
+// resolveAccommodation returns the candidate's accommodation for the assessment,
+// preferring an assessment-specific record over a general one. Nil means none applies.
+func resolveAccommodation(ctx context.Context, repo repositories.AccommodationRepository, aID, cID primitive.ObjectID) *models.Accommodation {
+	if repo == nil {
+		return nil
+	}
+
+	accommodations, err := repo.FindAll(ctx, bson.M{
+		"candidate_id":  cID,
+		"assessment_id": bson.M{"$in": bson.A{aID, nil}},
+		"deleted_at":    nil,
+	}, options.Find().SetSort(bson.D{{Key: "updated_at", Value: -1}}))
+	if err != nil || len(accommodations) == 0 {
+		return nil
+	}
+
+	for i := range accommodations {
+		if accommodations[i].AssessmentID != nil {
+			return &accommodations[i]
+		}
+	}
+	return &accommodations[0]
+}
+
+// effectiveDuration applies the time multiplier and extra minutes to the assessment duration.
+func effectiveDuration(baseMinutes int, accommodation *models.Accommodation) int {
+	if accommodation == nil || baseMinutes <= 0 {
+		return baseMinutes
+	}
+
+	minutes := baseMinutes
+	if accommodation.TimeMultiplier > 1 {
+		minutes = int(math.Ceil(float64(baseMinutes) * accommodation.TimeMultiplier))
+	}
+	return minutes + accommodation.ExtraMinutes
+}
+
+// checkAssessmentWindow rejects new attempts once the assessment has closed,
+// honouring any extended window on the candidate's accommodation.
+func checkAssessmentWindow(assessment *models.Assessment, accommodation *models.Accommodation, now time.Time) error {
+	if assessment == nil || assessment.ClosesAt == nil {
+		return nil
+	}
+
+	closesAt := *assessment.ClosesAt
+	if accommodation != nil && accommodation.ExtendedUntil != nil && accommodation.ExtendedUntil.After(closesAt) {
+		closesAt = *accommodation.ExtendedUntil
+	}
+	if now.After(closesAt) {
+		return ErrAssessmentClosed
+	}
+	return nil
+}
+
+// applyAccommodation sets the attempt deadline and snapshots the accommodation on the submission.
+func applyAccommodation(submission *models.Submission, assessment *models.Assessment, accommodation *models.Accommodation) {
+	if assessment == nil {
+		return
+	}
+
+	minutes := effectiveDuration(assessment.Duration, accommodation)
+	if minutes > 0 && !submission.StartedAt.IsZero() {
+		deadline := submission.StartedAt.Add(time.Duration(minutes) * time.Minute)
+		submission.DeadlineAt = &deadline
+	}
+
+	if accommodation == nil {
+		submission.Accommodation = nil
+		return
+	}
+
+	submission.Accommodation = &models.AppliedAccommodation{
+		AccommodationID:          accommodation.ID,
+		TimeMultiplier:           accommodation.TimeMultiplier,
+		ExtraMinutes:             accommodation.ExtraMinutes,
+		BaseDuration:             assessment.Duration,
+		EffectiveDuration:        minutes,
+		ExtendedUntil:            accommodation.ExtendedUntil,
+		DisabledProctoringChecks: accommodation.DisabledProctoringChecks,
+		Reason:                   accommodation.Reason,
+		AppliedAt:                time.Now(),
+	}
+}
+
+// markWaivedViolations flags violations raised by checks the accommodation disabled.
+// They are kept so auditors still see what the proctoring client reported.
+func markWaivedViolations(violations []models.Violation, applied *models.AppliedAccommodation) {
+	if applied == nil || len(applied.DisabledProctoringChecks) == 0 {
+		return
+	}
+
+	disabled := make(map[string]bool, len(applied.DisabledProctoringChecks))
+	for _, check := range applied.DisabledProctoringChecks {
+		disabled[check] = true
+	}
+	for i := range violations {
+		if disabled[violations[i].Type] {
+			violations[i].Waived = true
+		}
+	}
+}
+
+// deadlinePassed reports whether the attempt is beyond its deadline plus grace period.
+func deadlinePassed(submission *models.Submission, now time.Time) bool {
+	return submission.DeadlineAt != nil && now.After(submission.DeadlineAt.Add(deadlineGracePeriod))
+}
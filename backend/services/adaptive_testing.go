@@ -0,0 +1,459 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"hireit-backend/models"
+	"hireit-backend/repositories"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	defaultAdaptiveMinItems = 5
+	defaultAdaptiveMaxItems = 20
+	// Each item adds at most 0.25 information under the Rasch model, so 0.45 needs at least ~16 items.
+	defaultAdaptiveTargetSE = 0.45
+	// Uncalibrated entries are placed at the middle of their label's band.
+	labelDifficultyLogit = 1.5
+	// The next item is drawn at random from this many best-matching items, so the
+	// same few items are not shown to every candidate of similar ability.
+	adaptiveExposureTopK = 5
+	// Scaled score = adaptiveScaleMean + adaptiveScaleSD * ability, clamped to the range below.
+	adaptiveScaleMean = 500
+	adaptiveScaleSD   = 100
+	adaptiveScaleMin  = 100
+	adaptiveScaleMax  = 900
+)
+
+var (
+	ErrNotAdaptive          = errors.New("assessment is not in adaptive mode")
+	ErrAdaptiveItemMismatch = errors.New("answer does not match the item currently presented")
+	ErrAdaptiveAnswerRoute  = errors.New("adaptive attempts are answered one item at a time")
+	ErrInvalidAdaptiveSetup = errors.New("invalid adaptive configuration")
+	ErrInvalidQuestionID    = errors.New("invalid question ID")
+)
+
+// validateAssessmentMode checks that an adaptive assessment has its configuration.
+func validateAssessmentMode(assessment *models.Assessment) error {
+	switch assessment.Mode {
+	case "", models.AssessmentModeFixed:
+		return nil
+	case models.AssessmentModeAdaptive:
+		if assessment.Adaptive == nil || assessment.Adaptive.Category == "" {
+			return fmt.Errorf("%w: a category is required", ErrInvalidAdaptiveSetup)
+		}
+		cfg := adaptiveSettings(assessment)
+		if cfg.MinItems > cfg.MaxItems {
+			return fmt.Errorf("%w: min_items cannot exceed max_items", ErrInvalidAdaptiveSetup)
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: unknown mode %q", ErrInvalidAdaptiveSetup, assessment.Mode)
+	}
+}
+
+func isAdaptive(assessment *models.Assessment) bool {
+	return assessment != nil && assessment.Mode == models.AssessmentModeAdaptive && assessment.Adaptive != nil
+}
+
+// adaptiveSettings fills in defaults for the stopping rule.
+func adaptiveSettings(assessment *models.Assessment) models.AdaptiveConfig {
+	cfg := *assessment.Adaptive
+	if cfg.MinItems <= 0 {
+		cfg.MinItems = defaultAdaptiveMinItems
+	}
+	if cfg.MaxItems <= 0 {
+		cfg.MaxItems = defaultAdaptiveMaxItems
+	}
+	if cfg.TargetSE <= 0 {
+		cfg.TargetSE = defaultAdaptiveTargetSE
+	}
+	return cfg
+}
+
+func newAdaptiveState() *models.AdaptiveState {
+	// The prior is standard normal, so the starting estimate is 0 with SE 1.
+	return &models.AdaptiveState{StandardError: 1, Items: []models.AdaptiveItem{}}
+}
+
+// estimateAbility computes the expected a posteriori ability and its posterior standard
+// deviation under a standard normal prior. Unlike maximum likelihood it stays finite when
+// every answer so far is right (or wrong).
+func estimateAbility(items []models.AdaptiveItem) (float64, float64) {
+	const points = 81
+	var weights [points]float64
+	var thetas [points]float64
+
+	maxLog := math.Inf(-1)
+	for k := 0; k < points; k++ {
+		theta := -4 + 8*float64(k)/float64(points-1)
+		logPost := -theta * theta / 2
+		for _, item := range items {
+			if item.Correct == nil {
+				continue
+			}
+			p := raschProbability(theta, item.Difficulty)
+			if *item.Correct {
+				logPost += math.Log(p)
+			} else {
+				logPost += math.Log(1 - p)
+			}
+		}
+		thetas[k] = theta
+		weights[k] = logPost
+		maxLog = math.Max(maxLog, logPost)
+	}
+
+	sum, mean := 0.0, 0.0
+	for k := range weights {
+		weights[k] = math.Exp(weights[k] - maxLog)
+		sum += weights[k]
+		mean += weights[k] * thetas[k]
+	}
+	mean /= sum
+
+	variance := 0.0
+	for k := range weights {
+		variance += weights[k] * (thetas[k] - mean) * (thetas[k] - mean)
+	}
+	return roundStat(mean), roundStat(math.Sqrt(variance / sum))
+}
+
+func adaptiveScaledScore(theta float64) int {
+	score := int(math.Round(adaptiveScaleMean + adaptiveScaleSD*theta))
+	return max(adaptiveScaleMin, min(adaptiveScaleMax, score))
+}
+
+func answeredAdaptiveItems(state *models.AdaptiveState) int {
+	answered := 0
+	for _, item := range state.Items {
+		if item.Correct != nil {
+			answered++
+		}
+	}
+	return answered
+}
+
+// pendingAdaptiveItem returns the item waiting for an answer, if any.
+func pendingAdaptiveItem(state *models.AdaptiveState) *models.AdaptiveItem {
+	if n := len(state.Items); n > 0 && state.Items[n-1].Correct == nil {
+		return &state.Items[n-1]
+	}
+	return nil
+}
+
+func adaptiveStopReason(cfg models.AdaptiveConfig, state *models.AdaptiveState) string {
+	answered := answeredAdaptiveItems(state)
+	switch {
+	case answered >= cfg.MaxItems:
+		return models.AdaptiveStopMaxItems
+	case answered >= cfg.MinItems && state.StandardError <= cfg.TargetSE:
+		return models.AdaptiveStopPrecision
+	}
+	return ""
+}
+
+// entryDifficultyLogit places an entry on the ability scale, preferring its calibration.
+func entryDifficultyLogit(entry models.QuestionBankEntry) float64 {
+	if entry.Calibration != nil {
+		return entry.Calibration.Difficulty
+	}
+	switch canonicalDifficulty(entry.Difficulty) {
+	case models.DifficultyEasy:
+		return -labelDifficultyLogit
+	case models.DifficultyHard:
+		return labelDifficultyLogit
+	}
+	return 0
+}
+
+// finishAdaptive closes the ability estimate and returns the scaled score.
+func finishAdaptive(state *models.AdaptiveState, reason string) int {
+	if state.StopReason == "" {
+		state.StopReason = reason
+	}
+	state.ScaledScore = adaptiveScaledScore(state.Theta)
+	return state.ScaledScore
+}
+
+// selectAdaptiveItem picks an unused entry from the configured slot whose difficulty is
+// closest to the current ability estimate, which maximises Rasch item information.
+func (s *submissionService) selectAdaptiveItem(ctx context.Context, cfg models.AdaptiveConfig, state *models.AdaptiveState) (*models.QuestionBankEntry, float64, error) {
+	used := make([]primitive.ObjectID, 0, len(state.Items))
+	for _, item := range state.Items {
+		used = append(used, item.QuestionID)
+	}
+
+	filter := bson.M{
+		"category": cfg.Category,
+		"type":     models.MultipleChoice,
+		"_id":      bson.M{"$nin": used},
+	}
+	if cfg.SubCategory != "" {
+		filter["sub_category"] = cfg.SubCategory
+	}
+
+	pool, err := s.qbRepo.Find(ctx, repositories.SampleableQuestionFilter(filter), options.Find().SetProjection(bson.M{"difficulty": 1, "calibration": 1}))
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(pool) == 0 {
+		return nil, 0, nil
+	}
+
+	sort.Slice(pool, func(i, j int) bool {
+		return math.Abs(entryDifficultyLogit(pool[i])-state.Theta) < math.Abs(entryDifficultyLogit(pool[j])-state.Theta)
+	})
+	chosen := pool[rand.Intn(min(adaptiveExposureTopK, len(pool)))]
+
+	entries, err := s.qbRepo.Find(ctx, bson.M{"_id": chosen.ID}, options.Find().SetLimit(1))
+	if err != nil || len(entries) == 0 {
+		return nil, 0, errors.New("failed to load adaptive item")
+	}
+	return &entries[0], entryDifficultyLogit(chosen), nil
+}
+
+// advanceAdaptive either finishes the attempt or presents the next item, then persists the
+// submission. The version check stops two concurrent requests from presenting two items.
+func (s *submissionService) advanceAdaptive(ctx context.Context, assessment *models.Assessment, submission *models.Submission) (*models.AdaptiveStep, error) {
+	cfg := adaptiveSettings(assessment)
+	state := submission.Adaptive
+	expected := submission.Version
+
+	reason := adaptiveStopReason(cfg, state)
+	var entry *models.QuestionBankEntry
+	if reason == "" {
+		var difficulty float64
+		var err error
+		entry, difficulty, err = s.selectAdaptiveItem(ctx, cfg, state)
+		if err != nil {
+			return nil, err
+		}
+		if entry == nil {
+			reason = models.AdaptiveStopPoolExhausted
+		} else {
+			question := models.Question{
+				ID:            entry.ID,
+				Text:          entry.Text,
+				Type:          entry.Type,
+				PassageTitle:  entry.PassageTitle,
+				PassageText:   entry.PassageText,
+				Options:       entry.Options,
+				CorrectAnswer: entry.CorrectAnswer,
+				Points:        1,
+				AudioURL:      entry.AudioURL,
+				Media:         entry.Media,
+				SourceVersion: entry.Version,
+			}
+			if entry.Template != nil {
+				if err := applyTemplate(&question, *entry, rand.New(rand.NewSource(time.Now().UnixNano()))); err != nil {
+					return nil, fmt.Errorf("question %s: %v", entry.ID.Hex(), err)
+				}
+			}
+
+			now := time.Now()
+			state.Items = append(state.Items, models.AdaptiveItem{QuestionID: entry.ID, Difficulty: difficulty, PresentedAt: now})
+			submission.GeneratedQuestions = append(submission.GeneratedQuestions, question)
+			submission.UpdatedAt = now
+		}
+	}
+
+	if reason != "" {
+		return s.completeAdaptive(ctx, submission, reason)
+	}
+
+	updated, err := s.repo.UpdateIfVersion(ctx, submission, expected)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		_, err := s.rejectedWrite(ctx, submission.ID)
+		return nil, err
+	}
+	return adaptiveStep(cfg, submission), nil
+}
+
+func (s *submissionService) completeAdaptive(ctx context.Context, submission *models.Submission, reason string) (*models.AdaptiveStep, error) {
+	gradeAnswers(submission.GeneratedQuestions, submission.Answers)
+	submission.Score = finishAdaptive(submission.Adaptive, reason)
+	submission.Passed = submission.Score >= submission.MinPassingScore
+	submission.Status = "completed"
+	submission.SubmittedAt = time.Now()
+	submission.UpdatedAt = time.Now()
+
+	if err := s.finalizeSubmission(ctx, submission); err != nil {
+		return nil, err
+	}
+
+	s.auditService.RecordAction(ctx, submission.CandidateID, submission.CandidateEmail, "SUBMIT_ASSESSMENT", "SUBMISSION", submission.ID, "SUCCESS", "Adaptive assessment finished: "+reason, "", nil)
+	s.advanceInvitation(ctx, submission.AssessmentID, submission.CandidateID, models.InvitationStatusCompleted, models.InvitationStatusOpened, models.InvitationStatusStarted)
+
+	return &models.AdaptiveStep{
+		ItemNumber: answeredAdaptiveItems(submission.Adaptive),
+		Finished:   true,
+		StopReason: submission.Adaptive.StopReason,
+		Score:      submission.Score,
+		Passed:     submission.Passed,
+	}, nil
+}
+
+// adaptiveStep describes the pending item without revealing its answer key.
+func adaptiveStep(cfg models.AdaptiveConfig, submission *models.Submission) *models.AdaptiveStep {
+	step := &models.AdaptiveStep{
+		ItemNumber: len(submission.Adaptive.Items),
+		MaxItems:   cfg.MaxItems,
+	}
+	if pending := pendingAdaptiveItem(submission.Adaptive); pending != nil {
+		for _, q := range submission.GeneratedQuestions {
+			if q.ID == pending.QuestionID {
+				question := q
+				question.CorrectAnswer = ""
+				step.Question = &question
+				break
+			}
+		}
+	}
+	return step
+}
+
+// loadAdaptiveAttempt returns the adaptive assessment and the candidate's open attempt,
+// starting the first attempt when there is none.
+func (s *submissionService) loadAdaptiveAttempt(ctx context.Context, aID, cID primitive.ObjectID) (*models.Assessment, *models.Submission, error) {
+	assessment, err := s.assessmentRepo.FindByID(ctx, aID)
+	if err != nil || assessment.DeletedAt != nil {
+		return nil, nil, ErrAssessmentNotFound
+	}
+	if !isAdaptive(assessment) {
+		return nil, nil, ErrNotAdaptive
+	}
+
+	submission, err := s.repo.FindLatestAttempt(ctx, aID, cID)
+	if err != nil {
+		accommodation := resolveAccommodation(ctx, s.accommRepo, aID, cID)
+		if err := checkAssessmentWindow(assessment, accommodation, time.Now()); err != nil {
+			return nil, nil, err
+		}
+		attemptNumber, err := s.repo.NextAttemptNumber(ctx, aID, cID)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		now := time.Now()
+		submission = &models.Submission{
+			AssessmentID:    aID,
+			CandidateID:     cID,
+			AttemptNumber:   attemptNumber,
+			MinPassingScore: assessment.PassingScore,
+			Status:          "in_progress",
+			CreatedBy:       cID,
+			CreatedAt:       now,
+			StartedAt:       now,
+			UpdatedAt:       now,
+			Adaptive:        newAdaptiveState(),
+		}
+		if user, _ := s.userRepo.FindByID(ctx, cID); user != nil {
+			submission.CandidateName = user.Name
+			submission.CandidateEmail = user.Email
+			submission.CandidatePhone = user.Phone
+			submission.IsDemo = user.IsDemo
+		}
+		applyAccommodation(submission, assessment, accommodation)
+
+		if _, err := s.repo.Create(ctx, submission); err != nil {
+			return nil, nil, err
+		}
+		s.advanceInvitation(ctx, aID, cID, models.InvitationStatusStarted, models.InvitationStatusOpened)
+	}
+
+	if submission.Adaptive == nil {
+		// Attempts created by the regular start or retake flow
+		submission.Adaptive = newAdaptiveState()
+	}
+	return assessment, submission, nil
+}
+
+// NextAdaptiveQuestion returns the item currently presented, or presents the next one.
+func (s *submissionService) NextAdaptiveQuestion(ctx context.Context, assessmentID, candidateID string) (*models.AdaptiveStep, error) {
+	aID, _ := primitive.ObjectIDFromHex(assessmentID)
+	cID, _ := primitive.ObjectIDFromHex(candidateID)
+
+	assessment, submission, err := s.loadAdaptiveAttempt(ctx, aID, cID)
+	if err != nil {
+		return nil, err
+	}
+
+	if submission.Status == "completed" {
+		return &models.AdaptiveStep{
+			ItemNumber: answeredAdaptiveItems(submission.Adaptive),
+			Finished:   true,
+			StopReason: submission.Adaptive.StopReason,
+			Score:      submission.Score,
+			Passed:     submission.Passed,
+		}, nil
+	}
+	if deadlinePassed(submission, time.Now()) {
+		return nil, s.autoSubmit(ctx, submission, nil)
+	}
+
+	if pendingAdaptiveItem(submission.Adaptive) != nil {
+		return adaptiveStep(adaptiveSettings(assessment), submission), nil
+	}
+	return s.advanceAdaptive(ctx, assessment, submission)
+}
+
+// AnswerAdaptiveQuestion grades the presented item, updates the ability estimate and
+// moves on to the next item or finishes the attempt.
+func (s *submissionService) AnswerAdaptiveQuestion(ctx context.Context, assessmentID, candidateID, questionID, value string) (*models.AdaptiveStep, error) {
+	aID, _ := primitive.ObjectIDFromHex(assessmentID)
+	cID, _ := primitive.ObjectIDFromHex(candidateID)
+	qID, err := primitive.ObjectIDFromHex(questionID)
+	if err != nil {
+		return nil, ErrInvalidQuestionID
+	}
+
+	assessment, submission, err := s.loadAdaptiveAttempt(ctx, aID, cID)
+	if err != nil {
+		return nil, err
+	}
+	if submission.Status == "completed" {
+		return nil, ErrSubmissionCompleted
+	}
+	if deadlinePassed(submission, time.Now()) {
+		return nil, s.autoSubmit(ctx, submission, nil)
+	}
+
+	pending := pendingAdaptiveItem(submission.Adaptive)
+	if pending == nil || pending.QuestionID != qID {
+		return nil, ErrAdaptiveItemMismatch
+	}
+
+	correctAnswer := ""
+	for _, q := range submission.GeneratedQuestions {
+		if q.ID == qID {
+			correctAnswer = q.CorrectAnswer
+			break
+		}
+	}
+	correct := value != "" && value == correctAnswer
+	now := time.Now()
+	pending.Correct = &correct
+	pending.AnsweredAt = &now
+
+	answer := models.Answer{QuestionID: qID, Value: value, IsCorrect: correct}
+	if correct {
+		answer.Points = 1
+	}
+	submission.Answers = append(submission.Answers, answer)
+	submission.Adaptive.Theta, submission.Adaptive.StandardError = estimateAbility(submission.Adaptive.Items)
+
+	return s.advanceAdaptive(ctx, assessment, submission)
+}
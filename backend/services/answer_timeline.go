@@ -0,0 +1,246 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	"hireit-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// Answers quicker than this are not plausible for any question type.
+	minPlausibleAnswerTime = 3 * time.Second
+	// This many consecutive implausibly fast answers raise a timing flag.
+	fastAnswerRunLength = 5
+	// Caps a single event so a tab left open overnight does not skew the stats.
+	maxEventTimeSpent = time.Hour
+)
+
+// applyAnswerEvents folds client events into the submission's timeline and
+// recomputes its timing flags.
+func applyAnswerEvents(submission *models.Submission, events []models.AnswerEvent) {
+	if len(events) == 0 {
+		return
+	}
+	submission.Timeline = mergeAnswerEvents(submission.Timeline, events, time.Now())
+	submission.TimingFlags = detectFastAnswerRuns(submission.Timeline)
+}
+
+// mergeAnswerEvents applies events in time order. Events at or before a question's last
+// applied event are skipped, so a client re-sending a batch after a failed save is harmless.
+func mergeAnswerEvents(timeline []models.QuestionTiming, events []models.AnswerEvent, now time.Time) []models.QuestionTiming {
+	index := make(map[primitive.ObjectID]int, len(timeline))
+	for i, timing := range timeline {
+		index[timing.QuestionID] = i
+	}
+
+	sorted := append([]models.AnswerEvent{}, events...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].At.Before(sorted[j].At)
+	})
+
+	for _, event := range sorted {
+		if event.QuestionID.IsZero() {
+			continue
+		}
+		at := event.At
+		if at.IsZero() {
+			at = now
+		}
+
+		i, ok := index[event.QuestionID]
+		if !ok {
+			timeline = append(timeline, models.QuestionTiming{QuestionID: event.QuestionID})
+			i = len(timeline) - 1
+			index[event.QuestionID] = i
+		}
+		timing := &timeline[i]
+		if !at.After(timing.LastEventAt) {
+			continue
+		}
+		timing.LastEventAt = at
+
+		spent := event.TimeSpentMs
+		if spent < 0 {
+			spent = 0
+		}
+		timing.TimeSpentMs += min(spent, maxEventTimeSpent.Milliseconds())
+
+		switch event.Type {
+		case models.AnswerEventSeen:
+			timing.Visits++
+			if timing.FirstSeenAt == nil {
+				timing.FirstSeenAt = &at
+			}
+		case models.AnswerEventAnswered, models.AnswerEventChanged:
+			if timing.FirstAnsweredAt == nil {
+				timing.FirstAnsweredAt = &at
+			} else {
+				timing.Changes++
+			}
+			timing.LastAnsweredAt = &at
+		}
+	}
+
+	return timeline
+}
+
+// answerTimeMs is the time the candidate spent before answering, or -1 when unknown.
+// Clients that do not report time spent fall back to first seen -> first answered.
+func answerTimeMs(timing models.QuestionTiming) int64 {
+	if timing.TimeSpentMs > 0 {
+		return timing.TimeSpentMs
+	}
+	if timing.FirstSeenAt != nil && timing.FirstAnsweredAt != nil {
+		return timing.FirstAnsweredAt.Sub(*timing.FirstSeenAt).Milliseconds()
+	}
+	return -1
+}
+
+// detectFastAnswerRuns flags runs of consecutively answered questions that were each
+// answered faster than minPlausibleAnswerTime.
+func detectFastAnswerRuns(timeline []models.QuestionTiming) []models.TimingFlag {
+	answered := make([]models.QuestionTiming, 0, len(timeline))
+	for _, timing := range timeline {
+		if timing.FirstAnsweredAt != nil {
+			answered = append(answered, timing)
+		}
+	}
+	sort.SliceStable(answered, func(i, j int) bool {
+		return answered[i].FirstAnsweredAt.Before(*answered[j].FirstAnsweredAt)
+	})
+
+	var flags []models.TimingFlag
+	var run []models.QuestionTiming
+	closeRun := func() {
+		if len(run) >= fastAnswerRunLength {
+			flag := models.TimingFlag{
+				Type:      models.TimingFlagFastAnswerRun,
+				StartedAt: *run[0].FirstAnsweredAt,
+				EndedAt:   *run[len(run)-1].FirstAnsweredAt,
+			}
+			var total int64
+			for _, timing := range run {
+				flag.QuestionIDs = append(flag.QuestionIDs, timing.QuestionID)
+				total += answerTimeMs(timing)
+			}
+			flag.AvgTimeMs = total / int64(len(run))
+			flags = append(flags, flag)
+		}
+		run = nil
+	}
+
+	for _, timing := range answered {
+		spent := answerTimeMs(timing)
+		if spent >= 0 && spent < minPlausibleAnswerTime.Milliseconds() {
+			run = append(run, timing)
+			continue
+		}
+		closeRun()
+	}
+	closeRun()
+
+	return flags
+}
+
+// GetQuestionTimeStats aggregates the answer timelines of completed submissions per question.
+func (s *submissionService) GetQuestionTimeStats(ctx context.Context, assessmentID string) (*models.AssessmentTimeStats, error) {
+	aID, err := primitive.ObjectIDFromHex(assessmentID)
+	if err != nil {
+		return nil, errors.New("invalid assessment ID")
+	}
+
+	opts := options.Find().SetProjection(bson.M{
+		"timeline":                 1,
+		"timing_flags":             1,
+		"generated_questions._id":  1,
+		"generated_questions.text": 1,
+	})
+	subs, err := s.repo.FindAll(ctx, bson.M{"assessment_id": aID, "status": "completed", "deleted_at": nil}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	type accumulator struct {
+		stats   models.QuestionTimeStats
+		times   []int64
+		changes int
+		changed int
+	}
+	byQuestion := make(map[primitive.ObjectID]*accumulator)
+	order := []primitive.ObjectID{}
+	result := &models.AssessmentTimeStats{Submissions: len(subs), Questions: []models.QuestionTimeStats{}}
+
+	for _, sub := range subs {
+		if len(sub.TimingFlags) > 0 {
+			result.FlaggedSubmissions++
+		}
+		texts := make(map[primitive.ObjectID]string, len(sub.GeneratedQuestions))
+		for _, q := range sub.GeneratedQuestions {
+			texts[q.ID] = q.Text
+		}
+
+		for _, timing := range sub.Timeline {
+			spent := answerTimeMs(timing)
+			if spent < 0 {
+				continue
+			}
+			acc, ok := byQuestion[timing.QuestionID]
+			if !ok {
+				acc = &accumulator{stats: models.QuestionTimeStats{QuestionID: timing.QuestionID}}
+				byQuestion[timing.QuestionID] = acc
+				order = append(order, timing.QuestionID)
+			}
+			if acc.stats.QuestionText == "" {
+				acc.stats.QuestionText = texts[timing.QuestionID]
+			}
+			acc.times = append(acc.times, spent)
+			acc.changes += timing.Changes
+			if timing.Changes > 0 {
+				acc.changed++
+			}
+			if spent < minPlausibleAnswerTime.Milliseconds() {
+				acc.stats.FastAnswers++
+			}
+		}
+	}
+
+	for _, qID := range order {
+		acc := byQuestion[qID]
+		n := len(acc.times)
+		sort.Slice(acc.times, func(i, j int) bool { return acc.times[i] < acc.times[j] })
+
+		var total int64
+		for _, t := range acc.times {
+			total += t
+		}
+		acc.stats.Responses = n
+		acc.stats.MeanTimeMs = total / int64(n)
+		acc.stats.MedianTimeMs = percentileMs(acc.times, 0.5)
+		acc.stats.P90TimeMs = percentileMs(acc.times, 0.9)
+		acc.stats.MeanChanges = float64(acc.changes) / float64(n)
+		acc.stats.ChangedRate = float64(acc.changed) / float64(n)
+		result.Questions = append(result.Questions, acc.stats)
+	}
+
+	return result, nil
+}
+
+// percentileMs uses the nearest-rank method on an ascending slice.
+func percentileMs(sorted []int64, p float64) int64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank]
+}
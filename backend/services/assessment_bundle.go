@@ -0,0 +1,829 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"hireit-backend/models"
+	"hireit-backend/repositories"
+	"hireit-backend/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	AssessmentBundleFormat  = "hireit-assessment-bundle"
+	AssessmentBundleVersion = 1
+
+	// Conflict handling on import: a taken title is renamed or fails the import, and a
+	// question already in the bank is reused or imported again as a copy.
+	BundleConflictRename = "rename"
+	BundleConflictFail   = "fail"
+	BundleConflictReuse  = "reuse"
+	BundleConflictCopy   = "copy"
+
+	bundleManifestFile   = "manifest.json"
+	bundleAssessmentFile = "assessment.json"
+	bundleStructureFile  = "bank_structure.json"
+	bundleQuestionsFile  = "questions.json"
+	bundleAudioDir       = "audio/"
+	bundleMediaDir       = "media/" // Files named after their media ID
+	audioURLPrefix       = "/audio/"
+
+	// Limits that keep a crafted bundle from exhausting memory
+	maxBundleManifestSize  = 1 << 20
+	maxBundleExtractedSize = 500 << 20
+	maxBundleTitleAttempts = 100
+)
+
+var (
+	ErrInvalidBundle            = errors.New("invalid assessment bundle")
+	ErrBundleChecksum           = errors.New("bundle file does not match its checksum")
+	ErrBundleTitleConflict      = errors.New("an assessment with this title already exists")
+	ErrBundleAssessmentNotFound = errors.New("assessment not found")
+	ErrBundleAudioMissing       = errors.New("audio file referenced by the assessment is missing")
+	ErrBundleMediaMissing       = errors.New("media attached to a question is missing")
+	bundleAudioExtensions       = map[string]bool{".mp3": true, ".wav": true, ".ogg": true, ".m4a": true, ".aac": true}
+)
+
+// BundleImportOptions choose how an import resolves conflicts with the target server. Empty
+// values mean rename and reuse. Approve stores the created entries as approved instead of
+// sending them to the review queue, for banks whose entries were reviewed where they were
+// exported.
+type BundleImportOptions struct {
+	TitleConflict    string
+	QuestionConflict string
+	Approve          bool
+}
+
+// AssessmentBundleService moves an assessment between servers, e.g. from staging to
+// production, as a zip archive holding the assessment, the bank structure its rules use,
+// the bank entries they sample from and the uploaded audio and media they reference.
+type AssessmentBundleService interface {
+	ExportBundle(ctx context.Context, id, ownerID primitive.ObjectID) ([]byte, *models.BundleManifest, error)
+	ImportBundle(ctx context.Context, data []byte, opts BundleImportOptions, importerID primitive.ObjectID) (*models.BundleImportReport, error)
+}
+
+type assessmentBundleService struct {
+	assessmentService AssessmentService
+	assessmentRepo    repositories.AssessmentRepository
+	qbRepo            repositories.QuestionBankRepository
+	mediaService      MediaService
+	audioDir          string
+}
+
+func NewAssessmentBundleService(assessmentService AssessmentService, assessmentRepo repositories.AssessmentRepository, qbRepo repositories.QuestionBankRepository, mediaService MediaService, audioDir string) AssessmentBundleService {
+	return &assessmentBundleService{
+		assessmentService: assessmentService,
+		assessmentRepo:    assessmentRepo,
+		qbRepo:            qbRepo,
+		mediaService:      mediaService,
+		audioDir:          audioDir,
+	}
+}
+
+// ExportBundle builds the bundle of an assessment. Only the authored fields of the sampleable
+// entries in its slots are included; item statistics and calibration are specific to the
+// responses collected on this server. A non-zero ownerID restricts the export to the
+// assessments created by that user; others are reported as not found.
+func (s *assessmentBundleService) ExportBundle(ctx context.Context, id, ownerID primitive.ObjectID) ([]byte, *models.BundleManifest, error) {
+	assessment, err := s.assessmentRepo.FindByID(ctx, id)
+	if errors.Is(err, mongo.ErrNoDocuments) || (err == nil && (assessment.DeletedAt != nil || (!ownerID.IsZero() && assessment.CreatedBy != ownerID))) {
+		return nil, nil, ErrBundleAssessmentNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	assessment.CreatedBy = primitive.NilObjectID
+
+	slots, categories := assessmentSlots(assessment)
+	entries := []models.QuestionBankEntry{}
+	if len(slots) > 0 {
+		entries, err = s.qbRepo.Find(ctx, repositories.SampleableQuestionFilter(bson.M{"$or": slots}),
+			options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+		if err != nil {
+			return nil, nil, err
+		}
+		for i := range entries {
+			entries[i] = repositories.QuestionContent(entries[i])
+		}
+	}
+
+	config, err := s.qbRepo.GetBankConfig(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	structure := models.QuestionBankConfig{Categories: []models.CategoryConfig{}}
+	if config != nil {
+		structure.Skills = config.Skills
+		for _, category := range config.Categories {
+			if categories[category.Name] {
+				structure.Categories = append(structure.Categories, category)
+			}
+		}
+	}
+
+	audio := map[string][]byte{}
+	var audioNames []string
+	var audioErr error
+	bundleAudioURLs(assessment, &structure, entries, func(url *string) {
+		name := localAudioName(*url)
+		if name == "" || audioErr != nil {
+			return
+		}
+		if _, ok := audio[name]; ok {
+			return
+		}
+		content, err := os.ReadFile(filepath.Join(s.audioDir, name))
+		if os.IsNotExist(err) {
+			audioErr = fmt.Errorf("%w: %s", ErrBundleAudioMissing, *url)
+			return
+		}
+		if err != nil {
+			audioErr = err
+			return
+		}
+		audio[name] = content
+		audioNames = append(audioNames, name)
+	})
+	if audioErr != nil {
+		return nil, nil, audioErr
+	}
+
+	media := map[string][]byte{}
+	var mediaNames []string
+	for i := range entries {
+		for _, attachment := range entries[i].Media {
+			stored, content, err := s.mediaService.ReadFile(ctx, attachment.MediaID)
+			if errors.Is(err, ErrMediaNotFound) {
+				return nil, nil, fmt.Errorf("%w: %s", ErrBundleMediaMissing, attachment.MediaID.Hex())
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+			name := attachment.MediaID.Hex() + filepath.Ext(stored.FileName)
+			if _, ok := media[name]; !ok {
+				media[name] = content
+				mediaNames = append(mediaNames, name)
+			}
+		}
+	}
+
+	type bundleContent struct {
+		path string
+		data []byte
+	}
+	var contents []bundleContent
+	for _, doc := range []struct {
+		path  string
+		value any
+	}{
+		{bundleAssessmentFile, assessment},
+		{bundleStructureFile, structure},
+		{bundleQuestionsFile, entries},
+	} {
+		data, err := json.MarshalIndent(doc.value, "", "  ")
+		if err != nil {
+			return nil, nil, err
+		}
+		contents = append(contents, bundleContent{doc.path, data})
+	}
+	for _, name := range audioNames {
+		contents = append(contents, bundleContent{bundleAudioDir + name, audio[name]})
+	}
+	for _, name := range mediaNames {
+		contents = append(contents, bundleContent{bundleMediaDir + name, media[name]})
+	}
+
+	manifest := &models.BundleManifest{
+		Format:             AssessmentBundleFormat,
+		Version:            AssessmentBundleVersion,
+		ExportedAt:         time.Now().UTC(),
+		SourceAssessmentID: assessment.ID,
+		Title:              assessment.Title,
+		QuestionCount:      len(entries),
+		AudioCount:         len(audioNames),
+		MediaCount:         len(mediaNames),
+		Files:              make([]models.BundleFile, 0, len(contents)),
+	}
+	for _, content := range contents {
+		manifest.Files = append(manifest.Files, models.BundleFile{Path: content.path, Size: int64(len(content.data)), SHA256: sha256Hex(content.data)})
+	}
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	write := func(name string, data []byte, method uint16) error {
+		w, err := archive.CreateHeader(&zip.FileHeader{Name: name, Method: method, Modified: manifest.ExportedAt})
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	}
+	if err := write(bundleManifestFile, manifestData, zip.Deflate); err != nil {
+		return nil, nil, err
+	}
+	for _, content := range contents {
+		// Audio and images are already compressed
+		method := zip.Deflate
+		if strings.HasPrefix(content.path, bundleAudioDir) || strings.HasPrefix(content.path, bundleMediaDir) {
+			method = zip.Store
+		}
+		if err := write(content.path, content.data, method); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return nil, nil, err
+	}
+	return buf.Bytes(), manifest, nil
+}
+
+// ImportBundle recreates a bundled assessment on this server. Every file is checked against
+// the manifest before anything is written. Questions get new IDs unless an identical
+// sampleable entry of the same slot can be reused, audio files get new names unless the
+// same file is already uploaded, media gets new IDs unless the same file is already
+// stored, and missing parts of the bank structure are added. Imported entries wait in the
+// review queue unless opts.Approve is set. The bank structure, entries and assessment are
+// written in one transaction; a failed import removes the audio files it wrote. Stored
+// media is kept, as it is shared by content with later uploads.
+func (s *assessmentBundleService) ImportBundle(ctx context.Context, data []byte, opts BundleImportOptions, importerID primitive.ObjectID) (_ *models.BundleImportReport, err error) {
+	manifest, files, err := readBundle(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var assessment models.Assessment
+	var structure models.QuestionBankConfig
+	var entries []models.QuestionBankEntry
+	for _, doc := range []struct {
+		path   string
+		target any
+	}{
+		{bundleAssessmentFile, &assessment},
+		{bundleStructureFile, &structure},
+		{bundleQuestionsFile, &entries},
+	} {
+		if err := json.Unmarshal(files[doc.path], doc.target); err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidBundle, doc.path, err)
+		}
+	}
+	if strings.TrimSpace(assessment.Title) == "" {
+		return nil, fmt.Errorf("%w: the assessment has no title", ErrInvalidBundle)
+	}
+	if err := validateAssessmentMode(&assessment); err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		entry := &entries[i]
+		if entry.ID.IsZero() {
+			return nil, fmt.Errorf("%w: question %d has no ID", ErrInvalidBundle, i+1)
+		}
+		// Templated entries are checked when their values are drawn
+		if entry.Template != nil {
+			continue
+		}
+		if issues, _ := ValidateImportEntry(entry, nil); len(issues) > 0 {
+			return nil, fmt.Errorf("%w: question %s: %s", ErrInvalidBundle, entry.ID.Hex(), issues[0].Message)
+		}
+	}
+	var missingAudio error
+	bundleAudioURLs(&assessment, &structure, entries, func(url *string) {
+		if name := localAudioName(*url); name != "" && missingAudio == nil {
+			if _, ok := files[bundleAudioDir+name]; !ok {
+				missingAudio = fmt.Errorf("%w: %s is referenced but not bundled", ErrInvalidBundle, *url)
+			}
+		}
+	})
+	if missingAudio != nil {
+		return nil, missingAudio
+	}
+	bundledMedia := map[primitive.ObjectID]string{}
+	for name := range files {
+		if mediaName, ok := strings.CutPrefix(name, bundleMediaDir); ok {
+			bundledMedia[bundleMediaID(mediaName)] = name
+		}
+	}
+	for i := range entries {
+		for _, attachment := range entries[i].Media {
+			if _, ok := bundledMedia[attachment.MediaID]; !ok {
+				return nil, fmt.Errorf("%w: media %s is attached but not bundled", ErrInvalidBundle, attachment.MediaID.Hex())
+			}
+		}
+	}
+
+	title, renamed, err := s.availableTitle(ctx, utils.SanitizeStrict(assessment.Title), opts.TitleConflict)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.BundleImportReport{
+		Title:          title,
+		Renamed:        renamed,
+		StructureAdded: []string{},
+		IDMap:          map[string]string{},
+		AudioMap:       map[string]string{},
+		MediaMap:       map[string]string{},
+	}
+
+	// Media goes through the same checks as an upload, so it is stored first: a file that
+	// fails them leaves no audio behind
+	for sourceID, name := range bundledMedia {
+		media, created, err := s.mediaService.Store(ctx, name, files[name], importerID)
+		if errors.Is(err, ErrUnsupportedMedia) || errors.Is(err, ErrMediaTooLarge) {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidBundle, name, err)
+		}
+		if err != nil {
+			return nil, err
+		}
+		report.MediaMap[sourceID.Hex()] = media.ID.Hex()
+		if created {
+			report.MediaFilesWritten++
+		} else {
+			report.MediaFilesReused++
+		}
+	}
+	for i := range entries {
+		entry := &entries[i]
+		for j := range entry.Media {
+			entry.Media[j].MediaID, _ = primitive.ObjectIDFromHex(report.MediaMap[entry.Media[j].MediaID.Hex()])
+		}
+		if err := s.mediaService.ResolveAttachments(ctx, entry); err != nil {
+			if errors.Is(err, ErrInvalidAttachment) {
+				return nil, fmt.Errorf("%w: question %s: %v", ErrInvalidBundle, entry.ID.Hex(), err)
+			}
+			return nil, err
+		}
+	}
+
+	writtenAudio, err := s.importAudio(files, report)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			for _, name := range writtenAudio {
+				os.Remove(name)
+			}
+		}
+	}()
+	bundleAudioURLs(&assessment, &structure, entries, func(url *string) {
+		if mapped, ok := report.AudioMap[*url]; ok {
+			*url = mapped
+		}
+	})
+
+	reusable := map[string]primitive.ObjectID{}
+	if opts.QuestionConflict != BundleConflictCopy && len(entries) > 0 {
+		hashes := make(bson.A, len(entries))
+		for i := range entries {
+			hashes[i] = repositories.QuestionContentHash(&entries[i])
+		}
+		existing, err := s.qbRepo.Find(ctx, repositories.SampleableQuestionFilter(bson.M{"content_hash": bson.M{"$in": hashes}}),
+			options.Find().
+				SetProjection(bson.M{"category": 1, "sub_category": 1, "difficulty": 1, "type": 1, "correct_answer": 1, "content_hash": 1, "media": 1}).
+				SetSort(bson.D{{Key: "_id", Value: 1}}))
+		if err != nil {
+			return nil, err
+		}
+		for i := range existing {
+			key := bundleReuseKey(&existing[i], existing[i].ContentHash)
+			if _, ok := reusable[key]; !ok {
+				reusable[key] = existing[i].ID
+			}
+		}
+	}
+
+	created := make([]*models.QuestionBankEntry, 0, len(entries))
+	for i := range entries {
+		entry := &entries[i]
+		sourceID := entry.ID.Hex()
+		if id, ok := reusable[bundleReuseKey(entry, repositories.QuestionContentHash(entry))]; ok {
+			report.IDMap[sourceID] = id.Hex()
+			report.QuestionsReused++
+			continue
+		}
+		entry.ID = primitive.NewObjectID()
+		entry.ReviewStatus = ""
+		if opts.Approve {
+			entry.ReviewStatus = models.ReviewStatusApproved
+		}
+		entry.UpdatedBy = importerID
+		report.IDMap[sourceID] = entry.ID.Hex()
+		created = append(created, entry)
+	}
+
+	sourceID := assessment.ID.Hex()
+	assessment.ID = primitive.NilObjectID
+	assessment.Title = title
+	assessment.CreatedBy = importerID
+	assessment.DeletedAt = nil
+
+	var newID string
+	write := func(ctx context.Context) error {
+		config, err := s.qbRepo.GetBankConfig(ctx)
+		if err != nil {
+			return err
+		}
+		if config == nil {
+			config = &models.QuestionBankConfig{}
+		}
+		var changed bool
+		if report.StructureAdded, changed = mergeBankStructure(config, &structure); changed {
+			if err := s.qbRepo.SaveBankConfig(ctx, config); err != nil {
+				return err
+			}
+		}
+		if report.QuestionsCreated, err = s.qbRepo.CreateMany(ctx, created); err != nil {
+			return err
+		}
+		newID, err = s.assessmentService.CreateAssessment(ctx, &assessment)
+		return err
+	}
+	err = s.qbRepo.WithTransaction(ctx, write)
+	if errors.Is(err, repositories.ErrTransactionsUnsupported) {
+		err = s.writeWithoutTransaction(ctx, write, created)
+	}
+	if err != nil {
+		return nil, err
+	}
+	report.AssessmentID, _ = primitive.ObjectIDFromHex(newID)
+	report.IDMap[sourceID] = newID
+
+	fmt.Printf("[Assessment Bundle] Imported %q (exported %s) as %s: %d questions created, %d reused\n",
+		title, manifest.ExportedAt.Format(time.RFC3339), newID, report.QuestionsCreated, report.QuestionsReused)
+	return report, nil
+}
+
+// writeWithoutTransaction runs the writes of an import on a standalone server, which has
+// no transactions. On failure the created entries are deleted and the bank structure is
+// put back as it was.
+func (s *assessmentBundleService) writeWithoutTransaction(ctx context.Context, write func(ctx context.Context) error, created []*models.QuestionBankEntry) error {
+	previous, err := s.qbRepo.GetBankConfig(ctx)
+	if err != nil {
+		return err
+	}
+	if err = write(ctx); err == nil {
+		return nil
+	}
+
+	ids := make([]primitive.ObjectID, len(created))
+	for i, entry := range created {
+		ids[i] = entry.ID
+	}
+	if deleteErr := s.qbRepo.DeleteMany(ctx, ids); deleteErr != nil {
+		fmt.Printf("[Assessment Bundle] Failed to remove the entries of a failed import: %v\n", deleteErr)
+	}
+	if previous == nil {
+		previous = &models.QuestionBankConfig{}
+	}
+	if saveErr := s.qbRepo.SaveBankConfig(ctx, previous); saveErr != nil {
+		fmt.Printf("[Assessment Bundle] Failed to restore the bank structure after a failed import: %v\n", saveErr)
+	}
+	return err
+}
+
+// availableTitle returns title, or with the rename policy the first free "title (n)".
+func (s *assessmentBundleService) availableTitle(ctx context.Context, title, onConflict string) (string, bool, error) {
+	for n := 1; n <= maxBundleTitleAttempts; n++ {
+		candidate := title
+		if n > 1 {
+			candidate = fmt.Sprintf("%s (%d)", title, n)
+		}
+		existing, err := s.assessmentRepo.FindAll(ctx, bson.M{"title": candidate, "deleted_at": nil},
+			options.Find().SetLimit(1).SetProjection(bson.M{"_id": 1}))
+		if err != nil {
+			return "", false, err
+		}
+		if len(existing) == 0 {
+			return candidate, n > 1, nil
+		}
+		if onConflict == BundleConflictFail {
+			break
+		}
+	}
+	return "", false, ErrBundleTitleConflict
+}
+
+// importAudio stores the bundled audio files under new names and records their URLs in
+// report.AudioMap. A file already uploaded under the same name with the same content is
+// reused. It returns the paths of the files it wrote; on failure, these are removed.
+func (s *assessmentBundleService) importAudio(files map[string][]byte, report *models.BundleImportReport) (written []string, err error) {
+	defer func() {
+		if err != nil {
+			for _, name := range written {
+				os.Remove(name)
+			}
+			written = nil
+		}
+	}()
+
+	if err := os.MkdirAll(s.audioDir, 0755); err != nil {
+		return nil, err
+	}
+	for name, content := range files {
+		audioName, ok := strings.CutPrefix(name, bundleAudioDir)
+		if !ok {
+			continue
+		}
+		sourceURL := audioURLPrefix + audioName
+
+		if existing, err := os.ReadFile(filepath.Join(s.audioDir, audioName)); err == nil && bytes.Equal(existing, content) {
+			report.AudioMap[sourceURL] = sourceURL
+			report.AudioFilesReused++
+			continue
+		}
+
+		ext := strings.ToLower(filepath.Ext(audioName))
+		for {
+			target := filepath.Join(s.audioDir, fmt.Sprintf("%d%s", time.Now().UnixNano(), ext))
+			f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+			if os.IsExist(err) {
+				continue
+			}
+			if err != nil {
+				return written, err
+			}
+			written = append(written, target)
+			_, err = f.Write(content)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return written, err
+			}
+			report.AudioMap[sourceURL] = audioURLPrefix + filepath.Base(target)
+			report.AudioFilesWritten++
+			break
+		}
+	}
+	return written, nil
+}
+
+// readBundle reads the manifest and every file it lists, verifying sizes and checksums.
+// Files missing from the archive or present but not listed make the bundle invalid.
+func readBundle(data []byte) (*models.BundleManifest, map[string][]byte, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+	}
+	archived := map[string]*zip.File{}
+	for _, f := range archive.File {
+		if !f.FileInfo().IsDir() {
+			archived[f.Name] = f
+		}
+	}
+
+	manifestFile, ok := archived[bundleManifestFile]
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: %s is missing", ErrInvalidBundle, bundleManifestFile)
+	}
+	raw, err := readBundleFile(manifestFile, maxBundleManifestSize)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+	}
+	var manifest models.BundleManifest
+	if err := json.Unmarshal(raw, &manifest); err != nil {
+		return nil, nil, fmt.Errorf("%w: %s: %v", ErrInvalidBundle, bundleManifestFile, err)
+	}
+	if manifest.Format != AssessmentBundleFormat {
+		return nil, nil, fmt.Errorf("%w: not an assessment bundle", ErrInvalidBundle)
+	}
+	if manifest.Version < 1 || manifest.Version > AssessmentBundleVersion {
+		return nil, nil, fmt.Errorf("%w: unsupported bundle version %d", ErrInvalidBundle, manifest.Version)
+	}
+
+	files := make(map[string][]byte, len(manifest.Files))
+	var total int64
+	for _, listed := range manifest.Files {
+		if listed.Path == bundleManifestFile || path.Clean(listed.Path) != listed.Path || strings.HasPrefix(listed.Path, "/") || strings.HasPrefix(listed.Path, "..") {
+			return nil, nil, fmt.Errorf("%w: invalid path %q", ErrInvalidBundle, listed.Path)
+		}
+		if _, dup := files[listed.Path]; dup {
+			return nil, nil, fmt.Errorf("%w: %s is listed twice", ErrInvalidBundle, listed.Path)
+		}
+		if name, ok := strings.CutPrefix(listed.Path, bundleAudioDir); ok && (localAudioName(audioURLPrefix+name) == "" || !bundleAudioExtensions[strings.ToLower(path.Ext(name))]) {
+			return nil, nil, fmt.Errorf("%w: %s is not a supported audio file", ErrInvalidBundle, listed.Path)
+		}
+		if name, ok := strings.CutPrefix(listed.Path, bundleMediaDir); ok && bundleMediaID(name).IsZero() {
+			return nil, nil, fmt.Errorf("%w: %s is not a supported media file", ErrInvalidBundle, listed.Path)
+		}
+		f, ok := archived[listed.Path]
+		if !ok {
+			return nil, nil, fmt.Errorf("%w: %s is listed in the manifest but missing", ErrInvalidBundle, listed.Path)
+		}
+		if total += listed.Size; listed.Size < 0 || total > maxBundleExtractedSize {
+			return nil, nil, fmt.Errorf("%w: bundle expands to more than %d MB", ErrInvalidBundle, maxBundleExtractedSize>>20)
+		}
+		content, err := readBundleFile(f, listed.Size)
+		if err != nil || int64(len(content)) != listed.Size || !strings.EqualFold(sha256Hex(content), listed.SHA256) {
+			return nil, nil, fmt.Errorf("%w: %s", ErrBundleChecksum, listed.Path)
+		}
+		files[listed.Path] = content
+	}
+	for _, f := range archive.File {
+		if _, ok := files[f.Name]; !ok && f.Name != bundleManifestFile && !f.FileInfo().IsDir() {
+			return nil, nil, fmt.Errorf("%w: %s is not listed in the manifest", ErrInvalidBundle, f.Name)
+		}
+	}
+	for _, required := range []string{bundleAssessmentFile, bundleStructureFile, bundleQuestionsFile} {
+		if _, ok := files[required]; !ok {
+			return nil, nil, fmt.Errorf("%w: %s is missing", ErrInvalidBundle, required)
+		}
+	}
+	return &manifest, files, nil
+}
+
+func readBundleFile(f *zip.File, limit int64) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	content, err := io.ReadAll(io.LimitReader(rc, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(content)) > limit {
+		return nil, fmt.Errorf("%s is larger than expected", f.Name)
+	}
+	return content, nil
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// assessmentSlots returns the bank filters an assessment samples from and the categories
+// they use.
+func assessmentSlots(assessment *models.Assessment) (bson.A, map[string]bool) {
+	slots := bson.A{}
+	categories := map[string]bool{}
+	if isAdaptive(assessment) {
+		cfg := adaptiveSettings(assessment)
+		slot := bson.M{"category": cfg.Category, "type": models.MultipleChoice}
+		if cfg.SubCategory != "" {
+			slot["sub_category"] = cfg.SubCategory
+		}
+		return append(slots, slot), map[string]bool{cfg.Category: true}
+	}
+	for _, rule := range assessment.QuestionRules {
+		slots = append(slots, ruleSlotFilter(rule))
+		categories[rule.Category] = true
+	}
+	return slots, categories
+}
+
+// bundleAudioURLs calls fn with every audio URL field of the bundled documents, set or not.
+func bundleAudioURLs(assessment *models.Assessment, structure *models.QuestionBankConfig, entries []models.QuestionBankEntry, fn func(url *string)) {
+	for i := range assessment.QuestionRules {
+		fn(&assessment.QuestionRules[i].AudioURL)
+	}
+	for i := range structure.Categories {
+		category := &structure.Categories[i]
+		fn(&category.AudioURL)
+		for j := range category.Difficulties {
+			fn(&category.Difficulties[j].AudioURL)
+		}
+		for j := range category.SubCategories {
+			sub := &category.SubCategories[j]
+			fn(&sub.AudioURL)
+			for k := range sub.Difficulties {
+				fn(&sub.Difficulties[k].AudioURL)
+			}
+		}
+	}
+	for i := range entries {
+		fn(&entries[i].AudioURL)
+	}
+}
+
+// localAudioName returns the file name of an uploaded audio URL, or "" for an external URL.
+func localAudioName(url string) string {
+	name, ok := strings.CutPrefix(url, audioURLPrefix)
+	if !ok || name == "" || name != path.Base(name) || strings.HasPrefix(name, ".") {
+		return ""
+	}
+	return name
+}
+
+// bundleReuseKey identifies the entries a bundled question may be replaced with: same
+// content, slot, type, answer and attached media. Media is stored once per file, so the
+// same image has the same ID on either side once imported.
+func bundleReuseKey(entry *models.QuestionBankEntry, contentHash string) string {
+	parts := []string{contentHash, entry.Category, entry.SubCategory, entry.Difficulty, string(entry.Type), entry.CorrectAnswer}
+	for _, attachment := range entry.Media {
+		option := ""
+		if attachment.Option != nil {
+			option = strconv.Itoa(*attachment.Option)
+		}
+		parts = append(parts, attachment.MediaID.Hex()+"@"+option)
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// bundleMediaID returns the media ID a bundled media file is named after, or the zero ID
+// if the name is not an ID with a supported extension.
+func bundleMediaID(name string) primitive.ObjectID {
+	ext := strings.ToLower(path.Ext(name))
+	id, err := primitive.ObjectIDFromHex(strings.TrimSuffix(name, ext))
+	if err != nil {
+		return primitive.NilObjectID
+	}
+	for _, accepted := range mediaTypes {
+		for _, allowed := range accepted.extensions {
+			if ext == allowed {
+				return id
+			}
+		}
+	}
+	return primitive.NilObjectID
+}
+
+// mergeBankStructure adds the categories, sub-categories, difficulties and skills of from
+// that into lacks and describes each addition. Existing settings are kept, except that an
+// unset audio URL is taken from from. changed reports whether into was modified at all.
+func mergeBankStructure(into, from *models.QuestionBankConfig) (added []string, changed bool) {
+	added = []string{}
+	fillAudio := func(into *string, from string) {
+		if *into == "" && from != "" {
+			*into = from
+			changed = true
+		}
+	}
+	mergeDifficulties := func(into *[]models.DifficultyConfig, from []models.DifficultyConfig, label string) {
+		for _, d := range from {
+			found := false
+			for i := range *into {
+				if (*into)[i].Difficulty == d.Difficulty {
+					fillAudio(&(*into)[i].AudioURL, d.AudioURL)
+					found = true
+					break
+				}
+			}
+			if !found {
+				*into = append(*into, d)
+				added = append(added, label+" / "+d.Difficulty)
+			}
+		}
+	}
+
+	for _, category := range from.Categories {
+		var target *models.CategoryConfig
+		for i := range into.Categories {
+			if into.Categories[i].Name == category.Name {
+				target = &into.Categories[i]
+				break
+			}
+		}
+		if target == nil {
+			into.Categories = append(into.Categories, category)
+			added = append(added, category.Name)
+			continue
+		}
+
+		fillAudio(&target.AudioURL, category.AudioURL)
+		mergeDifficulties(&target.Difficulties, category.Difficulties, category.Name)
+		for _, sub := range category.SubCategories {
+			var targetSub *models.SubCategoryConfig
+			for i := range target.SubCategories {
+				if target.SubCategories[i].Name == sub.Name {
+					targetSub = &target.SubCategories[i]
+					break
+				}
+			}
+			if targetSub == nil {
+				target.SubCategories = append(target.SubCategories, sub)
+				added = append(added, category.Name+" / "+sub.Name)
+				continue
+			}
+			fillAudio(&targetSub.AudioURL, sub.AudioURL)
+			mergeDifficulties(&targetSub.Difficulties, sub.Difficulties, category.Name+" / "+sub.Name)
+		}
+	}
+	for _, skill := range mergeSkillTaxonomy(&into.Skills, from.Skills, "") {
+		added = append(added, "skill "+skill)
+	}
+	return added, changed || len(added) > 0
+}
@@ -34,6 +34,9 @@ func (s *assessmentService) CreateAssessment(ctx context.Context, assessment *mo
 	// Sanitization
 	assessment.Title = utils.SanitizeStrict(assessment.Title)
 	assessment.Description = utils.SanitizeStrict(assessment.Description)
+	if err := validateAssessmentMode(assessment); err != nil {
+		return "", err
+	}
 
 	assessment.CreatedAt = time.Now()
 	assessment.UpdatedAt = time.Now()
@@ -89,6 +92,9 @@ func (s *assessmentService) UpdateAssessment(ctx context.Context, idStr string,
 	if err != nil || existing.DeletedAt != nil {
 		return errors.New("assessment not found or deleted")
 	}
+	if err := validateAssessmentMode(assessment); err != nil {
+		return err
+	}
 
 	assessment.UpdatedAt = time.Now()
 	return s.repo.Update(ctx, id, assessment)
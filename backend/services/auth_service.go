@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -23,6 +24,7 @@ type AuthService interface {
 	StartPublicAssessment(ctx context.Context, name, email, phone string) (string, *models.User, error)
 	StartDemoAssessment(ctx context.Context) (string, *models.User, error)
 	StartAssessmentWithOTP(ctx context.Context, phone, otp string) (string, *models.User, error)
+	StartInvitedAssessment(ctx context.Context, candidateID primitive.ObjectID) (string, *models.User, error)
 }
 
 type authService struct {
@@ -218,6 +220,21 @@ func (s *authService) StartAssessmentWithOTP(ctx context.Context, phone, otp str
 	return tokenString, user, nil
 }
 
+// StartInvitedAssessment issues a session for a candidate whose invitation link was just consumed
+func (s *authService) StartInvitedAssessment(ctx context.Context, candidateID primitive.ObjectID) (string, *models.User, error) {
+	user, err := s.userRepo.FindByID(ctx, candidateID)
+	if err != nil {
+		return "", nil, errors.New("candidate not found")
+	}
+
+	tokenString, err := s.generateJWT(user)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return tokenString, user, nil
+}
+
 func (s *authService) generateJWT(user *models.User) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"sub":  user.ID.Hex(),
@@ -0,0 +1,43 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"hireit-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrStaleSubmission     = errors.New("submission was modified by another request")
+	ErrSubmissionCompleted = errors.New("submission has already been completed")
+)
+
+// checkExpectedVersion rejects a write up front when the client's version is already behind.
+// A nil expected version means the client did not send one and the stored version is used.
+func checkExpectedVersion(submission *models.Submission, expectedVersion *int64) (int64, error) {
+	if submission.Status == "completed" {
+		return submission.Version, ErrSubmissionCompleted
+	}
+	if expectedVersion == nil {
+		return submission.Version, nil
+	}
+	if *expectedVersion != submission.Version {
+		return submission.Version, ErrStaleSubmission
+	}
+	return *expectedVersion, nil
+}
+
+// rejectedWrite explains why a conditional write matched nothing and returns the
+// version the client should resync to.
+func (s *submissionService) rejectedWrite(ctx context.Context, id primitive.ObjectID) (int64, error) {
+	current, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	if current.Status == "completed" {
+		return current.Version, ErrSubmissionCompleted
+	}
+	return current.Version, ErrStaleSubmission
+}
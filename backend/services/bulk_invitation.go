@@ -0,0 +1,249 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"hireit-backend/models"
+	"hireit-backend/utils"
+	"io"
+	"strings"
+	"time"
+	"unicode"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// Uploads above this many rows are processed by the worker pool instead of inline.
+	bulkInvitationInlineLimit = 50
+	bulkInvitationJobTTL      = 24 * time.Hour
+	bulkInvitationCachePrefix = "bulk_invitation_job:"
+)
+
+var ErrBulkInviteJobNotFound = errors.New("bulk invitation job not found")
+
+type bulkInvitationRecord struct {
+	row   int
+	name  string
+	email string
+	phone string
+}
+
+func normalizeBulkHeaderKey(value string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(value)) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// readBulkInvitationCSV reads every candidate row so the whole file is validated
+// before any background work starts.
+func readBulkInvitationCSV(csvFile io.Reader) ([]bulkInvitationRecord, error) {
+	reader := csv.NewReader(csvFile)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.LazyQuotes = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, errors.New("failed to read CSV header")
+	}
+
+	colMap := make(map[string]int)
+	for i, h := range header {
+		colMap[normalizeBulkHeaderKey(h)] = i
+	}
+
+	val := func(row []string, keys ...string) string {
+		for _, key := range keys {
+			if idx, ok := colMap[key]; ok && idx < len(row) {
+				return strings.TrimSpace(row[idx])
+			}
+		}
+		return ""
+	}
+
+	if _, ok := colMap["email"]; !ok {
+		return nil, errors.New("CSV must have an email column")
+	}
+
+	records := []bulkInvitationRecord{}
+	rowNumber := 1 // Header row
+	for {
+		row, err := reader.Read()
+		rowNumber++
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			records = append(records, bulkInvitationRecord{row: rowNumber})
+			continue
+		}
+
+		records = append(records, bulkInvitationRecord{
+			row:   rowNumber,
+			name:  val(row, "name", "fullname", "candidatename"),
+			email: val(row, "email", "emailaddress", "mail"),
+			phone: val(row, "phone", "phonenumber", "mobile", "mobilenumber"),
+		})
+	}
+
+	return records, nil
+}
+
+func (s *invitationService) BulkInvite(ctx context.Context, assessmentIDStr, interviewerIDStr string, csvFile io.Reader, expiresInHours int) (*models.BulkInvitationJob, error) {
+	assessment, err := s.findActiveAssessment(ctx, assessmentIDStr)
+	if err != nil {
+		return nil, err
+	}
+	interviewerID, _ := utils.ToObjectID(interviewerIDStr)
+
+	records, err := readBulkInvitationCSV(csvFile)
+	if err != nil {
+		return nil, err
+	}
+
+	job := &models.BulkInvitationJob{
+		ID:           primitive.NewObjectID().Hex(),
+		AssessmentID: assessment.ID,
+		Status:       "processing",
+		TotalRows:    len(records),
+		Summary:      map[string]int{},
+		Rows:         []models.BulkInvitationRow{},
+		CreatedAt:    time.Now(),
+	}
+	ttl := invitationTTL(expiresInHours)
+
+	// Inline jobs are cached too, so every returned job ID can be polled
+	saveBulkInviteJob(job)
+	if len(records) <= bulkInvitationInlineLimit {
+		s.processBulkInvitations(ctx, job, records, interviewerID, ttl)
+		return job, nil
+	}
+
+	utils.GetWorkerPool().Submit(func() {
+		// The request context is gone by the time the worker runs.
+		bgCtx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+		defer cancel()
+		s.processBulkInvitations(bgCtx, job, records, interviewerID, ttl)
+	})
+
+	return snapshotBulkInviteJob(job), nil
+}
+
+func (s *invitationService) GetBulkInviteJob(jobID string) (*models.BulkInvitationJob, error) {
+	cached, ok := utils.GetCache().Get(bulkInvitationCachePrefix + jobID)
+	if !ok {
+		return nil, ErrBulkInviteJobNotFound
+	}
+	return cached.(*models.BulkInvitationJob), nil
+}
+
+func (s *invitationService) processBulkInvitations(ctx context.Context, job *models.BulkInvitationJob, records []bulkInvitationRecord, interviewerID primitive.ObjectID, ttl time.Duration) {
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("candidate123"), 10)
+	seen := make(map[string]bool)
+
+	for i, record := range records {
+		row := s.inviteBulkRecord(ctx, job.AssessmentID, record, seen, string(hashedPassword), interviewerID, ttl)
+		job.Rows = append(job.Rows, row)
+		job.Summary[row.Status]++
+		job.ProcessedRows++
+
+		if (i+1)%25 == 0 {
+			saveBulkInviteJob(job)
+		}
+	}
+
+	now := time.Now()
+	job.Status = "completed"
+	job.CompletedAt = &now
+	saveBulkInviteJob(job)
+}
+
+func (s *invitationService) inviteBulkRecord(ctx context.Context, aID primitive.ObjectID, record bulkInvitationRecord, seen map[string]bool, hashedPassword string, interviewerID primitive.ObjectID, ttl time.Duration) models.BulkInvitationRow {
+	row := models.BulkInvitationRow{
+		Row:   record.row,
+		Name:  utils.SanitizeStrict(record.name),
+		Email: strings.TrimSpace(strings.ToLower(record.email)),
+		Phone: record.phone,
+	}
+
+	if row.Email == "" || row.Name == "" {
+		row.Status = models.BulkRowInvalid
+		row.Error = "name and email are required"
+		return row
+	}
+	if row.Phone != "" {
+		if !utils.IsValidPhone(row.Phone) {
+			row.Status = models.BulkRowInvalidPhone
+			row.Error = "phone must be a 10-digit mobile number"
+			return row
+		}
+		row.Phone = utils.NormalizePhone(row.Phone)
+	}
+
+	if seen["email:"+row.Email] || (row.Phone != "" && seen["phone:"+row.Phone]) {
+		row.Status = models.BulkRowDuplicate
+		row.Error = "candidate appears earlier in the file"
+		return row
+	}
+	seen["email:"+row.Email] = true
+	if row.Phone != "" {
+		seen["phone:"+row.Phone] = true
+	}
+
+	row.Status = models.BulkRowCreated
+	if existing, err := s.userRepo.FindByEmail(ctx, row.Email); err == nil && existing != nil {
+		row.Status = models.BulkRowExisting
+	} else if row.Phone != "" {
+		if existing, err := s.userRepo.FindByPhone(ctx, row.Phone); err == nil && existing != nil {
+			row.Status = models.BulkRowExisting
+		}
+	}
+
+	candidate, err := s.userRepo.UpsertCandidate(ctx, &models.User{
+		Name:      row.Name,
+		Email:     row.Email,
+		Phone:     row.Phone,
+		Password:  hashedPassword,
+		Role:      "candidate",
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	})
+	if err != nil {
+		row.Status = models.BulkRowFailed
+		row.Error = err.Error()
+		return row
+	}
+
+	invitation, token, err := s.issueInvitation(ctx, aID, candidate, interviewerID, ttl)
+	if err != nil {
+		row.Status = models.BulkRowFailed
+		row.Error = err.Error()
+		return row
+	}
+
+	row.InvitationID = invitation.ID
+	row.InviteURL = s.InvitationURL(invitation, token)
+	return row
+}
+
+// saveBulkInviteJob stores a copy so readers never share slices with the running worker.
+func saveBulkInviteJob(job *models.BulkInvitationJob) {
+	utils.GetCache().Set(bulkInvitationCachePrefix+job.ID, snapshotBulkInviteJob(job), bulkInvitationJobTTL)
+}
+
+func snapshotBulkInviteJob(job *models.BulkInvitationJob) *models.BulkInvitationJob {
+	snapshot := *job
+	snapshot.Rows = append([]models.BulkInvitationRow{}, job.Rows...)
+	snapshot.Summary = make(map[string]int, len(job.Summary))
+	for k, v := range job.Summary {
+		snapshot.Summary[k] = v
+	}
+	return &snapshot
+}
@@ -0,0 +1,406 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"time"
+
+	"hireit-backend/models"
+	"hireit-backend/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	raschMaxIterations = 100
+	raschTolerance     = 0.001
+	raschMaxStep       = 1.0
+	// Items outside ±difficultyBandCutoff logits of the mean item are Easy or Hard.
+	difficultyBandCutoff = 1.0
+	// Relabelling needs this many responses and an estimate this many standard errors
+	// outside the current label's band, so noise does not produce suggestions.
+	minCalibrationResponses = 30
+	suggestionConfidenceZ   = 2.0
+)
+
+// raschData is the sparse person-by-item response matrix gathered from graded submissions.
+// Candidates only see a sample of the bank, which joint maximum likelihood handles directly.
+type raschData struct {
+	itemIndex map[primitive.ObjectID]int
+	items     []primitive.ObjectID
+	persons   [][]raschResponse
+}
+
+type raschResponse struct {
+	item    int
+	correct bool
+}
+
+type raschEstimate struct {
+	difficulty    float64
+	standardError float64
+	responses     int
+}
+
+func newRaschData() *raschData {
+	return &raschData{itemIndex: map[primitive.ObjectID]int{}}
+}
+
+func (d *raschData) add(sub *models.Submission) {
+	answers := make(map[primitive.ObjectID]string, len(sub.Answers))
+	for _, a := range sub.Answers {
+		answers[a.QuestionID] = a.Value
+	}
+
+	responses := make([]raschResponse, 0, len(sub.GeneratedQuestions))
+	for _, q := range sub.GeneratedQuestions {
+		if q.Type != models.MultipleChoice || q.ID.IsZero() {
+			continue
+		}
+		idx, ok := d.itemIndex[q.ID]
+		if !ok {
+			idx = len(d.items)
+			d.itemIndex[q.ID] = idx
+			d.items = append(d.items, q.ID)
+		}
+		value := answers[q.ID]
+		responses = append(responses, raschResponse{item: idx, correct: value != "" && value == q.CorrectAnswer})
+	}
+	if len(responses) > 0 {
+		d.persons = append(d.persons, responses)
+	}
+}
+
+// fit estimates item difficulties with joint maximum likelihood. Persons and items with
+// all-correct or all-wrong scores have no finite estimate and are dropped until none remain.
+func (d *raschData) fit() map[primitive.ObjectID]raschEstimate {
+	activePerson := make([]bool, len(d.persons))
+	activeItem := make([]bool, len(d.items))
+	for i := range activePerson {
+		activePerson[i] = true
+	}
+	for i := range activeItem {
+		activeItem[i] = true
+	}
+
+	personScore := make([]int, len(d.persons))
+	personCount := make([]int, len(d.persons))
+	itemScore := make([]int, len(d.items))
+	itemCount := make([]int, len(d.items))
+
+	for changed := true; changed; {
+		changed = false
+		for i := range itemScore {
+			itemScore[i], itemCount[i] = 0, 0
+		}
+		for n, responses := range d.persons {
+			personScore[n], personCount[n] = 0, 0
+			if !activePerson[n] {
+				continue
+			}
+			for _, r := range responses {
+				if !activeItem[r.item] {
+					continue
+				}
+				personCount[n]++
+				if r.correct {
+					personScore[n]++
+				}
+			}
+		}
+		for n, responses := range d.persons {
+			if !activePerson[n] {
+				continue
+			}
+			if personScore[n] == 0 || personScore[n] == personCount[n] {
+				activePerson[n] = false
+				changed = true
+				continue
+			}
+			for _, r := range responses {
+				if !activeItem[r.item] {
+					continue
+				}
+				itemCount[r.item]++
+				if r.correct {
+					itemScore[r.item]++
+				}
+			}
+		}
+		for i := range activeItem {
+			if activeItem[i] && (itemScore[i] == 0 || itemScore[i] == itemCount[i]) {
+				activeItem[i] = false
+				changed = true
+			}
+		}
+	}
+
+	theta := make([]float64, len(d.persons))
+	for n := range theta {
+		if activePerson[n] {
+			theta[n] = math.Log(float64(personScore[n]) / float64(personCount[n]-personScore[n]))
+		}
+	}
+	b := make([]float64, len(d.items))
+	for i := range b {
+		if activeItem[i] {
+			b[i] = math.Log(float64(itemCount[i]-itemScore[i]) / float64(itemScore[i]))
+		}
+	}
+
+	expected := make([]float64, len(d.items))
+	information := make([]float64, len(d.items))
+	for iter := 0; iter < raschMaxIterations; iter++ {
+		maxChange := 0.0
+
+		// Person step
+		for n, responses := range d.persons {
+			if !activePerson[n] {
+				continue
+			}
+			sumP, sumInfo := 0.0, 0.0
+			for _, r := range responses {
+				if !activeItem[r.item] {
+					continue
+				}
+				p := raschProbability(theta[n], b[r.item])
+				sumP += p
+				sumInfo += p * (1 - p)
+			}
+			step := clampStep((float64(personScore[n]) - sumP) / sumInfo)
+			theta[n] += step
+			maxChange = math.Max(maxChange, math.Abs(step))
+		}
+
+		// Item step
+		for i := range expected {
+			expected[i], information[i] = 0, 0
+		}
+		for n, responses := range d.persons {
+			if !activePerson[n] {
+				continue
+			}
+			for _, r := range responses {
+				if !activeItem[r.item] {
+					continue
+				}
+				p := raschProbability(theta[n], b[r.item])
+				expected[r.item] += p
+				information[r.item] += p * (1 - p)
+			}
+		}
+		for i := range b {
+			if !activeItem[i] {
+				continue
+			}
+			step := clampStep((expected[i] - float64(itemScore[i])) / information[i])
+			b[i] += step
+			maxChange = math.Max(maxChange, math.Abs(step))
+		}
+
+		// Anchor the scale on the mean item difficulty
+		mean, count := 0.0, 0
+		for i := range b {
+			if activeItem[i] {
+				mean += b[i]
+				count++
+			}
+		}
+		if count == 0 {
+			break
+		}
+		mean /= float64(count)
+		for i := range b {
+			if activeItem[i] {
+				b[i] -= mean
+			}
+		}
+		for n := range theta {
+			if activePerson[n] {
+				theta[n] -= mean
+			}
+		}
+
+		if maxChange < raschTolerance {
+			break
+		}
+	}
+
+	// Standard errors from the final information
+	for i := range information {
+		information[i] = 0
+	}
+	for n, responses := range d.persons {
+		if !activePerson[n] {
+			continue
+		}
+		for _, r := range responses {
+			if activeItem[r.item] {
+				p := raschProbability(theta[n], b[r.item])
+				information[r.item] += p * (1 - p)
+			}
+		}
+	}
+
+	// JML overstates difficulties by roughly L/(L-1) for tests of L items; with sampled
+	// forms L is the mean number of calibrated items a candidate answered.
+	correction := 1.0
+	responses, persons := 0, 0
+	for n := range d.persons {
+		if activePerson[n] {
+			responses += personCount[n]
+			persons++
+		}
+	}
+	if persons > 0 {
+		if length := float64(responses) / float64(persons); length > 1 {
+			correction = (length - 1) / length
+		}
+	}
+
+	estimates := make(map[primitive.ObjectID]raschEstimate)
+	for i, id := range d.items {
+		if !activeItem[i] || information[i] <= 0 {
+			continue
+		}
+		estimates[id] = raschEstimate{
+			difficulty:    roundStat(b[i] * correction),
+			standardError: roundStat(1 / math.Sqrt(information[i])),
+			responses:     itemCount[i],
+		}
+	}
+	return estimates
+}
+
+func raschProbability(theta, difficulty float64) float64 {
+	return 1 / (1 + math.Exp(difficulty-theta))
+}
+
+func clampStep(step float64) float64 {
+	return math.Max(-raschMaxStep, math.Min(raschMaxStep, step))
+}
+
+// difficultyBand returns the label band for a canonical difficulty label.
+func difficultyBand(label string) (float64, float64, bool) {
+	switch label {
+	case models.DifficultyEasy:
+		return math.Inf(-1), -difficultyBandCutoff, true
+	case models.DifficultyMedium:
+		return -difficultyBandCutoff, difficultyBandCutoff, true
+	case models.DifficultyHard:
+		return difficultyBandCutoff, math.Inf(1), true
+	}
+	return 0, 0, false
+}
+
+// canonicalDifficulty maps author-typed labels such as "easy" onto the calibrated labels.
+func canonicalDifficulty(label string) string {
+	for _, known := range []string{models.DifficultyEasy, models.DifficultyMedium, models.DifficultyHard} {
+		if strings.EqualFold(strings.TrimSpace(label), known) {
+			return known
+		}
+	}
+	return ""
+}
+
+// suggestDifficulty proposes a new label only when the estimate is confidently outside the
+// band of the current one. Entries with custom difficulty labels are never relabelled.
+func suggestDifficulty(current string, estimate raschEstimate) string {
+	label := canonicalDifficulty(current)
+	low, high, ok := difficultyBand(label)
+	if !ok || estimate.responses < minCalibrationResponses {
+		return ""
+	}
+
+	margin := suggestionConfidenceZ * estimate.standardError
+	if estimate.difficulty >= low-margin && estimate.difficulty <= high+margin {
+		return ""
+	}
+
+	switch {
+	case estimate.difficulty < -difficultyBandCutoff:
+		return models.DifficultyEasy
+	case estimate.difficulty > difficultyBandCutoff:
+		return models.DifficultyHard
+	default:
+		return models.DifficultyMedium
+	}
+}
+
+// calibrationUpdates turns the Rasch estimates into calibration fields for BulkSet.
+func (s *itemAnalysisService) calibrationUpdates(ctx context.Context, estimates map[primitive.ObjectID]raschEstimate, now time.Time) (map[primitive.ObjectID]bson.M, int, error) {
+	ids := make([]primitive.ObjectID, 0, len(estimates))
+	for id := range estimates {
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return nil, 0, nil
+	}
+
+	entries, err := s.qbRepo.Find(ctx, bson.M{"_id": bson.M{"$in": ids}}, options.Find().SetProjection(bson.M{"difficulty": 1}))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	updates := make(map[primitive.ObjectID]bson.M, len(entries))
+	suggestions := 0
+	for _, entry := range entries {
+		estimate := estimates[entry.ID]
+		calibration := &models.ItemCalibration{
+			Difficulty:          estimate.difficulty,
+			StandardError:       estimate.standardError,
+			Responses:           estimate.responses,
+			SuggestedDifficulty: suggestDifficulty(entry.Difficulty, estimate),
+			ComputedAt:          now,
+		}
+		if calibration.SuggestedDifficulty != "" {
+			suggestions++
+		}
+		updates[entry.ID] = bson.M{"calibration": calibration}
+	}
+	return updates, suggestions, nil
+}
+
+func (s *itemAnalysisService) ListDifficultySuggestions(ctx context.Context, category string) ([]models.QuestionBankEntry, error) {
+	filter := bson.M{"calibration.suggested_difficulty": bson.M{"$exists": true, "$ne": ""}}
+	if category != "" {
+		filter["category"] = category
+	}
+
+	opts := options.Find().SetSort(bson.D{
+		{Key: "category", Value: 1},
+		{Key: "sub_category", Value: 1},
+		{Key: "calibration.difficulty", Value: 1},
+	})
+	suggestions, err := s.qbRepo.Find(ctx, filter, opts)
+	if suggestions == nil {
+		suggestions = []models.QuestionBankEntry{}
+	}
+	return suggestions, err
+}
+
+// AcceptDifficultySuggestions applies the suggested labels to the given entries, or to
+// every entry with a suggestion when all is set.
+func (s *itemAnalysisService) AcceptDifficultySuggestions(ctx context.Context, ids []string, all bool) (int64, error) {
+	filter := bson.M{}
+	if !all {
+		if len(ids) == 0 {
+			return 0, errors.New("no question IDs given")
+		}
+		objectIDs := make([]primitive.ObjectID, 0, len(ids))
+		for _, id := range ids {
+			objectID, err := utils.ToObjectID(id)
+			if err != nil {
+				return 0, err
+			}
+			objectIDs = append(objectIDs, objectID)
+		}
+		filter["_id"] = bson.M{"$in": objectIDs}
+	}
+
+	return s.qbRepo.AcceptDifficultySuggestions(ctx, filter)
+}
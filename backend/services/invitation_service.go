@@ -0,0 +1,337 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hireit-backend/models"
+	"hireit-backend/repositories"
+	"hireit-backend/utils"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	defaultInvitationTTL  = 72 * time.Hour
+	MaxInvitationTTLHours = 720
+	invitationTokenType   = "assessment_invitation"
+)
+
+var (
+	ErrInvitationInvalid = errors.New("invalid invitation link")
+	ErrInvitationExpired = errors.New("invitation has expired")
+	ErrInvitationUsed    = errors.New("invitation has already been used")
+	ErrInvitationRevoked = errors.New("invitation has been revoked")
+
+	ErrInvalidInvitationID = errors.New("invalid invitation ID")
+	ErrInvitationNotFound  = errors.New("invitation not found")
+
+	ErrInvalidAssessmentID = errors.New("invalid assessment ID")
+	ErrAssessmentNotFound  = errors.New("assessment not found or deleted")
+)
+
+type InvitationService interface {
+	CreateInvitation(ctx context.Context, assessmentID, interviewerID string, req *models.CreateInvitationRequest) (*models.Invitation, string, error)
+	GetInvitations(ctx context.Context, assessmentID string, ownerID primitive.ObjectID) ([]models.Invitation, error)
+	RevokeInvitation(ctx context.Context, id string, ownerID primitive.ObjectID) error
+	AcceptInvitation(ctx context.Context, token string) (string, *models.User, *models.Invitation, error)
+	InvitationURL(invitation *models.Invitation, token string) string
+	BulkInvite(ctx context.Context, assessmentID, interviewerID string, csvFile io.Reader, expiresInHours int) (*models.BulkInvitationJob, error)
+	GetBulkInviteJob(jobID string) (*models.BulkInvitationJob, error)
+}
+
+type invitationService struct {
+	repo           repositories.InvitationRepository
+	assessmentRepo repositories.AssessmentRepository
+	userRepo       repositories.UserRepository
+	authService    AuthService
+}
+
+func NewInvitationService(repo repositories.InvitationRepository, assessmentRepo repositories.AssessmentRepository, userRepo repositories.UserRepository, authService AuthService) InvitationService {
+	return &invitationService{
+		repo:           repo,
+		assessmentRepo: assessmentRepo,
+		userRepo:       userRepo,
+		authService:    authService,
+	}
+}
+
+// invitationSecret keeps invitation links signed with a different key than session
+// tokens, so an invitation link can never be replayed as an Authorization header.
+func invitationSecret() []byte {
+	if secret := os.Getenv("INVITATION_TOKEN_SECRET"); secret != "" {
+		return []byte(secret)
+	}
+	return []byte(os.Getenv("JWT_SECRET") + ":invitation")
+}
+
+func (s *invitationService) CreateInvitation(ctx context.Context, assessmentIDStr, interviewerIDStr string, req *models.CreateInvitationRequest) (*models.Invitation, string, error) {
+	assessment, err := s.findActiveAssessment(ctx, assessmentIDStr)
+	if err != nil {
+		return nil, "", err
+	}
+	interviewerID, _ := utils.ToObjectID(interviewerIDStr)
+
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("candidate123"), 10)
+	candidate, err := s.userRepo.UpsertCandidate(ctx, &models.User{
+		Name:      utils.SanitizeStrict(req.Name),
+		Email:     strings.TrimSpace(strings.ToLower(req.Email)),
+		Phone:     utils.NormalizePhone(req.Phone),
+		Password:  string(hashedPassword),
+		Role:      "candidate",
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return s.issueInvitation(ctx, assessment.ID, candidate, interviewerID, invitationTTL(req.ExpiresInHours))
+}
+
+func (s *invitationService) findActiveAssessment(ctx context.Context, assessmentIDStr string) (*models.Assessment, error) {
+	aID, err := utils.ToObjectID(assessmentIDStr)
+	if err != nil {
+		return nil, ErrInvalidAssessmentID
+	}
+
+	assessment, err := s.assessmentRepo.FindByID(ctx, aID)
+	if errors.Is(err, mongo.ErrNoDocuments) || (err == nil && assessment.DeletedAt != nil) {
+		return nil, ErrAssessmentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return assessment, nil
+}
+
+// invitationTTL returns the requested lifetime, capped at MaxInvitationTTLHours, or the
+// default when none is given.
+func invitationTTL(expiresInHours int) time.Duration {
+	if expiresInHours <= 0 {
+		return defaultInvitationTTL
+	}
+	return time.Duration(min(expiresInHours, MaxInvitationTTLHours)) * time.Hour
+}
+
+// issueInvitation stores a new invitation for an already upserted candidate and returns its signed token.
+func (s *invitationService) issueInvitation(ctx context.Context, aID primitive.ObjectID, candidate *models.User, interviewerID primitive.ObjectID, ttl time.Duration) (*models.Invitation, string, error) {
+	now := time.Now()
+	invitation := &models.Invitation{
+		ID:             primitive.NewObjectID(),
+		AssessmentID:   aID,
+		CandidateID:    candidate.ID,
+		CandidateName:  candidate.Name,
+		CandidateEmail: candidate.Email,
+		CandidatePhone: candidate.Phone,
+		TokenID:        utils.GenerateRandomString(16),
+		Status:         models.InvitationStatusSent,
+		ExpiresAt:      now.Add(ttl),
+		SentAt:         now,
+		CreatedBy:      interviewerID,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+
+	token, err := signInvitationToken(invitation)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if _, err := s.repo.Create(ctx, invitation); err != nil {
+		return nil, "", err
+	}
+
+	return invitation, token, nil
+}
+
+// GetInvitations lists the invitations of an assessment. A non-zero ownerID restricts
+// this to assessments created by that user; others are reported as not found.
+func (s *invitationService) GetInvitations(ctx context.Context, assessmentIDStr string, ownerID primitive.ObjectID) ([]models.Invitation, error) {
+	assessment, err := s.findActiveAssessment(ctx, assessmentIDStr)
+	if err != nil {
+		return nil, err
+	}
+	if !ownerID.IsZero() && assessment.CreatedBy != ownerID {
+		return nil, ErrAssessmentNotFound
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	return s.repo.FindAll(ctx, bson.M{"assessment_id": assessment.ID, "deleted_at": nil}, opts)
+}
+
+// RevokeInvitation revokes an invitation that has not been opened yet. The status is
+// checked in the update itself, so a revoke racing an accept leaves only one of them done.
+// A non-zero ownerID restricts this to invitations to assessments created by that user.
+func (s *invitationService) RevokeInvitation(ctx context.Context, idStr string, ownerID primitive.ObjectID) error {
+	id, err := utils.ToObjectID(idStr)
+	if err != nil {
+		return ErrInvalidInvitationID
+	}
+	if !ownerID.IsZero() {
+		invitation, err := s.repo.FindByID(ctx, id)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return ErrInvitationNotFound
+		}
+		if err != nil {
+			return err
+		}
+		assessment, err := s.assessmentRepo.FindByID(ctx, invitation.AssessmentID)
+		if errors.Is(err, mongo.ErrNoDocuments) || (err == nil && assessment.CreatedBy != ownerID) {
+			return ErrInvitationNotFound
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	revoked, err := s.repo.UpdateMany(ctx, bson.M{
+		"_id":        id,
+		"status":     models.InvitationStatusSent,
+		"deleted_at": nil,
+	}, bson.M{
+		"$set": bson.M{
+			"status":     models.InvitationStatusRevoked,
+			"updated_at": time.Now(),
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if revoked > 0 {
+		return nil
+	}
+
+	invitation, err := s.repo.FindByID(ctx, id)
+	if errors.Is(err, mongo.ErrNoDocuments) || (err == nil && invitation.DeletedAt != nil) {
+		return ErrInvitationNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return ErrInvitationUsed
+}
+
+// AcceptInvitation validates the signed link, consumes the invitation and
+// issues a candidate session token for the invited candidate. If no session can be
+// issued, the invitation is put back to sent so the link still works.
+func (s *invitationService) AcceptInvitation(ctx context.Context, token string) (string, *models.User, *models.Invitation, error) {
+	invitationID, tokenID, err := parseInvitationToken(token)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	now := time.Now()
+	// Consume atomically so the same link cannot start two sessions.
+	invitation, err := s.repo.FindOneAndUpdate(ctx, bson.M{
+		"_id":        invitationID,
+		"token_id":   tokenID,
+		"status":     models.InvitationStatusSent,
+		"expires_at": bson.M{"$gt": now},
+		"deleted_at": nil,
+	}, bson.M{
+		"$set": bson.M{
+			"status":     models.InvitationStatusOpened,
+			"opened_at":  now,
+			"updated_at": now,
+		},
+	})
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return "", nil, nil, s.invitationRejection(ctx, invitationID, tokenID)
+		}
+		return "", nil, nil, err
+	}
+
+	sessionToken, user, err := s.authService.StartInvitedAssessment(ctx, invitation.CandidateID)
+	if err != nil {
+		// No session was issued, so the link is handed back for another try
+		if _, restoreErr := s.repo.UpdateMany(context.WithoutCancel(ctx), bson.M{
+			"_id":      invitation.ID,
+			"token_id": tokenID,
+			"status":   models.InvitationStatusOpened,
+		}, bson.M{
+			"$set":   bson.M{"status": models.InvitationStatusSent, "updated_at": time.Now()},
+			"$unset": bson.M{"opened_at": ""},
+		}); restoreErr != nil {
+			fmt.Printf("[Invitation] Failed to restore invitation %s after a failed accept: %v\n", invitation.ID.Hex(), restoreErr)
+		}
+		return "", nil, nil, err
+	}
+
+	return sessionToken, user, invitation, nil
+}
+
+// invitationRejection explains why a consume attempt matched nothing.
+func (s *invitationService) invitationRejection(ctx context.Context, invitationID primitive.ObjectID, tokenID string) error {
+	invitation, err := s.repo.FindByID(ctx, invitationID)
+	if err != nil || invitation.TokenID != tokenID {
+		return ErrInvitationInvalid
+	}
+
+	switch {
+	case invitation.Status == models.InvitationStatusRevoked:
+		return ErrInvitationRevoked
+	case invitation.Status != models.InvitationStatusSent:
+		return ErrInvitationUsed
+	case !invitation.ExpiresAt.After(time.Now()):
+		return ErrInvitationExpired
+	default:
+		return ErrInvitationInvalid
+	}
+}
+
+func (s *invitationService) InvitationURL(invitation *models.Invitation, token string) string {
+	frontendURL := strings.TrimRight(strings.TrimSpace(os.Getenv("FRONTEND_URL")), "/")
+	return fmt.Sprintf("%s/public/assessments/%s?invite=%s", frontendURL, invitation.AssessmentID.Hex(), token)
+}
+
+func signInvitationToken(invitation *models.Invitation) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub": invitation.ID.Hex(),
+		"aid": invitation.AssessmentID.Hex(),
+		"jti": invitation.TokenID,
+		"typ": invitationTokenType,
+		"exp": invitation.ExpiresAt.Unix(),
+	})
+
+	return token.SignedString(invitationSecret())
+}
+
+func parseInvitationToken(tokenString string) (primitive.ObjectID, string, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return invitationSecret(), nil
+	})
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return primitive.NilObjectID, "", ErrInvitationExpired
+		}
+		return primitive.NilObjectID, "", ErrInvitationInvalid
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid || claims["typ"] != invitationTokenType {
+		return primitive.NilObjectID, "", ErrInvitationInvalid
+	}
+
+	subject, _ := claims["sub"].(string)
+	tokenID, _ := claims["jti"].(string)
+	invitationID, err := primitive.ObjectIDFromHex(subject)
+	if err != nil || tokenID == "" {
+		return primitive.NilObjectID, "", ErrInvitationInvalid
+	}
+
+	return invitationID, tokenID, nil
+}
@@ -0,0 +1,367 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"hireit-backend/models"
+	"hireit-backend/repositories"
+	"hireit-backend/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	itemAnalysisCacheKey        = "item_analysis_job"
+	itemAnalysisJobTTL          = 7 * 24 * time.Hour
+	defaultItemAnalysisInterval = 24 * time.Hour
+	// Statistics are stored for every item, but flags need at least this many responses.
+	minItemAnalysisResponses = 20
+	// A distractor needs this share of responses before it can be called misleading.
+	minDistractorRate = 0.05
+)
+
+var (
+	ErrItemAnalysisRunning  = errors.New("item analysis is already running")
+	ErrItemAnalysisNotFound = errors.New("item analysis has not been run yet")
+)
+
+type ItemAnalysisService interface {
+	StartItemAnalysis() (*models.ItemAnalysisJob, error)
+	GetItemAnalysisJob() (*models.ItemAnalysisJob, error)
+	ListDifficultySuggestions(ctx context.Context, category string) ([]models.QuestionBankEntry, error)
+	AcceptDifficultySuggestions(ctx context.Context, ids []string, all bool) (int64, error)
+	StartSchedule(ctx context.Context)
+}
+
+type itemAnalysisService struct {
+	subRepo repositories.SubmissionRepository
+	qbRepo  repositories.QuestionBankRepository
+
+	mu      sync.Mutex
+	running bool
+}
+
+func NewItemAnalysisService(subRepo repositories.SubmissionRepository, qbRepo repositories.QuestionBankRepository) ItemAnalysisService {
+	return &itemAnalysisService{subRepo: subRepo, qbRepo: qbRepo}
+}
+
+func (s *itemAnalysisService) StartItemAnalysis() (*models.ItemAnalysisJob, error) {
+	return s.start("manual")
+}
+
+func (s *itemAnalysisService) GetItemAnalysisJob() (*models.ItemAnalysisJob, error) {
+	cached, ok := utils.GetCache().Get(itemAnalysisCacheKey)
+	if !ok {
+		return nil, ErrItemAnalysisNotFound
+	}
+	return cached.(*models.ItemAnalysisJob), nil
+}
+
+// StartSchedule reruns the analysis every ITEM_ANALYSIS_INTERVAL_HOURS (default 24, 0 disables)
+// until ctx is cancelled.
+func (s *itemAnalysisService) StartSchedule(ctx context.Context) {
+	interval := defaultItemAnalysisInterval
+	if hours, err := strconv.Atoi(os.Getenv("ITEM_ANALYSIS_INTERVAL_HOURS")); err == nil {
+		if hours <= 0 {
+			return
+		}
+		interval = time.Duration(hours) * time.Hour
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := s.start("scheduled"); err != nil {
+					utils.GetLogger().Warnf("Scheduled item analysis skipped: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+func (s *itemAnalysisService) start(trigger string) (*models.ItemAnalysisJob, error) {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return nil, ErrItemAnalysisRunning
+	}
+	s.running = true
+	s.mu.Unlock()
+
+	job := &models.ItemAnalysisJob{
+		ID:        primitive.NewObjectID().Hex(),
+		Status:    "processing",
+		Trigger:   trigger,
+		StartedAt: time.Now(),
+	}
+	saveItemAnalysisJob(job)
+
+	utils.GetWorkerPool().Submit(func() {
+		defer func() {
+			s.mu.Lock()
+			s.running = false
+			s.mu.Unlock()
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+		defer cancel()
+		s.run(ctx, job)
+	})
+
+	snapshot := *job
+	return &snapshot, nil
+}
+
+// run computes classical item statistics and a Rasch calibration from the same scan of
+// graded submissions and writes both onto the bank entries.
+func (s *itemAnalysisService) run(ctx context.Context, job *models.ItemAnalysisJob) {
+	items := make(map[primitive.ObjectID]*itemAccumulator)
+	responses := newRaschData()
+
+	opts := options.Find().SetProjection(bson.M{
+		"answers":                            1,
+		"adaptive.theta":                     1,
+		"generated_questions._id":            1,
+		"generated_questions.type":           1,
+		"generated_questions.options":        1,
+		"generated_questions.correct_answer": 1,
+		"generated_questions.points":         1,
+		"generated_questions.parameters":     1,
+	})
+	filter := bson.M{"status": "completed", "is_demo": bson.M{"$ne": true}, "deleted_at": nil}
+
+	scanned, err := s.subRepo.ForEach(ctx, filter, opts, func(sub *models.Submission) error {
+		// Adaptive attempts pick items by ability, which biases p-values and
+		// point-biserials, so they only feed the Rasch calibration
+		if sub.Adaptive == nil {
+			accumulateItemResponses(items, sub)
+		}
+		responses.add(sub)
+		return nil
+	})
+	job.SubmissionsScanned = scanned
+
+	now := time.Now()
+	var updates map[primitive.ObjectID]bson.M
+	if err == nil {
+		estimates := responses.fit()
+		job.ItemsCalibrated = len(estimates)
+		updates, job.Suggestions, err = s.calibrationUpdates(ctx, estimates, now)
+	}
+	if err == nil {
+		if updates == nil {
+			updates = make(map[primitive.ObjectID]bson.M, len(items))
+		}
+		for id, item := range items {
+			if updates[id] == nil {
+				updates[id] = bson.M{}
+			}
+			updates[id]["item_stats"] = item.statistics(now)
+		}
+		job.ItemsUpdated, err = s.qbRepo.BulkSet(ctx, updates)
+	}
+
+	completedAt := time.Now()
+	job.CompletedAt = &completedAt
+	job.Status = "completed"
+	if err != nil {
+		job.Status = "failed"
+		job.Error = err.Error()
+		utils.GetLogger().Errorf("Item analysis failed: %v", err)
+	}
+	saveItemAnalysisJob(job)
+}
+
+func saveItemAnalysisJob(job *models.ItemAnalysisJob) {
+	snapshot := *job
+	utils.GetCache().Set(itemAnalysisCacheKey, &snapshot, itemAnalysisJobTTL)
+}
+
+// itemAccumulator keeps running sums of rest scores (the submission score without this
+// item) so the corrected point-biserial can be computed in a single pass.
+type itemAccumulator struct {
+	correctAnswer string
+	responses     int
+	correct       int
+	omitted       int
+	sumRest       float64
+	sumRestSq     float64
+	sumRestRight  float64
+
+	optionOrder []string
+	options     map[string]*optionAccumulator
+}
+
+type optionAccumulator struct {
+	count   int
+	sumRest float64
+}
+
+func (item *itemAccumulator) option(value string) *optionAccumulator {
+	if opt, ok := item.options[value]; ok {
+		return opt
+	}
+	opt := &optionAccumulator{}
+	item.options[value] = opt
+	item.optionOrder = append(item.optionOrder, value)
+	return opt
+}
+
+// accumulateItemResponses adds one graded submission to the per-entry sums. Only MCQ
+// questions are auto-graded, so other types are skipped. The rest score is taken from the
+// points of the graded answers rather than Submission.Score, which need not be a raw total.
+// A templated entry shows each candidate different options, so only whether it was
+// answered correctly is counted, not which option was chosen.
+func accumulateItemResponses(items map[primitive.ObjectID]*itemAccumulator, sub *models.Submission) {
+	answers := make(map[primitive.ObjectID]string, len(sub.Answers))
+	total := 0
+	for _, a := range sub.Answers {
+		answers[a.QuestionID] = a.Value
+		total += a.Points
+	}
+
+	for _, q := range sub.GeneratedQuestions {
+		if q.Type != models.MultipleChoice || q.ID.IsZero() {
+			continue
+		}
+
+		templated := q.Parameters != nil
+		item, ok := items[q.ID]
+		if !ok {
+			item = &itemAccumulator{options: map[string]*optionAccumulator{}}
+			if !templated {
+				item.correctAnswer = q.CorrectAnswer
+				for _, opt := range q.Options {
+					item.option(opt)
+				}
+			}
+			items[q.ID] = item
+		}
+
+		value := answers[q.ID]
+		correct := value != "" && value == q.CorrectAnswer
+		rest := float64(total)
+		if correct {
+			rest -= float64(q.Points)
+		}
+
+		item.responses++
+		item.sumRest += rest
+		item.sumRestSq += rest * rest
+		if correct {
+			item.correct++
+			item.sumRestRight += rest
+		}
+
+		if value == "" {
+			item.omitted++
+			continue
+		}
+		if templated {
+			continue
+		}
+		opt := item.option(value)
+		opt.count++
+		opt.sumRest += rest
+	}
+}
+
+func (item *itemAccumulator) statistics(now time.Time) *models.ItemStatistics {
+	n := float64(item.responses)
+	p := float64(item.correct) / n
+
+	stats := &models.ItemStatistics{
+		Responses:  item.responses,
+		PValue:     roundStat(p),
+		Omitted:    item.omitted,
+		Options:    make([]models.OptionStatistics, 0, len(item.optionOrder)),
+		ComputedAt: now,
+	}
+
+	if item.correct > 0 && item.correct < item.responses {
+		mean := item.sumRest / n
+		variance := item.sumRestSq/n - mean*mean
+		if variance > 0 {
+			meanRight := item.sumRestRight / float64(item.correct)
+			meanWrong := (item.sumRest - item.sumRestRight) / float64(item.responses-item.correct)
+			r := roundStat((meanRight - meanWrong) / math.Sqrt(variance) * math.Sqrt(p*(1-p)))
+			stats.PointBiserial = &r
+		}
+	}
+
+	for _, value := range item.optionOrder {
+		opt := item.options[value]
+		optStats := models.OptionStatistics{
+			Option:    value,
+			IsCorrect: value == item.correctAnswer,
+			Count:     opt.count,
+			Rate:      roundStat(float64(opt.count) / n),
+		}
+		if opt.count > 0 {
+			optStats.MeanRestScore = roundStat(opt.sumRest / float64(opt.count))
+		}
+		stats.Options = append(stats.Options, optStats)
+	}
+
+	stats.Flags = itemFlags(stats)
+	return stats
+}
+
+// itemFlags applies the usual classical test theory rules of thumb.
+func itemFlags(stats *models.ItemStatistics) []string {
+	if stats.Responses < minItemAnalysisResponses {
+		return nil
+	}
+
+	var flags []string
+	switch {
+	case stats.PValue > 0.9:
+		flags = append(flags, models.ItemFlagTooEasy)
+	case stats.PValue < 0.2:
+		flags = append(flags, models.ItemFlagTooHard)
+	}
+
+	if stats.PointBiserial != nil {
+		switch {
+		case *stats.PointBiserial < 0:
+			flags = append(flags, models.ItemFlagNegativeDiscrimination)
+		case *stats.PointBiserial < 0.2:
+			flags = append(flags, models.ItemFlagLowDiscrimination)
+		}
+	}
+
+	// A distractor chosen by stronger candidates than the key suggests an ambiguous stem or wrong key.
+	var key *models.OptionStatistics
+	for i := range stats.Options {
+		if stats.Options[i].IsCorrect {
+			key = &stats.Options[i]
+		}
+	}
+	if key != nil && key.Count > 0 {
+		for _, opt := range stats.Options {
+			if !opt.IsCorrect && opt.Rate >= minDistractorRate && opt.MeanRestScore > key.MeanRestScore {
+				flags = append(flags, models.ItemFlagMisleadingDistractor)
+				break
+			}
+		}
+	}
+
+	return flags
+}
+
+func roundStat(v float64) float64 {
+	return math.Round(v*10000) / 10000
+}
@@ -0,0 +1,283 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif" // Registers the decoders used to check uploaded images
+	_ "image/jpeg"
+	_ "image/png"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"hireit-backend/models"
+	"hireit-backend/repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	MaxImageSize       = 5 << 20
+	MaxAudioSize       = 25 << 20
+	MaxMediaUploadSize = MaxAudioSize
+	mediaURLPrefix     = "/media/"
+	maxImageDimension  = 10000
+	maxAttachments     = 20
+)
+
+var (
+	ErrUnsupportedMedia  = errors.New("unsupported media file")
+	ErrMediaTooLarge     = errors.New("media file is too large")
+	ErrMediaNotFound     = errors.New("media not found")
+	ErrInvalidAttachment = errors.New("invalid media attachment")
+)
+
+// mediaTypes lists the accepted content types, as sniffed, with their kind and the file
+// extensions an upload of that type may have. The first extension is used on disk. SVG is
+// deliberately not accepted, as it can carry scripts.
+var mediaTypes = map[string]struct {
+	kind       string
+	extensions []string
+}{
+	"image/png":  {models.MediaKindImage, []string{".png"}},
+	"image/jpeg": {models.MediaKindImage, []string{".jpg", ".jpeg"}},
+	"image/gif":  {models.MediaKindImage, []string{".gif"}},
+	"image/webp": {models.MediaKindImage, []string{".webp"}},
+	"audio/mpeg": {models.MediaKindAudio, []string{".mp3"}},
+	"audio/wav":  {models.MediaKindAudio, []string{".wav"}},
+	"audio/ogg":  {models.MediaKindAudio, []string{".ogg", ".oga"}},
+	"audio/mp4":  {models.MediaKindAudio, []string{".m4a"}},
+	"audio/aac":  {models.MediaKindAudio, []string{".aac"}},
+}
+
+// MediaService stores the images and audio attached to questions and resolves the
+// attachments of bank entries.
+type MediaService interface {
+	Store(ctx context.Context, name string, data []byte, uploadedBy primitive.ObjectID) (*models.Media, bool, error)
+	Find(ctx context.Context, id primitive.ObjectID) (*models.Media, string, error)
+	ReadFile(ctx context.Context, id primitive.ObjectID) (*models.Media, []byte, error)
+	ResolveAttachments(ctx context.Context, entry *models.QuestionBankEntry) error
+}
+
+type mediaService struct {
+	repo     repositories.MediaRepository
+	mediaDir string
+}
+
+func NewMediaService(repo repositories.MediaRepository, mediaDir string) MediaService {
+	return &mediaService{repo: repo, mediaDir: mediaDir}
+}
+
+// Store validates an upload and saves it, unless the same file is already stored, in which
+// case that media is returned and created is false. name is only used for its extension,
+// which has to agree with the sniffed content type.
+func (s *mediaService) Store(ctx context.Context, name string, data []byte, uploadedBy primitive.ObjectID) (*models.Media, bool, error) {
+	media, ext, err := inspectMedia(name, data)
+	if err != nil {
+		return nil, false, err
+	}
+
+	existing, err := s.repo.FindBySHA256(ctx, media.SHA256)
+	if err == nil {
+		return withMediaURL(existing), false, nil
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, false, err
+	}
+
+	media.ID = primitive.NewObjectID()
+	media.FileName = media.ID.Hex() + ext
+	media.UploadedBy = uploadedBy
+	media.CreatedAt = time.Now()
+
+	if err := os.MkdirAll(s.mediaDir, 0755); err != nil {
+		return nil, false, err
+	}
+	path := filepath.Join(s.mediaDir, media.FileName)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return nil, false, err
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = s.repo.Create(ctx, media)
+	}
+	if err != nil {
+		os.Remove(path)
+		if mongo.IsDuplicateKeyError(err) {
+			// Uploaded concurrently by another request
+			if existing, err := s.repo.FindBySHA256(ctx, media.SHA256); err == nil {
+				return withMediaURL(existing), false, nil
+			}
+		}
+		return nil, false, err
+	}
+	return withMediaURL(media), true, nil
+}
+
+// Find returns the media and the path of its file.
+func (s *mediaService) Find(ctx context.Context, id primitive.ObjectID) (*models.Media, string, error) {
+	media, err := s.repo.FindByID(ctx, id)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, "", ErrMediaNotFound
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return withMediaURL(media), filepath.Join(s.mediaDir, media.FileName), nil
+}
+
+func (s *mediaService) ReadFile(ctx context.Context, id primitive.ObjectID) (*models.Media, []byte, error) {
+	media, path, err := s.Find(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil, fmt.Errorf("%w: the file of %s is missing", ErrMediaNotFound, id.Hex())
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return media, data, nil
+}
+
+// ResolveAttachments checks the media attached to an entry and fills in the kind and URL
+// of each attachment. Options can only carry media on MCQs whose options are stored rather
+// than generated by a template.
+func (s *mediaService) ResolveAttachments(ctx context.Context, entry *models.QuestionBankEntry) error {
+	if len(entry.Media) == 0 {
+		entry.Media = nil
+		return nil
+	}
+	if len(entry.Media) > maxAttachments {
+		return fmt.Errorf("%w: at most %d attachments per question", ErrInvalidAttachment, maxAttachments)
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(entry.Media))
+	for _, attachment := range entry.Media {
+		ids = append(ids, attachment.MediaID)
+	}
+	found, err := s.repo.FindByIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+	byID := make(map[primitive.ObjectID]*models.Media, len(found))
+	for i := range found {
+		byID[found[i].ID] = &found[i]
+	}
+
+	for i := range entry.Media {
+		attachment := &entry.Media[i]
+		media, ok := byID[attachment.MediaID]
+		if !ok {
+			return fmt.Errorf("%w: media %s not found", ErrInvalidAttachment, attachment.MediaID.Hex())
+		}
+		if option := attachment.Option; option != nil {
+			if entry.Type != models.MultipleChoice || entry.Template != nil {
+				return fmt.Errorf("%w: only the stored options of an MCQ can carry media", ErrInvalidAttachment)
+			}
+			if *option < 0 || *option >= len(entry.Options) {
+				return fmt.Errorf("%w: option %d does not exist", ErrInvalidAttachment, *option)
+			}
+		}
+		attachment.AltText = strings.TrimSpace(attachment.AltText)
+		attachment.Caption = strings.TrimSpace(attachment.Caption)
+		attachment.Kind = media.Kind
+		attachment.URL = mediaURLPrefix + media.ID.Hex()
+	}
+	return nil
+}
+
+// inspectMedia sniffs and checks an upload and returns its media document, without ID, and
+// the extension to store it under.
+func inspectMedia(name string, data []byte) (*models.Media, string, error) {
+	if len(data) == 0 {
+		return nil, "", fmt.Errorf("%w: the file is empty", ErrUnsupportedMedia)
+	}
+	contentType := sniffMediaType(data)
+	accepted, ok := mediaTypes[contentType]
+	if !ok {
+		return nil, "", fmt.Errorf("%w: only PNG, JPEG, GIF and WebP images and MP3, WAV, OGG, M4A and AAC audio are accepted", ErrUnsupportedMedia)
+	}
+	ext := strings.ToLower(filepath.Ext(name))
+	extOK := false
+	for _, allowed := range accepted.extensions {
+		extOK = extOK || ext == allowed
+	}
+	if !extOK {
+		return nil, "", fmt.Errorf("%w: the file is %s, which does not match its extension %q", ErrUnsupportedMedia, contentType, ext)
+	}
+
+	limit := MaxAudioSize
+	if accepted.kind == models.MediaKindImage {
+		limit = MaxImageSize
+	}
+	if len(data) > limit {
+		return nil, "", fmt.Errorf("%w: %s files are limited to %d MB", ErrMediaTooLarge, accepted.kind, limit>>20)
+	}
+
+	media := &models.Media{
+		Kind:         accepted.kind,
+		ContentType:  contentType,
+		OriginalName: filepath.Base(name),
+		Size:         int64(len(data)),
+		SHA256:       sha256Hex(data),
+	}
+	if accepted.kind == models.MediaKindImage && contentType != "image/webp" {
+		// Decoding the header catches truncated or mislabelled files and oversized images
+		config, _, err := image.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			return nil, "", fmt.Errorf("%w: the image cannot be read: %v", ErrUnsupportedMedia, err)
+		}
+		if config.Width > maxImageDimension || config.Height > maxImageDimension {
+			return nil, "", fmt.Errorf("%w: images are limited to %dx%d pixels", ErrUnsupportedMedia, maxImageDimension, maxImageDimension)
+		}
+		media.Width, media.Height = config.Width, config.Height
+	}
+	return media, accepted.extensions[0], nil
+}
+
+// sniffMediaType identifies a file from its leading bytes. It extends
+// http.DetectContentType with the audio formats it does not recognise.
+func sniffMediaType(data []byte) string {
+	switch contentType := http.DetectContentType(data); contentType {
+	case "audio/wave":
+		return "audio/wav"
+	case "application/ogg":
+		return "audio/ogg"
+	case "video/mp4":
+		// M4A is an MP4 container with an audio brand
+		if len(data) >= 12 && (string(data[8:12]) == "M4A " || string(data[8:12]) == "M4B ") {
+			return "audio/mp4"
+		}
+		return contentType
+	case "application/octet-stream":
+		// Frame sync without an ID3 tag: ADTS AAC has layer 0, MPEG audio a non-zero layer
+		if len(data) >= 2 && data[0] == 0xFF {
+			switch {
+			case data[1]&0xF6 == 0xF0:
+				return "audio/aac"
+			case data[1]&0xE0 == 0xE0 && data[1]&0x06 != 0:
+				return "audio/mpeg"
+			}
+		}
+		return contentType
+	default:
+		return contentType
+	}
+}
+
+func withMediaURL(media *models.Media) *models.Media {
+	media.URL = mediaURLPrefix + media.ID.Hex()
+	return media
+}
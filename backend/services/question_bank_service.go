@@ -0,0 +1,269 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"hireit-backend/models"
+	"hireit-backend/repositories"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrRetirementUnderstocks = errors.New("retiring these questions would leave live assessments under-stocked")
+	ErrInvalidReviewAction   = errors.New("unknown review action")
+	ErrReviewTransition      = errors.New("the question's review status does not allow this action")
+	ErrReviewCommentRequired = errors.New("a comment is required")
+	ErrQuestionNotFound      = errors.New("question not found")
+)
+
+// MaxReviewQueueLimit caps the page size of the review queue.
+const MaxReviewQueueLimit = 100
+
+// reviewTransitions lists, per action, the statuses it applies to and the resulting status.
+// A comment keeps the current status.
+var reviewTransitions = map[string]struct {
+	from []string
+	to   string
+}{
+	models.ReviewActionSubmit:  {from: []string{models.ReviewStatusDraft, models.ReviewStatusRejected}, to: models.ReviewStatusInReview},
+	models.ReviewActionApprove: {from: []string{models.ReviewStatusInReview}, to: models.ReviewStatusApproved},
+	models.ReviewActionReject:  {from: []string{models.ReviewStatusInReview, models.ReviewStatusApproved}, to: models.ReviewStatusRejected},
+}
+
+type QuestionBankService interface {
+	RetireQuestions(ctx context.Context, filter bson.M, force bool, retiredBy primitive.ObjectID) (*models.RetirementReport, error)
+	RestoreQuestions(ctx context.Context, filter bson.M, restoredBy primitive.ObjectID) (int64, error)
+	ReviewQuestion(ctx context.Context, id primitive.ObjectID, action, comment string, reviewerID primitive.ObjectID) (*models.QuestionBankEntry, error)
+	ReviewQueue(ctx context.Context, filter bson.M, page, limit int) ([]models.QuestionBankEntry, int64, error)
+	NewImportDeduper() *ImportDeduper
+	ScanDuplicates(ctx context.Context, filter bson.M, threshold float64) (*models.DuplicateScanReport, error)
+	StageImport(ctx context.Context, rows []ImportRow) (*models.ImportValidationReport, error)
+	ConfirmImport(ctx context.Context, token string, authorID primitive.ObjectID) (*ImportResult, error)
+	SearchQuestions(ctx context.Context, search string, filter bson.M, page, limit int) ([]models.QuestionSearchHit, int64, error)
+	BankStats(ctx context.Context, minSampleable int) (*models.QuestionBankStats, error)
+	ApplyStructureOperation(ctx context.Context, op models.StructureOperation, authorID primitive.ObjectID) (*models.StructureOperationReport, error)
+	BulkUpdateQuestions(ctx context.Context, filter bson.M, changes models.QuestionBulkChanges, dryRun bool, authorID primitive.ObjectID) (*models.QuestionBulkUpdateReport, error)
+}
+
+type questionBankService struct {
+	repo           repositories.QuestionBankRepository
+	assessmentRepo repositories.AssessmentRepository
+	auditService   AuditLogService
+}
+
+func NewQuestionBankService(repo repositories.QuestionBankRepository, assessmentRepo repositories.AssessmentRepository, auditService AuditLogService) QuestionBankService {
+	return &questionBankService{repo: repo, assessmentRepo: assessmentRepo, auditService: auditService}
+}
+
+// RetireQuestions retires the live entries matching filter. When that would leave a live
+// assessment's rule with fewer entries than it samples, nothing is retired and the report
+// is returned with ErrRetirementUnderstocks, unless force is set.
+func (s *questionBankService) RetireQuestions(ctx context.Context, filter bson.M, force bool, retiredBy primitive.ObjectID) (*models.RetirementReport, error) {
+	matched, err := s.repo.CountByFilter(ctx, repositories.LiveQuestionFilter(copyFilter(filter)))
+	if err != nil {
+		return nil, err
+	}
+	report := &models.RetirementReport{Matched: matched, UnderstockedRules: []models.UnderstockedRule{}}
+	if matched == 0 {
+		return report, nil
+	}
+
+	report.UnderstockedRules, err = s.understockedRules(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	assessments := map[primitive.ObjectID]bool{}
+	for _, rule := range report.UnderstockedRules {
+		assessments[rule.AssessmentID] = true
+	}
+	report.UnderstockedAssessments = len(assessments)
+	if report.UnderstockedAssessments > 0 && !force {
+		return report, ErrRetirementUnderstocks
+	}
+
+	report.Retired, err = s.repo.Retire(ctx, copyFilter(filter), retiredBy)
+	s.recordRetirement(ctx, "RETIRE_QUESTIONS", filter, report.Retired, retiredBy, err)
+	return report, err
+}
+
+func (s *questionBankService) RestoreQuestions(ctx context.Context, filter bson.M, restoredBy primitive.ObjectID) (int64, error) {
+	restored, err := s.repo.Restore(ctx, copyFilter(filter))
+	s.recordRetirement(ctx, "RESTORE_QUESTIONS", filter, restored, restoredBy, err)
+	return restored, err
+}
+
+// recordRetirement writes the audit entry of a retire or restore, which change no content
+// and so are not recorded as versions.
+func (s *questionBankService) recordRetirement(ctx context.Context, action string, filter bson.M, count int64, authorID primitive.ObjectID, err error) {
+	entityID, _ := filter["_id"].(primitive.ObjectID)
+	metadata := map[string]interface{}{"filter": filter, "count": count}
+	if err != nil {
+		s.auditService.RecordAction(ctx, authorID, "", action, "QUESTION_BANK", entityID, "ERROR", "Question retirement change failed", err.Error(), metadata)
+		return
+	}
+	s.auditService.RecordAction(ctx, authorID, "", action, "QUESTION_BANK", entityID, "SUCCESS", fmt.Sprintf("%d questions affected", count), "", metadata)
+}
+
+// understockedRules finds the rules of open assessments whose slot loses entries to the
+// retirement and ends up with fewer sampleable entries than the rule samples.
+func (s *questionBankService) understockedRules(ctx context.Context, retiring bson.M) ([]models.UnderstockedRule, error) {
+	now := time.Now()
+	assessments, err := s.assessmentRepo.FindAll(ctx, bson.M{
+		"deleted_at": nil,
+		"$or":        bson.A{bson.M{"closes_at": nil}, bson.M{"closes_at": bson.M{"$gt": now}}},
+	}, options.Find().SetProjection(bson.M{"title": 1, "question_rules": 1, "mode": 1, "adaptive": 1}))
+	if err != nil {
+		return nil, err
+	}
+
+	type stock struct{ before, after int64 }
+	stocks := map[string]stock{}
+	slotStock := func(slot bson.M) (stock, error) {
+		key := fmt.Sprint(slot)
+		if st, ok := stocks[key]; ok {
+			return st, nil
+		}
+		before, err := s.repo.CountByFilter(ctx, repositories.SampleableQuestionFilter(copyFilter(slot)))
+		if err != nil {
+			return stock{}, err
+		}
+		after := before
+		if before > 0 {
+			remaining := repositories.SampleableQuestionFilter(bson.M{"$and": bson.A{slot, bson.M{"$nor": bson.A{retiring}}}})
+			if after, err = s.repo.CountByFilter(ctx, remaining); err != nil {
+				return stock{}, err
+			}
+		}
+		stocks[key] = stock{before, after}
+		return stocks[key], nil
+	}
+
+	understocked := []models.UnderstockedRule{}
+	check := func(a models.Assessment, slot bson.M, rule models.UnderstockedRule) error {
+		st, err := slotStock(slot)
+		if err != nil {
+			return err
+		}
+		if st.after < st.before && st.after < int64(rule.Required) {
+			rule.AssessmentID = a.ID
+			rule.AssessmentTitle = a.Title
+			rule.Available = st.after
+			understocked = append(understocked, rule)
+		}
+		return nil
+	}
+
+	for _, a := range assessments {
+		if isAdaptive(&a) {
+			cfg := adaptiveSettings(&a)
+			slot := bson.M{"category": cfg.Category, "type": models.MultipleChoice}
+			if cfg.SubCategory != "" {
+				slot["sub_category"] = cfg.SubCategory
+			}
+			rule := models.UnderstockedRule{Category: cfg.Category, SubCategory: cfg.SubCategory, Required: cfg.MaxItems}
+			if err := check(a, slot, rule); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		for _, r := range a.QuestionRules {
+			slot := ruleSlotFilter(r)
+			rule := models.UnderstockedRule{Category: r.Category, SubCategory: r.SubCategory, Difficulty: r.Difficulty, Required: r.Count}
+			if err := check(a, slot, rule); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return understocked, nil
+}
+
+// ReviewQuestion applies a review action. Rejecting or commenting needs a comment so the
+// author knows what to fix.
+func (s *questionBankService) ReviewQuestion(ctx context.Context, id primitive.ObjectID, action, comment string, reviewerID primitive.ObjectID) (*models.QuestionBankEntry, error) {
+	comment = strings.TrimSpace(comment)
+	if (action == models.ReviewActionReject || action == models.ReviewActionComment) && comment == "" {
+		return nil, ErrReviewCommentRequired
+	}
+
+	current, err := s.repo.FindByID(ctx, id)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrQuestionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	status := current.ReviewStatus
+	if status == "" {
+		status = models.ReviewStatusApproved
+	}
+
+	var from []string
+	to := status
+	if action == models.ReviewActionComment {
+		from = []string{status}
+	} else {
+		transition, ok := reviewTransitions[action]
+		if !ok {
+			return nil, ErrInvalidReviewAction
+		}
+		from, to = transition.from, transition.to
+	}
+
+	review := models.ReviewComment{
+		Action:    action,
+		Comment:   comment,
+		Status:    to,
+		AuthorID:  reviewerID,
+		CreatedAt: time.Now(),
+	}
+	// The status condition makes two reviewers acting at once safe: only one transition applies.
+	updated, err := s.repo.Review(ctx, id, from, review)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, ErrReviewTransition
+	}
+	return s.repo.FindByID(ctx, id)
+}
+
+// ReviewQueue lists live entries awaiting review, oldest first, so nothing waits forever.
+func (s *questionBankService) ReviewQueue(ctx context.Context, filter bson.M, page, limit int) ([]models.QuestionBankEntry, int64, error) {
+	filter = repositories.LiveQuestionFilter(copyFilter(filter))
+	if _, ok := filter["review_status"]; !ok {
+		filter["review_status"] = models.ReviewStatusInReview
+	}
+
+	total, err := s.repo.CountByFilter(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "updated_at", Value: 1}, {Key: "_id", Value: 1}}).
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit))
+	entries, err := s.repo.Find(ctx, filter, opts)
+	if entries == nil {
+		entries = []models.QuestionBankEntry{}
+	}
+	return entries, total, err
+}
+
+// copyFilter lets repository helpers add conditions without changing the caller's filter.
+func copyFilter(filter bson.M) bson.M {
+	copied := make(bson.M, len(filter)+1)
+	for k, v := range filter {
+		copied[k] = v
+	}
+	return copied
+}
@@ -0,0 +1,223 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"hireit-backend/models"
+	"hireit-backend/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var ErrInvalidBulkUpdate = errors.New("invalid bulk update")
+
+// BulkUpdateQuestions applies changes to every entry matching filter in a single update.
+// With dryRun nothing is written and the report only previews how many entries match. Each
+// updated entry gets a new version, approved entries whose type or category changes go
+// back to review, and the edit is recorded in the audit log.
+func (s *questionBankService) BulkUpdateQuestions(ctx context.Context, filter bson.M, changes models.QuestionBulkChanges, dryRun bool, authorID primitive.ObjectID) (*models.QuestionBulkUpdateReport, error) {
+	set, unset, err := s.bulkUpdateFields(ctx, &changes)
+	if err != nil {
+		return nil, err
+	}
+
+	matched, err := s.repo.CountByFilter(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if changes.Type != nil && *changes.Type == models.MultipleChoice {
+		// Only entries that already have options and an answer can become MCQs
+		incomplete := copyFilter(filter)
+		incomplete["$or"] = []bson.M{
+			{"options.1": bson.M{"$exists": false}},
+			{"correct_answer": bson.M{"$in": []interface{}{"", nil}}},
+		}
+		n, err := s.repo.CountByFilter(ctx, incomplete)
+		if err != nil {
+			return nil, err
+		}
+		if n > 0 {
+			return nil, fmt.Errorf("%w: %d of the matching questions lack the options or correct answer of an MCQ", ErrInvalidBulkUpdate, n)
+		}
+	}
+
+	report := &models.QuestionBulkUpdateReport{Matched: matched, DryRun: dryRun}
+	if dryRun || matched == 0 {
+		return report, nil
+	}
+
+	// Values are wrapped in $literal so that a name starting with "$" is not read as a
+	// field path by the pipeline
+	stage := bson.M{"updated_at": time.Now()}
+	for field, value := range set {
+		stage[field] = bson.M{"$literal": value}
+	}
+	if !authorID.IsZero() {
+		stage["updated_by"] = authorID
+	}
+	if len(changes.AddTags) > 0 || len(changes.RemoveTags) > 0 {
+		stage["tags"] = bson.M{"$setUnion": bson.A{
+			bson.M{"$setDifference": bson.A{bson.M{"$ifNull": bson.A{"$tags", bson.A{}}}, bson.M{"$literal": changes.RemoveTags}}},
+			bson.M{"$literal": changes.AddTags},
+		}}
+	}
+	// A new type or category changes what an entry assesses, so it needs another review
+	var relabelled bson.A
+	for _, field := range []string{"type", "category", "sub_category"} {
+		if value, ok := set[field]; ok {
+			relabelled = append(relabelled, bson.M{"$ne": bson.A{bson.M{"$ifNull": bson.A{"$" + field, ""}}, bson.M{"$literal": value}}})
+		}
+	}
+	for _, field := range unset {
+		if field == "sub_category" {
+			relabelled = append(relabelled, bson.M{"$ne": bson.A{bson.M{"$ifNull": bson.A{"$sub_category", ""}}, ""}})
+		}
+	}
+	if len(relabelled) > 0 {
+		stage["review_status"] = bson.M{"$cond": bson.A{
+			bson.M{"$and": bson.A{
+				bson.M{"$eq": bson.A{bson.M{"$ifNull": bson.A{"$review_status", models.ReviewStatusApproved}}, models.ReviewStatusApproved}},
+				bson.M{"$or": relabelled},
+			}},
+			models.ReviewStatusInReview,
+			"$review_status",
+		}}
+	}
+	pipeline := bson.A{bson.M{"$set": stage}}
+	if len(unset) > 0 {
+		pipeline = append(pipeline, bson.M{"$unset": unset})
+	}
+
+	metadata := map[string]interface{}{
+		"filter":      filter,
+		"set":         set,
+		"unset":       unset,
+		"add_tags":    changes.AddTags,
+		"remove_tags": changes.RemoveTags,
+		"matched":     matched,
+	}
+	report.Matched, report.Modified, err = s.repo.UpdateVersioned(ctx, filter, pipeline, authorID)
+	if err != nil {
+		s.auditService.RecordAction(ctx, authorID, "", "BULK_UPDATE_QUESTIONS", "QUESTION_BANK", primitive.NilObjectID, "ERROR", "Bulk question update failed", err.Error(), metadata)
+		return nil, err
+	}
+	metadata["modified"] = report.Modified
+	s.auditService.RecordAction(ctx, authorID, "", "BULK_UPDATE_QUESTIONS", "QUESTION_BANK", primitive.NilObjectID, "SUCCESS", fmt.Sprintf("%d of %d questions updated", report.Modified, report.Matched), "", metadata)
+	return report, nil
+}
+
+// bulkUpdateFields validates changes against the bank structure and returns the fields to
+// set and to remove. Tags are normalised in place.
+func (s *questionBankService) bulkUpdateFields(ctx context.Context, changes *models.QuestionBulkChanges) (bson.M, []string, error) {
+	set := bson.M{}
+	var unset []string
+	required := func(field string, value *string) error {
+		if value == nil {
+			return nil
+		}
+		if *value = strings.TrimSpace(*value); *value == "" {
+			return fmt.Errorf("%w: %s cannot be empty", ErrInvalidBulkUpdate, field)
+		}
+		set[field] = *value
+		return nil
+	}
+	optional := func(field string, value *string) {
+		if value == nil {
+			return
+		}
+		if *value = strings.TrimSpace(*value); *value == "" {
+			unset = append(unset, field)
+		} else {
+			set[field] = *value
+		}
+	}
+
+	if err := required("category", changes.Category); err != nil {
+		return nil, nil, err
+	}
+	if err := required("difficulty", changes.Difficulty); err != nil {
+		return nil, nil, err
+	}
+	optional("sub_category", changes.SubCategory)
+	optional("audio_url", changes.AudioURL)
+	if changes.Type != nil {
+		switch *changes.Type {
+		case models.MultipleChoice, models.Coding, models.Subjective:
+			set["type"] = *changes.Type
+		default:
+			return nil, nil, fmt.Errorf("%w: unknown question type %q", ErrInvalidBulkUpdate, *changes.Type)
+		}
+	}
+
+	if changes.Tags != nil {
+		if len(changes.AddTags) > 0 || len(changes.RemoveTags) > 0 {
+			return nil, nil, fmt.Errorf("%w: tags replaces the tags and cannot be combined with add_tags or remove_tags", ErrInvalidBulkUpdate)
+		}
+		if tags := utils.NormalizeTags(changes.Tags); len(tags) > 0 {
+			set["tags"] = tags
+		} else {
+			unset = append(unset, "tags")
+		}
+	}
+	changes.AddTags = utils.NormalizeTags(changes.AddTags)
+	changes.RemoveTags = utils.NormalizeTags(changes.RemoveTags)
+
+	if len(set) == 0 && len(unset) == 0 && len(changes.AddTags) == 0 && len(changes.RemoveTags) == 0 {
+		return nil, nil, fmt.Errorf("%w: no changes given", ErrInvalidBulkUpdate)
+	}
+	if changes.SubCategory != nil && *changes.SubCategory != "" && changes.Category == nil {
+		return nil, nil, fmt.Errorf("%w: a sub-category can only be set together with its category", ErrInvalidBulkUpdate)
+	}
+
+	if changes.Category == nil {
+		return set, unset, nil
+	}
+	config, err := s.repo.GetBankConfig(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if config == nil || len(config.Categories) == 0 {
+		return set, unset, nil
+	}
+
+	// The entries have to land in a configured slot
+	ci := findCategoryConfig(config, *changes.Category)
+	if ci < 0 {
+		return nil, nil, fmt.Errorf("%w: unknown category %q", ErrInvalidBulkUpdate, *changes.Category)
+	}
+	category := &config.Categories[ci]
+	difficulties := category.Difficulties
+	if category.HasSubCategories {
+		if changes.SubCategory == nil || *changes.SubCategory == "" {
+			return nil, nil, fmt.Errorf("%w: %q has sub-categories; choose one", ErrInvalidBulkUpdate, category.Name)
+		}
+		si := findSubCategoryConfig(category, *changes.SubCategory)
+		if si < 0 {
+			return nil, nil, fmt.Errorf("%w: unknown sub-category %q in %q", ErrInvalidBulkUpdate, *changes.SubCategory, category.Name)
+		}
+		difficulties = category.SubCategories[si].Difficulties
+	} else {
+		if changes.SubCategory != nil && *changes.SubCategory != "" {
+			return nil, nil, fmt.Errorf("%w: %q has no sub-categories", ErrInvalidBulkUpdate, category.Name)
+		}
+		// Entries moved into a flat category drop their sub-category
+		if changes.SubCategory == nil {
+			unset = append(unset, "sub_category")
+		}
+	}
+	if changes.Difficulty != nil {
+		known := false
+		for _, d := range difficulties {
+			known = known || d.Difficulty == *changes.Difficulty
+		}
+		if !known {
+			return nil, nil, fmt.Errorf("%w: difficulty %q is not configured for the target slot", ErrInvalidBulkUpdate, *changes.Difficulty)
+		}
+	}
+	return set, unset, nil
+}
@@ -0,0 +1,209 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"hireit-backend/models"
+	"hireit-backend/repositories"
+	"hireit-backend/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// Three-character shingles keep a lightly reworded short question well above the
+	// threshold while different questions built on the same stem stay below it.
+	dedupeShingleSize = 3
+	// DefaultNearDuplicateThreshold is the Jaccard similarity from which two questions are
+	// reported as near-duplicates.
+	DefaultNearDuplicateThreshold = 0.6
+	maxImportDuplicateMatches     = 3
+)
+
+// dedupeCandidate is an entry prepared for similarity comparison
+type dedupeCandidate struct {
+	match    models.DuplicateMatch
+	shingles map[string]struct{}
+}
+
+func newDedupeCandidate(entry *models.QuestionBankEntry) dedupeCandidate {
+	return dedupeCandidate{
+		match:    models.DuplicateMatch{ID: entry.ID, Category: entry.Category, Text: entry.Text},
+		shingles: utils.Shingles(dedupeText(entry), dedupeShingleSize),
+	}
+}
+
+// dedupeText is what near-duplicate detection compares. The passage is left out, as every
+// question on a passage would otherwise look alike.
+func dedupeText(entry *models.QuestionBankEntry) string {
+	return entry.Text + " " + strings.Join(entry.Options, " ")
+}
+
+// ImportDeduper checks the questions of one import against the live bank and against the
+// questions imported earlier in the same run.
+type ImportDeduper struct {
+	repo      repositories.QuestionBankRepository
+	threshold float64
+	hashes    map[string]models.DuplicateMatch
+	// Live entries per category, loaded on first use
+	categories map[string][]dedupeCandidate
+}
+
+func (s *questionBankService) NewImportDeduper() *ImportDeduper {
+	return &ImportDeduper{
+		repo:       s.repo,
+		threshold:  DefaultNearDuplicateThreshold,
+		hashes:     map[string]models.DuplicateMatch{},
+		categories: map[string][]dedupeCandidate{},
+	}
+}
+
+// Check returns the entry that entry exactly duplicates, if any, and otherwise the most
+// similar entries of its category at or above the near-duplicate threshold.
+func (d *ImportDeduper) Check(ctx context.Context, entry *models.QuestionBankEntry) (*models.DuplicateMatch, []models.DuplicateMatch, error) {
+	hash := repositories.QuestionContentHash(entry)
+	if match, ok := d.hashes[hash]; ok {
+		return &match, nil, nil
+	}
+	existing, err := d.repo.Find(ctx, repositories.LiveQuestionFilter(bson.M{"content_hash": hash}),
+		options.Find().SetLimit(1).SetProjection(bson.M{"category": 1, "text": 1}))
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(existing) > 0 {
+		match := models.DuplicateMatch{ID: existing[0].ID, Category: existing[0].Category, Text: existing[0].Text, Similarity: 1}
+		return &match, nil, nil
+	}
+
+	candidates, err := d.categoryCandidates(ctx, entry.Category)
+	if err != nil {
+		return nil, nil, err
+	}
+	shingles := utils.Shingles(dedupeText(entry), dedupeShingleSize)
+	near := []models.DuplicateMatch{}
+	for _, candidate := range candidates {
+		if similarity := utils.JaccardSimilarity(shingles, candidate.shingles); similarity >= d.threshold {
+			match := candidate.match
+			match.Similarity = roundTo(similarity, 3)
+			near = append(near, match)
+		}
+	}
+	sort.SliceStable(near, func(i, j int) bool { return near[i].Similarity > near[j].Similarity })
+	if len(near) > maxImportDuplicateMatches {
+		near = near[:maxImportDuplicateMatches]
+	}
+	return nil, near, nil
+}
+
+// Add records an entry created by the import so later rows are checked against it too.
+func (d *ImportDeduper) Add(entry *models.QuestionBankEntry) {
+	candidate := newDedupeCandidate(entry)
+	exact := candidate.match
+	exact.Similarity = 1
+	d.hashes[repositories.QuestionContentHash(entry)] = exact
+	if candidates, loaded := d.categories[entry.Category]; loaded {
+		d.categories[entry.Category] = append(candidates, candidate)
+	}
+}
+
+func (d *ImportDeduper) categoryCandidates(ctx context.Context, category string) ([]dedupeCandidate, error) {
+	if candidates, ok := d.categories[category]; ok {
+		return candidates, nil
+	}
+	entries, err := d.repo.Find(ctx, repositories.LiveQuestionFilter(bson.M{"category": category}),
+		options.Find().SetProjection(bson.M{"category": 1, "text": 1, "options": 1}))
+	if err != nil {
+		return nil, err
+	}
+	candidates := make([]dedupeCandidate, 0, len(entries))
+	for i := range entries {
+		candidates = append(candidates, newDedupeCandidate(&entries[i]))
+	}
+	d.categories[category] = candidates
+	return candidates, nil
+}
+
+// ScanDuplicates groups live entries sharing a content hash and pairs up entries of the
+// same category whose similarity reaches threshold.
+func (s *questionBankService) ScanDuplicates(ctx context.Context, filter bson.M, threshold float64) (*models.DuplicateScanReport, error) {
+	entries, err := s.repo.Find(ctx, repositories.LiveQuestionFilter(copyFilter(filter)),
+		options.Find().
+			SetProjection(bson.M{"category": 1, "text": 1, "options": 1, "passage_text": 1, "media": 1, "content_hash": 1}).
+			SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.DuplicateScanReport{
+		Scanned:        len(entries),
+		Threshold:      threshold,
+		ExactGroups:    [][]models.DuplicateMatch{},
+		NearDuplicates: []models.NearDuplicatePair{},
+	}
+
+	byHash := map[string][]models.DuplicateMatch{}
+	var hashes []string
+	byCategory := map[string][]dedupeCandidate{}
+	for i := range entries {
+		entry := &entries[i]
+		hash := entry.ContentHash
+		if hash == "" {
+			hash = repositories.QuestionContentHash(entry)
+		}
+		if _, seen := byHash[hash]; !seen {
+			hashes = append(hashes, hash)
+		}
+		byHash[hash] = append(byHash[hash], models.DuplicateMatch{ID: entry.ID, Category: entry.Category, Text: entry.Text})
+		// Only the first of an exact group takes part in near-duplicate pairing
+		if len(byHash[hash]) == 1 {
+			byCategory[entry.Category] = append(byCategory[entry.Category], newDedupeCandidate(entry))
+		}
+	}
+	for _, hash := range hashes {
+		if len(byHash[hash]) > 1 {
+			report.ExactGroups = append(report.ExactGroups, byHash[hash])
+		}
+	}
+
+	categories := make([]string, 0, len(byCategory))
+	for category := range byCategory {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+	for _, category := range categories {
+		report.NearDuplicates = append(report.NearDuplicates, nearDuplicatePairs(byCategory[category], threshold)...)
+	}
+	sort.SliceStable(report.NearDuplicates, func(i, j int) bool {
+		return report.NearDuplicates[i].Similarity > report.NearDuplicates[j].Similarity
+	})
+	return report, nil
+}
+
+// nearDuplicatePairs compares every pair of candidates. Candidates are sorted by shingle
+// count: once the smaller set is below threshold times the larger one, no later candidate
+// can reach the threshold, which keeps large categories tractable.
+func nearDuplicatePairs(candidates []dedupeCandidate, threshold float64) []models.NearDuplicatePair {
+	sort.SliceStable(candidates, func(i, j int) bool { return len(candidates[i].shingles) < len(candidates[j].shingles) })
+
+	pairs := []models.NearDuplicatePair{}
+	for i := range candidates {
+		for j := i + 1; j < len(candidates); j++ {
+			if float64(len(candidates[i].shingles)) < threshold*float64(len(candidates[j].shingles)) {
+				break
+			}
+			similarity := utils.JaccardSimilarity(candidates[i].shingles, candidates[j].shingles)
+			if similarity < threshold {
+				continue
+			}
+			first, second := candidates[i].match, candidates[j].match
+			if second.ID.Hex() < first.ID.Hex() {
+				first, second = second, first
+			}
+			pairs = append(pairs, models.NearDuplicatePair{Similarity: roundTo(similarity, 3), First: first, Second: second})
+		}
+	}
+	return pairs
+}
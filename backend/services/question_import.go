@@ -0,0 +1,298 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"hireit-backend/models"
+	"hireit-backend/repositories"
+	"hireit-backend/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	stagedImportTTL         = 30 * time.Minute
+	stagedImportCachePrefix = "question_import:"
+	importPreviewLimit      = 100
+)
+
+var ErrStagedImportNotFound = errors.New("import not found or expired; run the dry run again")
+
+// confirmImportMu makes confirming a staged import a one-shot operation.
+var confirmImportMu sync.Mutex
+
+// ImportRow is one question parsed from an upload, numbered as in the file. Error is set
+// when the row could not be read at all.
+type ImportRow struct {
+	Row   int
+	Entry *models.QuestionBankEntry
+	Error string
+}
+
+// stagedImport holds the valid rows of a dry run until they are confirmed
+type stagedImport struct {
+	rows    []int
+	entries []models.QuestionBankEntry
+}
+
+// ImportResult is the outcome of confirming a staged import
+type ImportResult struct {
+	ImportedCount int                      `json:"imported_count"`
+	Duplicates    []models.ImportDuplicate `json:"duplicates"` // Rows that were added to the bank since the dry run
+}
+
+// StageImport validates every row and keeps the valid ones for ConfirmImport. The report
+// lists every error per row, not just the first few.
+func (s *questionBankService) StageImport(ctx context.Context, rows []ImportRow) (*models.ImportValidationReport, error) {
+	config, err := s.repo.GetBankConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.ImportValidationReport{
+		TotalRows: len(rows),
+		Errors:    []models.ImportIssue{},
+		Warnings:  []models.ImportIssue{},
+		Preview:   []models.QuestionBankEntry{},
+	}
+	staged := &stagedImport{}
+	deduper := s.NewImportDeduper()
+
+	for _, row := range rows {
+		if row.Error != "" {
+			report.Errors = append(report.Errors, models.ImportIssue{Row: row.Row, Message: row.Error})
+			report.InvalidRows++
+			continue
+		}
+
+		entry := row.Entry
+		issues, warnings := ValidateImportEntry(entry, config)
+		for i := range issues {
+			issues[i].Row = row.Row
+		}
+		for i := range warnings {
+			warnings[i].Row = row.Row
+		}
+		if len(issues) == 0 {
+			exact, near, err := deduper.Check(ctx, entry)
+			if err != nil {
+				return nil, err
+			}
+			if exact != nil {
+				issues = append(issues, models.ImportIssue{Row: row.Row, Message: fmt.Sprintf("duplicate of question %s: %q", exact.ID.Hex(), exact.Text)})
+			}
+			for _, match := range near {
+				warnings = append(warnings, models.ImportIssue{Row: row.Row, Message: fmt.Sprintf("%.0f%% similar to question %s: %q", match.Similarity*100, match.ID.Hex(), match.Text)})
+			}
+		}
+
+		report.Warnings = append(report.Warnings, warnings...)
+		if len(issues) > 0 {
+			report.Errors = append(report.Errors, issues...)
+			report.InvalidRows++
+			continue
+		}
+
+		if entry.ID.IsZero() {
+			entry.ID = primitive.NewObjectID()
+		}
+		deduper.Add(entry)
+		staged.rows = append(staged.rows, row.Row)
+		staged.entries = append(staged.entries, *entry)
+		if len(report.Preview) < importPreviewLimit {
+			report.Preview = append(report.Preview, *entry)
+		}
+	}
+	report.ValidRows = len(staged.entries)
+
+	if report.ValidRows > 0 {
+		expiresAt := time.Now().Add(stagedImportTTL)
+		report.ImportToken = utils.GenerateRandomString(16)
+		report.ExpiresAt = &expiresAt
+		utils.GetCache().Set(stagedImportCachePrefix+report.ImportToken, staged, stagedImportTTL)
+	}
+	return report, nil
+}
+
+// ConfirmImport stores the valid rows of a dry run in one batch, atomically where the
+// server supports transactions (see CreateMany). Rows that became exact duplicates since
+// the dry run are skipped. A token can be confirmed once.
+func (s *questionBankService) ConfirmImport(ctx context.Context, token string, authorID primitive.ObjectID) (*ImportResult, error) {
+	confirmImportMu.Lock()
+	cached, ok := utils.GetCache().Get(stagedImportCachePrefix + token)
+	if ok {
+		utils.GetCache().Delete(stagedImportCachePrefix + token)
+	}
+	confirmImportMu.Unlock()
+	if !ok {
+		return nil, ErrStagedImportNotFound
+	}
+	staged := cached.(*stagedImport)
+
+	hashes := make(bson.A, len(staged.entries))
+	for i := range staged.entries {
+		hashes[i] = repositories.QuestionContentHash(&staged.entries[i])
+	}
+	existing, err := s.repo.Find(ctx, repositories.LiveQuestionFilter(bson.M{"content_hash": bson.M{"$in": hashes}}),
+		options.Find().SetProjection(bson.M{"category": 1, "text": 1, "content_hash": 1}))
+	if err != nil {
+		return nil, err
+	}
+	taken := make(map[string]models.DuplicateMatch, len(existing))
+	for _, entry := range existing {
+		taken[entry.ContentHash] = models.DuplicateMatch{ID: entry.ID, Category: entry.Category, Text: entry.Text, Similarity: 1}
+	}
+
+	result := &ImportResult{Duplicates: []models.ImportDuplicate{}}
+	entries := make([]*models.QuestionBankEntry, 0, len(staged.entries))
+	for i := range staged.entries {
+		entry := &staged.entries[i]
+		if match, ok := taken[hashes[i].(string)]; ok {
+			result.Duplicates = append(result.Duplicates, models.ImportDuplicate{Row: staged.rows[i], Text: entry.Text, Matches: []models.DuplicateMatch{match}})
+			continue
+		}
+		entry.UpdatedBy = authorID
+		entries = append(entries, entry)
+	}
+
+	result.ImportedCount, err = s.repo.CreateMany(ctx, entries)
+	return result, err
+}
+
+// ValidateImportEntry checks an imported entry's fields and, when a bank structure has been
+// saved, that its category, sub-category, difficulty and skills are part of it. An MCQ answer given
+// as an option letter or with different casing is rewritten to the option's exact text,
+// since grading compares answers with the option text.
+func ValidateImportEntry(entry *models.QuestionBankEntry, config *models.QuestionBankConfig) (issues, warnings []models.ImportIssue) {
+	fail := func(field, format string, args ...any) {
+		issues = append(issues, models.ImportIssue{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if strings.TrimSpace(entry.Text) == "" {
+		fail("text", "question text is required")
+	}
+	if entry.Category == "" {
+		fail("category", "category is required")
+	}
+	if entry.Difficulty == "" {
+		fail("difficulty", "difficulty is required")
+	}
+
+	if err := ValidateImportReviewStatus(entry.ReviewStatus); err != nil {
+		fail("review_status", "%v", err)
+	}
+
+	switch entry.Type {
+	case models.MultipleChoice:
+		if len(entry.Options) < 2 {
+			fail("options", "an MCQ needs at least two options")
+		}
+		if entry.CorrectAnswer == "" {
+			fail("correct_answer", "correct answer is required for an MCQ")
+		} else if answer, ok := resolveImportAnswer(entry.CorrectAnswer, entry.Options); !ok {
+			fail("correct_answer", "correct answer %q does not match any option", entry.CorrectAnswer)
+		} else if answer != entry.CorrectAnswer {
+			warnings = append(warnings, models.ImportIssue{Field: "correct_answer", Message: fmt.Sprintf("correct answer %q read as option %q", entry.CorrectAnswer, answer)})
+			entry.CorrectAnswer = answer
+		}
+	case models.Coding, models.Subjective:
+	default:
+		fail("type", "unknown question type %q", entry.Type)
+	}
+
+	if config != nil && len(config.Skills) > 0 {
+		known := skillTaxonomyPaths(config.Skills)
+		for _, skill := range utils.NormalizeSkillPaths(entry.Skills) {
+			if !known[skill] {
+				fail("skills", "unknown skill %q", skill)
+			}
+		}
+	}
+
+	if config == nil || len(config.Categories) == 0 || entry.Category == "" {
+		return issues, warnings
+	}
+	var category *models.CategoryConfig
+	for i := range config.Categories {
+		if strings.EqualFold(config.Categories[i].Name, entry.Category) {
+			category = &config.Categories[i]
+			break
+		}
+	}
+	if category == nil {
+		fail("category", "unknown category %q", entry.Category)
+		return issues, warnings
+	}
+	entry.Category = category.Name
+
+	difficulties := category.Difficulties
+	if category.HasSubCategories {
+		var sub *models.SubCategoryConfig
+		for i := range category.SubCategories {
+			if strings.EqualFold(category.SubCategories[i].Name, entry.SubCategory) {
+				sub = &category.SubCategories[i]
+				break
+			}
+		}
+		if sub == nil {
+			fail("sub_category", "unknown sub-category %q for %s", entry.SubCategory, category.Name)
+			return issues, warnings
+		}
+		entry.SubCategory = sub.Name
+		difficulties = sub.Difficulties
+	}
+
+	if len(difficulties) > 0 && entry.Difficulty != "" {
+		known := false
+		for _, d := range difficulties {
+			if strings.EqualFold(d.Difficulty, entry.Difficulty) {
+				entry.Difficulty = d.Difficulty
+				known = true
+				break
+			}
+		}
+		if !known {
+			fail("difficulty", "difficulty %q is not configured for %s", entry.Difficulty, category.Name)
+		}
+	}
+	return issues, warnings
+}
+
+// ValidateImportReviewStatus checks the review status an imported question keeps. Empty
+// means the question waits for review.
+func ValidateImportReviewStatus(status string) error {
+	switch status {
+	case "", models.ReviewStatusDraft, models.ReviewStatusInReview, models.ReviewStatusApproved, models.ReviewStatusRejected:
+		return nil
+	}
+	return fmt.Errorf("unknown review status %q", status)
+}
+
+// resolveImportAnswer maps an answer to the exact text of the option it refers to, either
+// by text (ignoring case and surrounding space) or by letter (A for the first option).
+func resolveImportAnswer(answer string, options []string) (string, bool) {
+	trimmed := strings.TrimSpace(answer)
+	for _, option := range options {
+		if option == answer {
+			return option, true
+		}
+	}
+	for _, option := range options {
+		if strings.EqualFold(strings.TrimSpace(option), trimmed) {
+			return option, true
+		}
+	}
+	if len(trimmed) == 1 {
+		if idx := int(strings.ToUpper(trimmed)[0] - 'A'); idx >= 0 && idx < len(options) {
+			return options[idx], true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,692 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"hireit-backend/models"
+)
+
+const (
+	QTIVersion21 = "2.1"
+	QTIVersion30 = "3.0"
+
+	// Limits that keep a crafted package from exhausting memory
+	maxQTIFileSize    = 10 << 20
+	maxQTIPackageSize = 100 << 20
+	maxQTIItems       = 5000
+
+	questionBlank = "____" // Stands for a gap in a missing-word question
+	// The extended-text format used for coding questions; 2.1 spells it preFormatted
+	qtiPreformattedFormat = "preformatted"
+)
+
+var (
+	ErrInvalidQTIPackage     = errors.New("invalid QTI package")
+	ErrUnsupportedQTIVersion = errors.New("QTI version must be 2.1 or 3.0")
+	qtiSupportedInteractions = map[string]bool{"choiceinteraction": true, "textentryinteraction": true, "extendedtextinteraction": true}
+	qtiBlockElements         = map[string]bool{"p": true, "div": true, "br": true, "li": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true, "pre": true, "blockquote": true, "tr": true, "table": true, "ul": true, "ol": true}
+	qtiIgnoredBodyElements   = map[string]bool{"feedbackinline": true, "feedbackblock": true, "rubricblock": true, "templateinline": true, "templateblock": true}
+)
+
+// qtiNode is a parsed XML element. Names are normalised so QTI 2.1 (choiceInteraction) and
+// 3.0 (qti-choice-interaction) read alike. Character data is kept as children with an
+// empty name, in document order.
+type qtiNode struct {
+	name     string
+	attrs    map[string]string
+	children []*qtiNode
+	text     string
+}
+
+func qtiName(local string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimPrefix(local, "qti-"), "-", ""))
+}
+
+func parseQTIXML(data []byte) (*qtiNode, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Entity = xml.HTMLEntity
+
+	var root *qtiNode
+	var stack []*qtiNode
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			node := &qtiNode{name: qtiName(t.Name.Local), attrs: map[string]string{}}
+			for _, attr := range t.Attr {
+				node.attrs[qtiName(attr.Name.Local)] = attr.Value
+			}
+			if len(stack) == 0 {
+				if root != nil {
+					return nil, errors.New("more than one root element")
+				}
+				root = node
+			} else {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, node)
+			}
+			stack = append(stack, node)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, &qtiNode{text: string(t)})
+			}
+		}
+	}
+	if root == nil {
+		return nil, errors.New("empty document")
+	}
+	return root, nil
+}
+
+func (n *qtiNode) child(name string) *qtiNode {
+	for _, c := range n.children {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// find returns the descendants for which match is true, without descending into them.
+func (n *qtiNode) find(match func(*qtiNode) bool) []*qtiNode {
+	var found []*qtiNode
+	for _, c := range n.children {
+		if c.name == "" {
+			continue
+		}
+		if match(c) {
+			found = append(found, c)
+			continue
+		}
+		found = append(found, c.find(match)...)
+	}
+	return found
+}
+
+func isQTIStimulusDiv(n *qtiNode) bool {
+	return n.name == "div" && strings.Contains(" "+n.attrs["class"]+" ", " stimulus ")
+}
+
+// qtiText flattens an element to plain text, one line per block element. Elements for
+// which skip is true are left out; an inline text entry becomes a blank.
+func qtiText(n *qtiNode, skip func(*qtiNode) bool) string {
+	var b strings.Builder
+	var walk func(*qtiNode)
+	walk = func(node *qtiNode) {
+		for _, c := range node.children {
+			switch {
+			case c.name == "":
+				b.WriteString(c.text)
+			case qtiIgnoredBodyElements[c.name] || (skip != nil && skip(c)):
+			case c.name == "textentryinteraction":
+				b.WriteString(" " + questionBlank + " ")
+			case qtiBlockElements[c.name]:
+				b.WriteString("\n")
+				walk(c)
+				b.WriteString("\n")
+			default:
+				walk(c)
+			}
+		}
+	}
+	walk(n)
+
+	var lines []string
+	for _, line := range strings.Split(b.String(), "\n") {
+		if line = strings.Join(strings.FieldsFunc(line, unicode.IsSpace), " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// qtiItemEntry maps a QTI choice, text-entry or extended-text item to a bank entry. Shared
+// stimuli referenced by the item are read through loadStimulus.
+func qtiItemEntry(item *qtiNode, loadStimulus func(href string) (*qtiNode, error)) (*models.QuestionBankEntry, error) {
+	if item.name != "assessmentitem" {
+		return nil, fmt.Errorf("root element is not an assessment item")
+	}
+	body := item.child("itembody")
+	if body == nil {
+		return nil, errors.New("item has no body")
+	}
+
+	correct := map[string][]string{}
+	for _, declaration := range item.children {
+		if declaration.name != "responsedeclaration" {
+			continue
+		}
+		if response := declaration.child("correctresponse"); response != nil {
+			for _, value := range response.children {
+				if value.name == "value" {
+					correct[declaration.attrs["identifier"]] = append(correct[declaration.attrs["identifier"]], strings.TrimSpace(qtiText(value, nil)))
+				}
+			}
+		}
+	}
+
+	interactions := body.find(func(n *qtiNode) bool { return strings.HasSuffix(n.name, "interaction") })
+	if len(interactions) != 1 {
+		return nil, fmt.Errorf("items must have exactly one interaction, found %d", len(interactions))
+	}
+	interaction := interactions[0]
+	if !qtiSupportedInteractions[interaction.name] {
+		return nil, fmt.Errorf("unsupported interaction %q", interaction.name)
+	}
+	responseValues := correct[interaction.attrs["responseidentifier"]]
+
+	var passages []string
+	entry := &models.QuestionBankEntry{}
+	for _, ref := range item.children {
+		if ref.name != "assessmentstimulusref" {
+			continue
+		}
+		stimulus, err := loadStimulus(ref.attrs["href"])
+		if err != nil {
+			return nil, fmt.Errorf("stimulus %s: %v", ref.attrs["href"], err)
+		}
+		if entry.PassageTitle == "" {
+			entry.PassageTitle = firstNonEmpty(ref.attrs["title"], stimulus.attrs["title"])
+		}
+		if stimulusBody := stimulus.child("stimulusbody"); stimulusBody != nil {
+			passages = append(passages, qtiText(stimulusBody, nil))
+		}
+	}
+	for _, div := range body.find(isQTIStimulusDiv) {
+		if heading := div.find(func(n *qtiNode) bool {
+			return len(n.name) == 2 && n.name[0] == 'h' && n.name[1] >= '1' && n.name[1] <= '6'
+		}); len(heading) > 0 && entry.PassageTitle == "" {
+			entry.PassageTitle = qtiText(heading[0], nil)
+			passages = append(passages, qtiText(div, func(n *qtiNode) bool { return n == heading[0] }))
+		} else {
+			passages = append(passages, qtiText(div, nil))
+		}
+	}
+
+	bodyText := qtiText(body, func(n *qtiNode) bool {
+		return isQTIStimulusDiv(n) || (n != interaction && strings.HasSuffix(n.name, "interaction")) ||
+			(n == interaction && interaction.name != "textentryinteraction")
+	})
+	prompt := ""
+	if p := interaction.child("prompt"); p != nil {
+		prompt = qtiText(p, nil)
+	}
+	if prompt != "" {
+		entry.Text = prompt
+		passages = append(passages, bodyText)
+	} else {
+		entry.Text = bodyText
+	}
+	// An answer box after the question is not a blank within it
+	entry.Text = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(entry.Text), questionBlank))
+
+	switch interaction.name {
+	case "choiceinteraction":
+		// maxChoices defaults to 1; 0 means any number of choices
+		if raw, ok := interaction.attrs["maxchoices"]; ok {
+			if maxChoices, err := strconv.Atoi(strings.TrimSpace(raw)); err != nil || maxChoices != 1 {
+				return nil, errors.New("multiple-response choice items are not supported")
+			}
+		}
+		if len(responseValues) > 1 {
+			return nil, errors.New("multiple-response choice items are not supported")
+		}
+		entry.Type = models.MultipleChoice
+		for _, choice := range interaction.children {
+			if choice.name != "simplechoice" {
+				continue
+			}
+			text := qtiText(choice, nil)
+			entry.Options = append(entry.Options, text)
+			if len(responseValues) > 0 && choice.attrs["identifier"] == responseValues[0] {
+				entry.CorrectAnswer = text
+			}
+		}
+		if entry.CorrectAnswer == "" {
+			return nil, errors.New("choice item has no correct response")
+		}
+	case "textentryinteraction":
+		entry.Type = models.Subjective
+		if len(responseValues) > 0 {
+			entry.CorrectAnswer = responseValues[0]
+		}
+	case "extendedtextinteraction":
+		entry.Type = models.Subjective
+		if strings.EqualFold(interaction.attrs["format"], qtiPreformattedFormat) {
+			entry.Type = models.Coding
+		}
+	}
+
+	var nonEmpty []string
+	for _, passage := range passages {
+		if passage != "" {
+			nonEmpty = append(nonEmpty, passage)
+		}
+	}
+	entry.PassageText = strings.Join(nonEmpty, "\n\n")
+	return entry, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// ReadQTIPackage maps the items of a QTI 2.1 or 3.0 content package, or of a single item
+// XML file, to import rows numbered in manifest order. Items that cannot be mapped are
+// returned as rows with an error.
+func ReadQTIPackage(data []byte) ([]ImportRow, error) {
+	if !bytes.HasPrefix(data, []byte("PK")) {
+		item, err := parseQTIXML(data)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidQTIPackage, err)
+		}
+		entry, err := qtiItemEntry(item, func(string) (*qtiNode, error) {
+			return nil, errors.New("a single item file cannot reference stimuli; upload the content package")
+		})
+		if err != nil {
+			return []ImportRow{{Row: 1, Error: err.Error()}}, nil
+		}
+		return []ImportRow{{Row: 1, Entry: entry}}, nil
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidQTIPackage, err)
+	}
+	files := map[string]*zip.File{}
+	var total uint64
+	for _, f := range archive.File {
+		total += f.UncompressedSize64
+		files[path.Clean(f.Name)] = f
+	}
+	if total > maxQTIPackageSize {
+		return nil, fmt.Errorf("%w: package expands to more than %d MB", ErrInvalidQTIPackage, maxQTIPackageSize>>20)
+	}
+
+	read := func(name string) ([]byte, error) {
+		f, ok := files[path.Clean(name)]
+		if !ok {
+			return nil, fmt.Errorf("%s is missing from the package", name)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		content, err := io.ReadAll(io.LimitReader(rc, maxQTIFileSize+1))
+		if err != nil {
+			return nil, err
+		}
+		if len(content) > maxQTIFileSize {
+			return nil, fmt.Errorf("%s is larger than %d MB", name, maxQTIFileSize>>20)
+		}
+		return content, nil
+	}
+
+	itemPaths, err := qtiItemPaths(files, read)
+	if err != nil {
+		return nil, err
+	}
+	if len(itemPaths) > maxQTIItems {
+		return nil, fmt.Errorf("%w: more than %d items", ErrInvalidQTIPackage, maxQTIItems)
+	}
+
+	stimuli := map[string]*qtiNode{}
+	rows := make([]ImportRow, 0, len(itemPaths))
+	for i, itemPath := range itemPaths {
+		row := ImportRow{Row: i + 1}
+		content, err := read(itemPath)
+		var item *qtiNode
+		if err == nil {
+			item, err = parseQTIXML(content)
+		}
+		if err == nil {
+			row.Entry, err = qtiItemEntry(item, func(href string) (*qtiNode, error) {
+				stimulusPath := path.Join(path.Dir(itemPath), href)
+				if stimulus, ok := stimuli[stimulusPath]; ok {
+					return stimulus, nil
+				}
+				content, err := read(stimulusPath)
+				if err != nil {
+					return nil, err
+				}
+				stimulus, err := parseQTIXML(content)
+				if err != nil {
+					return nil, err
+				}
+				stimuli[stimulusPath] = stimulus
+				return stimulus, nil
+			})
+		}
+		if err != nil {
+			row.Entry = nil
+			row.Error = fmt.Sprintf("%s: %v", itemPath, err)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// qtiItemPaths lists the package's items in manifest order. Without a manifest, every XML
+// file whose root is an assessment item is taken, by name.
+func qtiItemPaths(files map[string]*zip.File, read func(string) ([]byte, error)) ([]string, error) {
+	if _, ok := files["imsmanifest.xml"]; ok {
+		content, err := read("imsmanifest.xml")
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidQTIPackage, err)
+		}
+		manifest, err := parseQTIXML(content)
+		if err != nil {
+			return nil, fmt.Errorf("%w: manifest: %v", ErrInvalidQTIPackage, err)
+		}
+		var paths []string
+		for _, resource := range manifest.find(func(n *qtiNode) bool { return n.name == "resource" }) {
+			if strings.HasPrefix(resource.attrs["type"], "imsqti_item") && resource.attrs["href"] != "" {
+				paths = append(paths, path.Clean(resource.attrs["href"]))
+			}
+		}
+		return paths, nil
+	}
+
+	var names []string
+	for name := range files {
+		if strings.HasSuffix(strings.ToLower(name), ".xml") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var paths []string
+	for _, name := range names {
+		content, err := read(name)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidQTIPackage, err)
+		}
+		if root, err := parseQTIXML(content); err == nil && root.name == "assessmentitem" {
+			paths = append(paths, name)
+		}
+	}
+	return paths, nil
+}
+
+// qtiDialect holds what differs between the QTI versions when writing a package
+type qtiDialect struct {
+	itemNamespace     string
+	manifestNamespace string
+	schema            string
+	schemaVersion     string
+	itemType          string
+	stimulusType      string
+	matchCorrect      string
+	preformatted      string
+	kebab             bool // 3.0 names elements qti-choice-interaction, attributes max-choices
+}
+
+var qtiDialects = map[string]qtiDialect{
+	QTIVersion21: {
+		itemNamespace:     "http://www.imsglobal.org/xsd/imsqti_v2p1",
+		manifestNamespace: "http://www.imsglobal.org/xsd/imscp_v1p1",
+		schema:            "QTIv2.1 Package",
+		schemaVersion:     "1.0.0",
+		itemType:          "imsqti_item_xmlv2p1",
+		matchCorrect:      "http://www.imsglobal.org/question/qti_v2p1/rptemplates/match_correct",
+		preformatted:      "preFormatted",
+	},
+	QTIVersion30: {
+		itemNamespace:     "http://www.imsglobal.org/xsd/imsqtiasi_v3p0",
+		manifestNamespace: "http://www.imsglobal.org/xsd/qti/qtiv3p0/imscp_v1p1",
+		schema:            "QTI Package",
+		schemaVersion:     "3.0.0",
+		itemType:          "imsqti_item_xmlv3p0",
+		stimulusType:      "imsqti_stimulus_xmlv3p0",
+		matchCorrect:      "https://purl.imsglobal.org/spec/qti/v3p0/rptemplates/match_correct.xml",
+		preformatted:      qtiPreformattedFormat,
+		kebab:             true,
+	},
+}
+
+// el and attr turn the 2.1 spelling of a QTI name into the dialect's.
+func (d qtiDialect) el(name string) string {
+	if !d.kebab {
+		return name
+	}
+	return "qti-" + kebabCase(name)
+}
+
+func (d qtiDialect) attr(name string) string {
+	if !d.kebab {
+		return name
+	}
+	return kebabCase(name)
+}
+
+func kebabCase(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('-')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func xmlEscape(s string) string {
+	var b bytes.Buffer
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// xmlParagraphs writes each line of text as a paragraph.
+func xmlParagraphs(text string) string {
+	var b strings.Builder
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			b.WriteString("<p>" + xmlEscape(line) + "</p>")
+		}
+	}
+	return b.String()
+}
+
+type qtiStimulus struct {
+	id    string
+	title string
+	text  string
+}
+
+// WriteQTIPackage writes the entries as a QTI content package. Templated entries have no
+// fixed wording and entries with media would lose it, so both are left out; their count is
+// returned.
+func WriteQTIPackage(w io.Writer, entries []models.QuestionBankEntry, version string) (int, error) {
+	dialect, ok := qtiDialects[version]
+	if !ok {
+		return 0, ErrUnsupportedQTIVersion
+	}
+
+	archive := zip.NewWriter(w)
+	var resources strings.Builder
+	stimuli := map[string]*qtiStimulus{}
+	var stimulusOrder []*qtiStimulus
+	skipped := 0
+
+	for _, entry := range entries {
+		if entry.Template != nil || len(entry.Media) > 0 {
+			skipped++
+			continue
+		}
+
+		var stimulus *qtiStimulus
+		if dialect.stimulusType != "" && entry.PassageText != "" {
+			sum := sha256.Sum256([]byte(entry.PassageTitle + "\x1f" + entry.PassageText))
+			key := hex.EncodeToString(sum[:])[:16]
+			if stimulus = stimuli[key]; stimulus == nil {
+				stimulus = &qtiStimulus{id: "S" + key, title: entry.PassageTitle, text: entry.PassageText}
+				stimuli[key] = stimulus
+				stimulusOrder = append(stimulusOrder, stimulus)
+			}
+		}
+
+		id := "Q" + entry.ID.Hex()
+		href := "items/" + id + ".xml"
+		f, err := archive.Create(href)
+		if err != nil {
+			return skipped, err
+		}
+		if _, err := io.WriteString(f, qtiItemXML(dialect, id, entry, stimulus)); err != nil {
+			return skipped, err
+		}
+
+		fmt.Fprintf(&resources, `<resource identifier="%s" type="%s" href="%s"><file href="%s"/>`, id, dialect.itemType, href, href)
+		if stimulus != nil {
+			fmt.Fprintf(&resources, `<dependency identifierref="%s"/>`, stimulus.id)
+		}
+		resources.WriteString("</resource>\n")
+	}
+
+	for _, stimulus := range stimulusOrder {
+		href := "stimuli/" + stimulus.id + ".xml"
+		f, err := archive.Create(href)
+		if err != nil {
+			return skipped, err
+		}
+		content := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<qti-assessment-stimulus xmlns="%s" identifier="%s" title="%s" xml:lang="en">
+<qti-stimulus-body>%s</qti-stimulus-body>
+</qti-assessment-stimulus>
+`, dialect.itemNamespace, stimulus.id, xmlEscape(stimulus.title), xmlParagraphs(stimulus.text))
+		if _, err := io.WriteString(f, content); err != nil {
+			return skipped, err
+		}
+		fmt.Fprintf(&resources, `<resource identifier="%s" type="%s" href="%s"><file href="%s"/></resource>`+"\n", stimulus.id, dialect.stimulusType, href, href)
+	}
+
+	f, err := archive.Create("imsmanifest.xml")
+	if err != nil {
+		return skipped, err
+	}
+	manifest := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<manifest xmlns="%s" identifier="MANIFEST-%d">
+<metadata><schema>%s</schema><schemaversion>%s</schemaversion></metadata>
+<organizations/>
+<resources>
+%s</resources>
+</manifest>
+`, dialect.manifestNamespace, time.Now().Unix(), dialect.schema, dialect.schemaVersion, resources.String())
+	if _, err := io.WriteString(f, manifest); err != nil {
+		return skipped, err
+	}
+	return skipped, archive.Close()
+}
+
+// qtiItemXML writes an MCQ as a single-choice item, a subjective question with an answer
+// as a text entry, and anything else as extended text (preformatted for coding).
+func qtiItemXML(d qtiDialect, id string, entry models.QuestionBankEntry, stimulus *qtiStimulus) string {
+	var b strings.Builder
+	title := []rune(strings.Join(strings.Fields(entry.Text), " "))
+	if len(title) > 80 {
+		title = append(title[:77], []rune("...")...)
+	}
+	fmt.Fprintf(&b, `<?xml version="1.0" encoding="UTF-8"?>
+<%s xmlns="%s" identifier="%s" title="%s" %s="false" %s="false">
+`, d.el("assessmentItem"), d.itemNamespace, id, xmlEscape(string(title)), d.attr("adaptive"), d.attr("timeDependent"))
+
+	correctLetter := ""
+	isChoice := entry.Type == models.MultipleChoice
+	isTextEntry := !isChoice && entry.Type != models.Coding && entry.CorrectAnswer != ""
+	baseType := "string"
+	correct := entry.CorrectAnswer
+	if isChoice {
+		baseType = "identifier"
+		correct = ""
+		for i, option := range entry.Options {
+			if option == entry.CorrectAnswer && i < 26 {
+				correctLetter = string(rune('A' + i))
+				correct = correctLetter
+				break
+			}
+		}
+	}
+	if !isChoice && !isTextEntry {
+		correct = ""
+	}
+
+	fmt.Fprintf(&b, `<%s identifier="RESPONSE" cardinality="single" %s="%s">`, d.el("responseDeclaration"), d.attr("baseType"), baseType)
+	if correct != "" {
+		fmt.Fprintf(&b, `<%s><%s>%s</%s></%s>`, d.el("correctResponse"), d.el("value"), xmlEscape(correct), d.el("value"), d.el("correctResponse"))
+	}
+	fmt.Fprintf(&b, "</%s>\n", d.el("responseDeclaration"))
+	fmt.Fprintf(&b, `<%s identifier="SCORE" cardinality="single" %s="float"/>`+"\n", d.el("outcomeDeclaration"), d.attr("baseType"))
+	if stimulus != nil {
+		fmt.Fprintf(&b, `<%s identifier="%s" href="../stimuli/%s.xml" title="%s"/>`+"\n", d.el("assessmentStimulusRef"), stimulus.id, stimulus.id, xmlEscape(stimulus.title))
+	}
+
+	fmt.Fprintf(&b, "<%s>", d.el("itemBody"))
+	if stimulus == nil && entry.PassageText != "" {
+		b.WriteString(`<div class="stimulus">`)
+		if entry.PassageTitle != "" {
+			b.WriteString("<h2>" + xmlEscape(entry.PassageTitle) + "</h2>")
+		}
+		b.WriteString(xmlParagraphs(entry.PassageText) + "</div>")
+	}
+
+	prompt := fmt.Sprintf("<%s>%s</%s>", d.el("prompt"), xmlEscape(entry.Text), d.el("prompt"))
+	switch {
+	case isChoice:
+		fmt.Fprintf(&b, `<%s %s="RESPONSE" shuffle="false" %s="1">%s`, d.el("choiceInteraction"), d.attr("responseIdentifier"), d.attr("maxChoices"), prompt)
+		for i, option := range entry.Options {
+			if i >= 26 {
+				break
+			}
+			fmt.Fprintf(&b, `<%s identifier="%c">%s</%s>`, d.el("simpleChoice"), 'A'+i, xmlEscape(option), d.el("simpleChoice"))
+		}
+		fmt.Fprintf(&b, "</%s>", d.el("choiceInteraction"))
+	case isTextEntry:
+		lines := strings.Split(strings.TrimSpace(entry.Text), "\n")
+		b.WriteString(xmlParagraphs(strings.Join(lines[:len(lines)-1], "\n")))
+		fmt.Fprintf(&b, `<p>%s <%s %s="RESPONSE"/></p>`, xmlEscape(lines[len(lines)-1]), d.el("textEntryInteraction"), d.attr("responseIdentifier"))
+	default:
+		format := "plain"
+		if entry.Type == models.Coding {
+			format = d.preformatted
+		}
+		fmt.Fprintf(&b, `<%s %s="RESPONSE" format="%s">%s</%s>`, d.el("extendedTextInteraction"), d.attr("responseIdentifier"), format, prompt, d.el("extendedTextInteraction"))
+	}
+	fmt.Fprintf(&b, "</%s>\n", d.el("itemBody"))
+
+	if correct != "" {
+		fmt.Fprintf(&b, `<%s template="%s"/>`+"\n", d.el("responseProcessing"), d.matchCorrect)
+	}
+	fmt.Fprintf(&b, "</%s>\n", d.el("assessmentItem"))
+	return b.String()
+}
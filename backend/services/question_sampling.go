@@ -106,6 +106,15 @@ func resolveQuestionAudioURL(config *models.QuestionBankConfig, rule models.Ques
 	return entry.AudioURL
 }
 
+// ruleSlotFilter is the bank filter a rule samples from.
+func ruleSlotFilter(rule models.QuestionRule) bson.M {
+	filter := bson.M{"category": rule.Category, "difficulty": rule.Difficulty}
+	if rule.SubCategory != "" {
+		filter["sub_category"] = rule.SubCategory
+	}
+	return repositories.TaggedQuestionFilter(filter, rule.IncludeTags, rule.ExcludeTags, rule.Skills)
+}
+
 func sampleQuestionsForRules(ctx context.Context, qbRepo repositories.QuestionBankRepository, rules []models.QuestionRule) ([]models.Question, error) {
 	config, _ := qbRepo.GetBankConfig(ctx)
 	orderedRules := sortQuestionRules(rules)
@@ -121,22 +130,14 @@ func sampleQuestionsForRules(ctx context.Context, qbRepo repositories.QuestionBa
 	allQuestions := make([]models.Question, 0)
 
 	for index, rule := range orderedRules {
-		filter := bson.M{
-			"category":   rule.Category,
-			"difficulty": rule.Difficulty,
-		}
-		if rule.SubCategory != "" {
-			filter["sub_category"] = rule.SubCategory
-		}
-
-		bankEntries, err := qbRepo.Sample(ctx, filter, rule.Count)
+		bankEntries, err := qbRepo.Sample(ctx, ruleSlotFilter(rule), rule.Count)
 		if err != nil {
 			return nil, err
 		}
 
 		ruleQuestions := make([]models.Question, 0, len(bankEntries))
 		for _, entry := range bankEntries {
-			ruleQuestions = append(ruleQuestions, models.Question{
+			question := models.Question{
 				ID:            entry.ID,
 				Text:          entry.Text,
 				Type:          entry.Type,
@@ -146,7 +147,16 @@ func sampleQuestionsForRules(ctx context.Context, qbRepo repositories.QuestionBa
 				CorrectAnswer: entry.CorrectAnswer,
 				Points:        rule.PointsPerQuestion,
 				AudioURL:      resolveQuestionAudioURL(config, rule, entry),
-			})
+				Media:         entry.Media,
+				SourceVersion: entry.Version,
+			}
+			if entry.Template != nil {
+				// Each candidate gets their own values for templated entries
+				if err := applyTemplate(&question, entry, rng); err != nil {
+					return nil, fmt.Errorf("question %s: %v", entry.ID.Hex(), err)
+				}
+			}
+			ruleQuestions = append(ruleQuestions, question)
 		}
 
 		displayOrder := effectiveDisplayOrder(rule, index+1)
@@ -0,0 +1,219 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"html"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"hireit-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	searchFragmentMaxLen  = 200 // Fields up to this length are highlighted whole
+	searchFragmentContext = 60  // Bytes kept on each side of the first match of a longer field
+	MaxSearchLimit        = 100
+)
+
+var ErrEmptySearchQuery = errors.New("a search query is required")
+
+// searchQuery is what highlighting needs from a $text search string
+type searchQuery struct {
+	stems   map[string]bool // Stems of the plain words
+	phrases [][]string      // Words of each quoted phrase
+}
+
+// SearchQuestions runs a full-text search among the entries matching filter and marks
+// where each hit matched. Quoted phrases match as a whole and words prefixed with a minus
+// sign exclude entries, as in MongoDB's $text syntax.
+func (s *questionBankService) SearchQuestions(ctx context.Context, search string, filter bson.M, page, limit int) ([]models.QuestionSearchHit, int64, error) {
+	search = strings.TrimSpace(search)
+	if search == "" {
+		return nil, 0, ErrEmptySearchQuery
+	}
+	hits, total, err := s.repo.Search(ctx, copyFilter(filter), search, int64((page-1)*limit), int64(limit))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := parseSearchQuery(search)
+	for i := range hits {
+		hits[i].Highlights = highlightQuestion(&hits[i].Question, query)
+	}
+	return hits, total, nil
+}
+
+func parseSearchQuery(search string) searchQuery {
+	query := searchQuery{stems: map[string]bool{}}
+	negated := false
+	for i, part := range strings.Split(search, `"`) {
+		if i%2 == 1 {
+			if words := searchWords(part); len(words) > 0 && !negated {
+				phrase := make([]string, len(words))
+				for j, word := range words {
+					phrase[j] = strings.ToLower(part[word[0]:word[1]])
+				}
+				query.phrases = append(query.phrases, phrase)
+			}
+			continue
+		}
+		for _, field := range strings.Fields(part) {
+			if strings.HasPrefix(field, "-") {
+				continue
+			}
+			for _, word := range searchWords(field) {
+				query.stems[searchStem(field[word[0]:word[1]])] = true
+			}
+		}
+		// A minus sign right before the quote negates the phrase that follows
+		negated = strings.HasSuffix(part, "-")
+	}
+	return query
+}
+
+// searchWords returns the byte ranges of the words (runs of letters and digits) of text.
+func searchWords(text string) [][2]int {
+	var words [][2]int
+	start := -1
+	for i, r := range text {
+		inWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		if inWord && start < 0 {
+			start = i
+		} else if !inWord && start >= 0 {
+			words = append(words, [2]int{start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		words = append(words, [2]int{start, len(text)})
+	}
+	return words
+}
+
+// searchStem is a light suffix stemmer, close enough to MongoDB's for highlighting, so
+// that "goroutines" in a question is marked for a search on "goroutine".
+func searchStem(word string) string {
+	word = strings.ToLower(word)
+	if stem, ok := strings.CutSuffix(word, "ies"); ok && len(stem) >= 3 {
+		return stem + "y"
+	}
+	for _, suffix := range []string{"ing", "ed", "s"} {
+		if stem, ok := strings.CutSuffix(word, suffix); ok && len(stem) >= 3 {
+			word = stem
+			break
+		}
+	}
+	if stem, ok := strings.CutSuffix(word, "e"); ok && len(stem) >= 3 {
+		word = stem
+	}
+	return word
+}
+
+func highlightQuestion(entry *models.QuestionBankEntry, query searchQuery) []models.SearchHighlight {
+	highlights := []models.SearchHighlight{}
+	add := func(field, value string) {
+		if fragment, ok := highlightFragment(value, query); ok {
+			highlights = append(highlights, models.SearchHighlight{Field: field, Fragment: fragment})
+		}
+	}
+	add("text", entry.Text)
+	for i, option := range entry.Options {
+		add("options."+strconv.Itoa(i), option)
+	}
+	add("passage_title", entry.PassageTitle)
+	add("passage_text", entry.PassageText)
+	return highlights
+}
+
+// highlightFragment marks the matches in value. Longer values are cut down to the text
+// around the first match.
+func highlightFragment(value string, query searchQuery) (string, bool) {
+	words := searchWords(value)
+	lower := make([]string, len(words))
+	for i, word := range words {
+		lower[i] = strings.ToLower(value[word[0]:word[1]])
+	}
+
+	// Matched words, as [first, last] word indexes
+	var spans [][2]int
+	for i := range words {
+		if query.stems[searchStem(lower[i])] {
+			spans = append(spans, [2]int{i, i})
+		}
+	}
+	for _, phrase := range query.phrases {
+		for i := 0; i+len(phrase) <= len(words); i++ {
+			matched := true
+			for j, word := range phrase {
+				if lower[i+j] != word {
+					matched = false
+					break
+				}
+			}
+			if matched {
+				spans = append(spans, [2]int{i, i + len(phrase) - 1})
+			}
+		}
+	}
+	if len(spans) == 0 {
+		return "", false
+	}
+
+	// Mark each word once, joining overlapping and adjacent matches
+	marked := make([]bool, len(words))
+	for _, span := range spans {
+		for i := span[0]; i <= span[1]; i++ {
+			marked[i] = true
+		}
+	}
+	var ranges [][2]int
+	for i := 0; i < len(words); i++ {
+		if !marked[i] {
+			continue
+		}
+		j := i
+		for j+1 < len(words) && marked[j+1] && strings.TrimSpace(value[words[j][1]:words[j+1][0]]) == "" {
+			j++
+		}
+		ranges = append(ranges, [2]int{words[i][0], words[j][1]})
+		i = j
+	}
+
+	start, end := 0, len(value)
+	if len(value) > searchFragmentMaxLen {
+		start = max(0, ranges[0][0]-searchFragmentContext)
+		end = min(len(value), ranges[0][1]+searchFragmentContext)
+		for start > 0 && !utf8.RuneStart(value[start]) {
+			start++
+		}
+		for end < len(value) && !utf8.RuneStart(value[end]) {
+			end--
+		}
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	pos := start
+	for _, r := range ranges {
+		if r[0] < start || r[1] > end {
+			continue
+		}
+		b.WriteString(html.EscapeString(value[pos:r[0]]))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(value[r[0]:r[1]]))
+		b.WriteString("</mark>")
+		pos = r[1]
+	}
+	b.WriteString(html.EscapeString(value[pos:end]))
+	if end < len(value) {
+		b.WriteString("…")
+	}
+	return b.String(), true
+}
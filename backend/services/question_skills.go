@@ -0,0 +1,50 @@
+package services
+
+import (
+	"hireit-backend/models"
+	"hireit-backend/utils"
+)
+
+// skillTaxonomyPaths lists the path of every skill in the taxonomy.
+func skillTaxonomyPaths(skills []models.SkillConfig) map[string]bool {
+	paths := map[string]bool{}
+	var walk func(prefix string, nodes []models.SkillConfig)
+	walk = func(prefix string, nodes []models.SkillConfig) {
+		for _, node := range nodes {
+			path := utils.NormalizeSkillPath(prefix + "/" + node.Name)
+			if path == "" {
+				continue
+			}
+			paths[path] = true
+			walk(path, node.Children)
+		}
+	}
+	walk("", skills)
+	return paths
+}
+
+// mergeSkillTaxonomy adds the skills of from that into lacks, matching names as normalised
+// skill path segments, and returns the paths of the skills added.
+func mergeSkillTaxonomy(into *[]models.SkillConfig, from []models.SkillConfig, prefix string) []string {
+	var added []string
+	for _, node := range from {
+		path := utils.NormalizeSkillPath(prefix + "/" + node.Name)
+		if path == "" {
+			continue
+		}
+		var target *models.SkillConfig
+		for i := range *into {
+			if utils.NormalizeSkillPath(prefix+"/"+(*into)[i].Name) == path {
+				target = &(*into)[i]
+				break
+			}
+		}
+		if target == nil {
+			*into = append(*into, node)
+			added = append(added, path)
+			continue
+		}
+		added = append(added, mergeSkillTaxonomy(&target.Children, node.Children, path)...)
+	}
+	return added
+}
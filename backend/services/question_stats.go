@@ -0,0 +1,147 @@
+package services
+
+import (
+	"context"
+
+	"hireit-backend/models"
+)
+
+// DefaultMinSampleable is the number of sampleable entries below which a configured slot
+// is reported as a coverage gap.
+const DefaultMinSampleable = 1
+
+// BankStats rolls the cached bank counts up per category, sub-category, difficulty and
+// type, and compares them with the configured bank structure.
+func (s *questionBankService) BankStats(ctx context.Context, minSampleable int) (*models.QuestionBankStats, error) {
+	counts, err := s.repo.Counts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	config, err := s.repo.GetBankConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &models.QuestionBankStats{
+		Retired:       counts.Retired,
+		Categories:    []models.CategoryStats{},
+		Tags:          counts.Tags,
+		MinSampleable: minSampleable,
+		CoverageGaps:  []models.CoverageGap{},
+		ComputedAt:    counts.ComputedAt,
+	}
+
+	// Slots are sorted by category and sub-category, so each one extends the last
+	// category and sub-category or starts a new one
+	byDifficulty, byType := []models.CountBreakdown{}, []models.CountBreakdown{}
+	categoryDifficulties := map[string][]models.CountBreakdown{}
+	categoryTypes := map[string][]models.CountBreakdown{}
+	type slotKey struct{ category, subCategory, difficulty string }
+	slots := map[slotKey]models.CoverageGap{}
+	var slotOrder []slotKey
+	for _, slot := range counts.Slots {
+		stats.Live += slot.Live
+		stats.Sampleable += slot.Sampleable
+		byDifficulty = addBreakdown(byDifficulty, slot.Difficulty, slot)
+		byType = addBreakdown(byType, string(slot.Type), slot)
+
+		if n := len(stats.Categories); n == 0 || stats.Categories[n-1].Name != slot.Category {
+			stats.Categories = append(stats.Categories, models.CategoryStats{Name: slot.Category, SubCategories: []models.SubCategoryStats{}})
+		}
+		category := &stats.Categories[len(stats.Categories)-1]
+		category.Live += slot.Live
+		category.Sampleable += slot.Sampleable
+		categoryDifficulties[slot.Category] = addBreakdown(categoryDifficulties[slot.Category], slot.Difficulty, slot)
+		categoryTypes[slot.Category] = addBreakdown(categoryTypes[slot.Category], string(slot.Type), slot)
+
+		if slot.SubCategory != "" {
+			if n := len(category.SubCategories); n == 0 || category.SubCategories[n-1].Name != slot.SubCategory {
+				category.SubCategories = append(category.SubCategories, models.SubCategoryStats{Name: slot.SubCategory})
+			}
+			sub := &category.SubCategories[len(category.SubCategories)-1]
+			sub.Live += slot.Live
+			sub.Sampleable += slot.Sampleable
+			sub.ByDifficulty = addBreakdown(sub.ByDifficulty, slot.Difficulty, slot)
+			sub.ByType = addBreakdown(sub.ByType, string(slot.Type), slot)
+		}
+
+		key := slotKey{slot.Category, slot.SubCategory, slot.Difficulty}
+		gap, seen := slots[key]
+		if !seen {
+			slotOrder = append(slotOrder, key)
+			gap = models.CoverageGap{Category: slot.Category, SubCategory: slot.SubCategory, Difficulty: slot.Difficulty}
+		}
+		gap.Live += slot.Live
+		gap.Sampleable += slot.Sampleable
+		slots[key] = gap
+	}
+	stats.ByDifficulty = byDifficulty
+	stats.ByType = byType
+
+	passageGroups := map[slotKey]int64{}
+	for _, passage := range counts.PassageGroups {
+		stats.PassageGroups += passage.Groups
+		passageGroups[slotKey{passage.Category, passage.SubCategory, ""}] += passage.Groups
+		if passage.SubCategory != "" {
+			passageGroups[slotKey{passage.Category, "", ""}] += passage.Groups
+		}
+	}
+	for i := range stats.Categories {
+		category := &stats.Categories[i]
+		category.ByDifficulty = categoryDifficulties[category.Name]
+		category.ByType = categoryTypes[category.Name]
+		category.PassageGroups = passageGroups[slotKey{category.Name, "", ""}]
+		for j := range category.SubCategories {
+			category.SubCategories[j].PassageGroups = passageGroups[slotKey{category.Name, category.SubCategories[j].Name, ""}]
+		}
+	}
+
+	if config == nil || len(config.Categories) == 0 {
+		return stats, nil
+	}
+	configured := map[slotKey]bool{}
+	checkSlot := func(key slotKey) {
+		configured[key] = true
+		gap, ok := slots[key]
+		if !ok {
+			gap = models.CoverageGap{Category: key.category, SubCategory: key.subCategory, Difficulty: key.difficulty}
+		}
+		if gap.Sampleable < int64(minSampleable) {
+			gap.Kind = models.CoverageGapUnderstocked
+			stats.CoverageGaps = append(stats.CoverageGaps, gap)
+		}
+	}
+	for _, category := range config.Categories {
+		if !category.HasSubCategories {
+			for _, d := range category.Difficulties {
+				checkSlot(slotKey{category.Name, "", d.Difficulty})
+			}
+			continue
+		}
+		for _, sub := range category.SubCategories {
+			for _, d := range sub.Difficulties {
+				checkSlot(slotKey{category.Name, sub.Name, d.Difficulty})
+			}
+		}
+	}
+	for _, key := range slotOrder {
+		if !configured[key] {
+			gap := slots[key]
+			gap.Kind = models.CoverageGapUnconfigured
+			stats.CoverageGaps = append(stats.CoverageGaps, gap)
+		}
+	}
+	return stats, nil
+}
+
+// addBreakdown adds a slot's counts to the breakdown named name, appending it if new.
+func addBreakdown(list []models.CountBreakdown, name string, slot models.QuestionSlotCount) []models.CountBreakdown {
+	for i := range list {
+		if list[i].Name == name {
+			list[i].Live += slot.Live
+			list[i].Sampleable += slot.Sampleable
+			return list
+		}
+	}
+	return append(list, models.CountBreakdown{Name: name, Live: slot.Live, Sampleable: slot.Sampleable})
+}
@@ -0,0 +1,366 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"hireit-backend/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrInvalidStructureOperation = errors.New("invalid structure operation")
+	ErrStructureNodeNotFound     = errors.New("category or sub-category not found")
+	ErrStructureConflict         = errors.New("the operation conflicts with the bank structure")
+	ErrStructureInUse            = errors.New("the category or sub-category is still used")
+)
+
+type slotNode struct {
+	category    string
+	subCategory string // Empty for a whole category
+}
+
+// slotMapping moves the entries and rules under from to to. With keepSub, the sub-category
+// of each entry is kept, for operations that move a whole category.
+type slotMapping struct {
+	from    slotNode
+	to      slotNode
+	keepSub bool
+}
+
+func (m slotMapping) apply(category, subCategory string) (string, string, bool) {
+	if category != m.from.category || (m.from.subCategory != "" && subCategory != m.from.subCategory) {
+		return category, subCategory, false
+	}
+	if m.keepSub {
+		return m.to.category, subCategory, true
+	}
+	return m.to.category, m.to.subCategory, true
+}
+
+func (m slotMapping) filter() bson.M {
+	filter := bson.M{"category": m.from.category}
+	if m.from.subCategory != "" {
+		filter["sub_category"] = m.from.subCategory
+	}
+	return filter
+}
+
+// ApplyStructureOperation changes the bank structure and, in the same transaction, moves
+// the entries (retired ones included) and the rules of live assessments along with it, so
+// that nothing refers to a category or sub-category that no longer exists.
+func (s *questionBankService) ApplyStructureOperation(ctx context.Context, op models.StructureOperation, authorID primitive.ObjectID) (*models.StructureOperationReport, error) {
+	var report *models.StructureOperationReport
+	err := s.repo.WithTransaction(ctx, func(ctx context.Context) error {
+		// The transaction may be retried, so every attempt starts afresh
+		report = &models.StructureOperationReport{Op: op.Op}
+
+		config, err := s.repo.GetBankConfig(ctx)
+		if err != nil {
+			return err
+		}
+		if config == nil {
+			return fmt.Errorf("%w: %q", ErrStructureNodeNotFound, op.Category)
+		}
+		mapping, reassign, err := applyStructureOperation(config, op)
+		if err != nil {
+			return err
+		}
+
+		assessments, err := s.assessmentRepo.FindAll(ctx, bson.M{
+			"deleted_at": nil,
+			"$or": []bson.M{
+				{"question_rules.category": mapping.from.category},
+				{"adaptive.category": mapping.from.category},
+			},
+		}, options.Find())
+		if err != nil {
+			return err
+		}
+
+		if !reassign {
+			// Deleting without a target is only allowed once nothing uses the node
+			entries, err := s.repo.CountByFilter(ctx, mapping.filter())
+			if err != nil {
+				return err
+			}
+			rules := 0
+			for i := range assessments {
+				rules += moveAssessmentRules(&assessments[i], mapping)
+			}
+			if entries > 0 || rules > 0 {
+				return fmt.Errorf("%w by %d questions and %d assessment rules; choose where to reassign them", ErrStructureInUse, entries, rules)
+			}
+		} else {
+			report.EntriesUpdated, err = s.repo.Relabel(ctx, mapping.filter(), func(category, subCategory string) (string, string) {
+				category, subCategory, _ = mapping.apply(category, subCategory)
+				return category, subCategory
+			}, authorID)
+			if err != nil {
+				return err
+			}
+
+			now := time.Now()
+			for i := range assessments {
+				assessment := &assessments[i]
+				moved := moveAssessmentRules(assessment, mapping)
+				if moved == 0 {
+					continue
+				}
+				if err := s.assessmentRepo.UpdateFields(ctx, assessment.ID, bson.M{
+					"question_rules": assessment.QuestionRules,
+					"adaptive":       assessment.Adaptive,
+					"updated_at":     now,
+				}); err != nil {
+					return err
+				}
+				report.AssessmentsUpdated++
+				report.RulesUpdated += moved
+			}
+		}
+
+		if err := s.repo.SaveBankConfig(ctx, config); err != nil {
+			return err
+		}
+		report.Structure = config
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// moveAssessmentRules moves the rules of the assessment under mapping's source, its adaptive
+// configuration included, and returns how many it moved.
+func moveAssessmentRules(assessment *models.Assessment, mapping slotMapping) int {
+	moved := 0
+	for i := range assessment.QuestionRules {
+		rule := &assessment.QuestionRules[i]
+		var ok bool
+		if rule.Category, rule.SubCategory, ok = mapping.apply(rule.Category, rule.SubCategory); ok {
+			moved++
+		}
+	}
+	if adaptive := assessment.Adaptive; adaptive != nil {
+		// An adaptive test over a whole category keeps drawing from it when one of its
+		// sub-categories moves away
+		var ok bool
+		if adaptive.Category, adaptive.SubCategory, ok = mapping.apply(adaptive.Category, adaptive.SubCategory); ok {
+			moved++
+		}
+	}
+	return moved
+}
+
+// applyStructureOperation applies op to config and returns how entries and rules move. It
+// reports false when they have nowhere to go, for a delete without a target.
+func applyStructureOperation(config *models.QuestionBankConfig, op models.StructureOperation) (slotMapping, bool, error) {
+	ci := findCategoryConfig(config, op.Category)
+	if ci < 0 {
+		return slotMapping{}, false, fmt.Errorf("%w: %q", ErrStructureNodeNotFound, op.Category)
+	}
+	category := &config.Categories[ci]
+	si := -1
+	if op.SubCategory != "" {
+		if si = findSubCategoryConfig(category, op.SubCategory); si < 0 {
+			return slotMapping{}, false, fmt.Errorf("%w: %q in %q", ErrStructureNodeNotFound, op.SubCategory, op.Category)
+		}
+	}
+	from := slotNode{category: category.Name, subCategory: op.SubCategory}
+
+	// findTarget looks up the category, and sub-category if given, that takes over the entries
+	findTarget := func() (int, int, error) {
+		if op.IntoCategory == "" {
+			return -1, -1, fmt.Errorf("%w: into_category is required for %s", ErrInvalidStructureOperation, op.Op)
+		}
+		ti := findCategoryConfig(config, op.IntoCategory)
+		if ti < 0 {
+			return -1, -1, fmt.Errorf("%w: %q", ErrStructureNodeNotFound, op.IntoCategory)
+		}
+		tj := -1
+		if op.IntoSubCategory != "" {
+			if tj = findSubCategoryConfig(&config.Categories[ti], op.IntoSubCategory); tj < 0 {
+				return -1, -1, fmt.Errorf("%w: %q in %q", ErrStructureNodeNotFound, op.IntoSubCategory, op.IntoCategory)
+			}
+		}
+		return ti, tj, nil
+	}
+
+	switch op.Op {
+	case models.StructureOpRename:
+		name := strings.TrimSpace(op.NewName)
+		if name == "" {
+			return slotMapping{}, false, fmt.Errorf("%w: new_name is required", ErrInvalidStructureOperation)
+		}
+		if si < 0 {
+			if other := findCategoryConfig(config, name); other >= 0 && other != ci {
+				return slotMapping{}, false, fmt.Errorf("%w: category %q already exists; merge into it instead", ErrStructureConflict, name)
+			}
+			category.Name = name
+			return slotMapping{from: from, to: slotNode{category: name}, keepSub: true}, true, nil
+		}
+		if other := findSubCategoryConfig(category, name); other >= 0 && other != si {
+			return slotMapping{}, false, fmt.Errorf("%w: %q already has a sub-category %q; merge into it instead", ErrStructureConflict, category.Name, name)
+		}
+		category.SubCategories[si].Name = name
+		return slotMapping{from: from, to: slotNode{category: category.Name, subCategory: name}}, true, nil
+
+	case models.StructureOpMerge:
+		ti, tj, err := findTarget()
+		if err != nil {
+			return slotMapping{}, false, err
+		}
+		target := &config.Categories[ti]
+		if si < 0 {
+			if tj >= 0 {
+				return slotMapping{}, false, fmt.Errorf("%w: a category merges into a category", ErrInvalidStructureOperation)
+			}
+			if ti == ci {
+				return slotMapping{}, false, fmt.Errorf("%w: a category cannot merge into itself", ErrInvalidStructureOperation)
+			}
+			if target.HasSubCategories != category.HasSubCategories {
+				return slotMapping{}, false, fmt.Errorf("%w: only one of %q and %q has sub-categories; move or delete with reassignment instead", ErrStructureConflict, category.Name, target.Name)
+			}
+			target.Difficulties = mergeDifficulties(target.Difficulties, category.Difficulties)
+			for _, sub := range category.SubCategories {
+				if j := findSubCategoryConfig(target, sub.Name); j >= 0 {
+					target.SubCategories[j].Difficulties = mergeDifficulties(target.SubCategories[j].Difficulties, sub.Difficulties)
+				} else {
+					target.SubCategories = append(target.SubCategories, sub)
+				}
+			}
+			to := slotNode{category: target.Name}
+			config.Categories = append(config.Categories[:ci], config.Categories[ci+1:]...)
+			return slotMapping{from: from, to: to, keepSub: true}, true, nil
+		}
+		if tj < 0 {
+			return slotMapping{}, false, fmt.Errorf("%w: a sub-category merges into a sub-category", ErrInvalidStructureOperation)
+		}
+		if ti == ci && tj == si {
+			return slotMapping{}, false, fmt.Errorf("%w: a sub-category cannot merge into itself", ErrInvalidStructureOperation)
+		}
+		targetSub := &target.SubCategories[tj]
+		targetSub.Difficulties = mergeDifficulties(targetSub.Difficulties, category.SubCategories[si].Difficulties)
+		to := slotNode{category: target.Name, subCategory: targetSub.Name}
+		category.SubCategories = append(category.SubCategories[:si], category.SubCategories[si+1:]...)
+		return slotMapping{from: from, to: to}, true, nil
+
+	case models.StructureOpMove:
+		if si < 0 {
+			return slotMapping{}, false, fmt.Errorf("%w: only a sub-category can be moved", ErrInvalidStructureOperation)
+		}
+		if op.IntoSubCategory != "" {
+			return slotMapping{}, false, fmt.Errorf("%w: a sub-category moves into a category; merge to combine sub-categories", ErrInvalidStructureOperation)
+		}
+		ti, _, err := findTarget()
+		if err != nil {
+			return slotMapping{}, false, err
+		}
+		target := &config.Categories[ti]
+		if ti == ci {
+			return slotMapping{}, false, fmt.Errorf("%w: %q is already in %q", ErrInvalidStructureOperation, op.SubCategory, target.Name)
+		}
+		if !target.HasSubCategories {
+			return slotMapping{}, false, fmt.Errorf("%w: %q has no sub-categories", ErrStructureConflict, target.Name)
+		}
+		sub := category.SubCategories[si]
+		if findSubCategoryConfig(target, sub.Name) >= 0 {
+			return slotMapping{}, false, fmt.Errorf("%w: %q already has a sub-category %q; merge into it instead", ErrStructureConflict, target.Name, sub.Name)
+		}
+		target.SubCategories = append(target.SubCategories, sub)
+		to := slotNode{category: target.Name, subCategory: sub.Name}
+		category.SubCategories = append(category.SubCategories[:si], category.SubCategories[si+1:]...)
+		return slotMapping{from: from, to: to}, true, nil
+
+	case models.StructureOpDelete:
+		// Difficulties of the deleted node, which the target has to offer
+		var difficulties []models.DifficultyConfig
+		switch {
+		case si >= 0:
+			difficulties = category.SubCategories[si].Difficulties
+		case category.HasSubCategories:
+			for _, sub := range category.SubCategories {
+				difficulties = mergeDifficulties(difficulties, sub.Difficulties)
+			}
+		default:
+			difficulties = category.Difficulties
+		}
+
+		reassign := op.IntoCategory != ""
+		var to slotNode
+		if reassign {
+			ti, tj, err := findTarget()
+			if err != nil {
+				return slotMapping{}, false, err
+			}
+			if ti == ci && (si < 0 || tj == si) {
+				return slotMapping{}, false, fmt.Errorf("%w: the entries cannot be reassigned to what is being deleted", ErrInvalidStructureOperation)
+			}
+			target := &config.Categories[ti]
+			switch {
+			case tj >= 0:
+				target.SubCategories[tj].Difficulties = mergeDifficulties(target.SubCategories[tj].Difficulties, difficulties)
+				to = slotNode{category: target.Name, subCategory: target.SubCategories[tj].Name}
+			case target.HasSubCategories:
+				return slotMapping{}, false, fmt.Errorf("%w: choose a sub-category of %q to reassign to", ErrInvalidStructureOperation, target.Name)
+			default:
+				target.Difficulties = mergeDifficulties(target.Difficulties, difficulties)
+				to = slotNode{category: target.Name}
+			}
+		}
+
+		if si >= 0 {
+			category.SubCategories = append(category.SubCategories[:si], category.SubCategories[si+1:]...)
+		} else {
+			config.Categories = append(config.Categories[:ci], config.Categories[ci+1:]...)
+		}
+		return slotMapping{from: from, to: to}, reassign, nil
+	}
+	return slotMapping{}, false, fmt.Errorf("%w: unknown op %q", ErrInvalidStructureOperation, op.Op)
+}
+
+func findCategoryConfig(config *models.QuestionBankConfig, name string) int {
+	for i := range config.Categories {
+		if config.Categories[i].Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func findSubCategoryConfig(category *models.CategoryConfig, name string) int {
+	for i := range category.SubCategories {
+		if category.SubCategories[i].Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// mergeDifficulties adds the difficulties of from missing in into. Audio configured on
+// into wins; from's fills in where into has none.
+func mergeDifficulties(into, from []models.DifficultyConfig) []models.DifficultyConfig {
+	for _, d := range from {
+		found := false
+		for i := range into {
+			if into[i].Difficulty == d.Difficulty {
+				if into[i].AudioURL == "" {
+					into[i].AudioURL = d.AudioURL
+				}
+				found = true
+				break
+			}
+		}
+		if !found {
+			into = append(into, d)
+		}
+	}
+	return into
+}
@@ -0,0 +1,244 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"regexp"
+	"strconv"
+	"time"
+
+	"hireit-backend/models"
+	"hireit-backend/utils"
+)
+
+const (
+	// Constraints are met by redrawing; a template that fails this often is rejected.
+	maxTemplateDraws    = 100
+	templateOptionCount = 4
+	maxTemplateDecimals = 6
+	// Caps the values a variable can take, so a huge range cannot overflow the draw
+	maxTemplateSteps = 1_000_000
+)
+
+var (
+	ErrInvalidTemplate = errors.New("invalid question template")
+
+	templatePlaceholder  = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+	templateVariableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// ValidateQuestionTemplate checks a templated entry before it is stored, including that
+// it can actually be instantiated. Entries without a template are always valid.
+func ValidateQuestionTemplate(entry *models.QuestionBankEntry) error {
+	t := entry.Template
+	if t == nil {
+		return nil
+	}
+	if entry.Type != models.MultipleChoice {
+		return fmt.Errorf("%w: only MCQ entries can be templated", ErrInvalidTemplate)
+	}
+	if len(t.Variables) == 0 {
+		return fmt.Errorf("%w: at least one variable is required", ErrInvalidTemplate)
+	}
+	if t.Decimals < 0 || t.Decimals > maxTemplateDecimals {
+		return fmt.Errorf("%w: decimals must be between 0 and %d", ErrInvalidTemplate, maxTemplateDecimals)
+	}
+
+	seen := make(map[string]bool, len(t.Variables))
+	for _, v := range t.Variables {
+		if !templateVariableName.MatchString(v.Name) {
+			return fmt.Errorf("%w: invalid variable name %q", ErrInvalidTemplate, v.Name)
+		}
+		if seen[v.Name] {
+			return fmt.Errorf("%w: variable %q is declared twice", ErrInvalidTemplate, v.Name)
+		}
+		seen[v.Name] = true
+		if v.Max < v.Min || v.Step < 0 {
+			return fmt.Errorf("%w: variable %q needs min <= max and a positive step", ErrInvalidTemplate, v.Name)
+		}
+		step := v.Step
+		if step == 0 {
+			step = 1
+		}
+		// Also rejects infinite and NaN bounds, for which the comparison is false
+		if !((v.Max-v.Min)/step <= maxTemplateSteps) {
+			return fmt.Errorf("%w: variable %q can take at most %d values; narrow the range or increase the step", ErrInvalidTemplate, v.Name, maxTemplateSteps)
+		}
+	}
+
+	for _, text := range []string{entry.Text, entry.PassageText} {
+		for _, match := range templatePlaceholder.FindAllStringSubmatch(text, -1) {
+			if !seen[match[1]] {
+				return fmt.Errorf("%w: placeholder {{%s}} has no variable", ErrInvalidTemplate, match[1])
+			}
+		}
+	}
+
+	var question models.Question
+	if err := applyTemplate(&question, *entry, rand.New(rand.NewSource(1))); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+	}
+	return nil
+}
+
+// PreviewQuestionTemplate validates a templated entry and returns count example
+// instances, so authors can check the ranges and distractors before saving.
+func PreviewQuestionTemplate(entry *models.QuestionBankEntry, count int) ([]models.Question, error) {
+	if entry.Template == nil {
+		return nil, fmt.Errorf("%w: entry has no template", ErrInvalidTemplate)
+	}
+	if err := ValidateQuestionTemplate(entry); err != nil {
+		return nil, err
+	}
+
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	questions := make([]models.Question, 0, count)
+	for i := 0; i < count; i++ {
+		question := models.Question{ID: entry.ID, Type: entry.Type, PassageTitle: entry.PassageTitle, Points: 1}
+		if err := applyTemplate(&question, *entry, rng); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+		}
+		questions = append(questions, question)
+	}
+	return questions, nil
+}
+
+// applyTemplate draws values for a templated entry and fills in the question's text,
+// options and answer. The drawn values are kept on the question so the attempt can be
+// regraded and replayed exactly as the candidate saw it.
+func applyTemplate(question *models.Question, entry models.QuestionBankEntry, rng *rand.Rand) error {
+	t := entry.Template
+
+	var values map[string]float64
+	var answer float64
+	for draw := 0; ; draw++ {
+		if draw == maxTemplateDraws {
+			return errors.New("no draw satisfied the constraints with a defined answer")
+		}
+
+		values = drawTemplateValues(t.Variables, rng)
+		ok, err := templateConstraintsHold(t.Constraints, values)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		answer, err = utils.EvalExpression(t.AnswerFormula, values)
+		if errors.Is(err, utils.ErrExpressionUndefined) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("answer formula: %v", err)
+		}
+		break
+	}
+
+	correct := formatTemplateNumber(answer, t.Decimals)
+	options, err := templateOptions(t, values, answer, rng)
+	if err != nil {
+		return err
+	}
+
+	question.Text = fillTemplatePlaceholders(entry.Text, values)
+	question.PassageText = fillTemplatePlaceholders(entry.PassageText, values)
+	question.Options = options
+	question.CorrectAnswer = correct
+	question.Parameters = values
+	return nil
+}
+
+func drawTemplateValues(variables []models.TemplateVariable, rng *rand.Rand) map[string]float64 {
+	values := make(map[string]float64, len(variables))
+	for _, v := range variables {
+		step := v.Step
+		if step == 0 {
+			step = 1
+		}
+		steps := int(math.Floor((v.Max-v.Min)/step + 1e-9))
+		value := v.Min + step*float64(rng.Intn(steps+1))
+		// Strip float noise such as 0.30000000000000004 from fractional steps
+		values[v.Name] = roundTo(value, maxTemplateDecimals)
+	}
+	return values
+}
+
+func templateConstraintsHold(constraints []string, values map[string]float64) (bool, error) {
+	for _, constraint := range constraints {
+		result, err := utils.EvalExpression(constraint, values)
+		if err != nil {
+			if errors.Is(err, utils.ErrExpressionUndefined) {
+				return false, nil
+			}
+			return false, fmt.Errorf("constraint %q: %v", constraint, err)
+		}
+		if result == 0 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// templateOptions combines the answer with the distractor formulas, topping up with
+// near-miss values when the formulas collapse onto each other or are missing.
+func templateOptions(t *models.QuestionTemplate, values map[string]float64, answer float64, rng *rand.Rand) ([]string, error) {
+	correct := formatTemplateNumber(answer, t.Decimals)
+	options := []string{correct}
+	seen := map[string]bool{correct: true}
+	add := func(value float64) {
+		option := formatTemplateNumber(value, t.Decimals)
+		if len(options) < templateOptionCount && !seen[option] {
+			seen[option] = true
+			options = append(options, option)
+		}
+	}
+
+	for _, formula := range t.Distractors {
+		value, err := utils.EvalExpression(formula, values)
+		if err != nil {
+			if errors.Is(err, utils.ErrExpressionUndefined) {
+				continue
+			}
+			return nil, fmt.Errorf("distractor %q: %v", formula, err)
+		}
+		add(value)
+	}
+
+	unit := math.Max(math.Pow(10, -float64(t.Decimals)), roundTo(math.Abs(answer)*0.1, t.Decimals))
+	for k := 1; len(options) < templateOptionCount && k <= 2*templateOptionCount; k++ {
+		add(answer + float64(k)*unit)
+		add(answer - float64(k)*unit)
+	}
+
+	rng.Shuffle(len(options), func(i, j int) {
+		options[i], options[j] = options[j], options[i]
+	})
+	return options, nil
+}
+
+func fillTemplatePlaceholders(text string, values map[string]float64) string {
+	return templatePlaceholder.ReplaceAllStringFunc(text, func(match string) string {
+		name := templatePlaceholder.FindStringSubmatch(match)[1]
+		if value, ok := values[name]; ok {
+			return formatTemplateNumber(value, maxTemplateDecimals)
+		}
+		return match
+	})
+}
+
+// formatTemplateNumber rounds to the given decimals and drops trailing zeros, so the
+// option text matches exactly what grading compares against.
+func formatTemplateNumber(value float64, decimals int) string {
+	rounded := roundTo(value, decimals)
+	if rounded == 0 {
+		rounded = 0 // normalise -0
+	}
+	return strconv.FormatFloat(rounded, 'f', -1, 64)
+}
+
+func roundTo(value float64, decimals int) float64 {
+	scale := math.Pow(10, float64(decimals))
+	return math.Round(value*scale) / scale
+}
@@ -0,0 +1,453 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"hireit-backend/models"
+)
+
+const (
+	QuestionFormatGIFT  = "gift"
+	QuestionFormatAiken = "aiken"
+)
+
+var (
+	ErrUnsupportedQuestionFormat = errors.New("format must be gift or aiken")
+
+	aikenOption = regexp.MustCompile(`^([A-Za-z])[.)]\s+(.*)$`)
+	aikenAnswer = regexp.MustCompile(`(?i)^ANSWER:\s*([A-Za-z])\b`)
+	giftFormat  = regexp.MustCompile(`^\[(html|moodle|markdown|plain)\]`)
+)
+
+// ReadQuestionText parses a GIFT or Aiken file into import rows numbered by the line each
+// question starts on. Questions embedding images, which the files do not carry, are
+// reported as invalid rows rather than imported without them.
+func ReadQuestionText(format, text string) ([]ImportRow, error) {
+	text = strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "\n"), "\r", "\n")
+	text = strings.TrimPrefix(text, "\ufeff")
+	var rows []ImportRow
+	switch format {
+	case QuestionFormatGIFT:
+		rows = readGIFT(text)
+	case QuestionFormatAiken:
+		rows = readAiken(text)
+	default:
+		return nil, ErrUnsupportedQuestionFormat
+	}
+	for i := range rows {
+		if entry := rows[i].Entry; entry != nil && embedsImage(entry) {
+			rows[i] = ImportRow{Row: rows[i].Row, Error: "embedded images cannot be imported; add the question without them and attach the media"}
+		}
+	}
+	return rows, nil
+}
+
+// embedsImage reports whether the text or options of an entry reference an image, as an
+// HTML img tag or a Moodle @@PLUGINFILE@@ link.
+func embedsImage(entry *models.QuestionBankEntry) bool {
+	for _, text := range append([]string{entry.Text}, entry.Options...) {
+		if strings.Contains(strings.ToLower(text), "<img") || strings.Contains(text, "@@PLUGINFILE@@") {
+			return true
+		}
+	}
+	return false
+}
+
+// readAiken parses Aiken: a stem of one or more lines, lettered options ("A." or "A)") and
+// an "ANSWER: B" line. Option lines that wrap are joined to the option above.
+func readAiken(text string) []ImportRow {
+	var rows []ImportRow
+	var (
+		start      int
+		stem       []string
+		options    []string
+		blankAfter bool
+	)
+	reset := func() {
+		stem, options, blankAfter = nil, nil, false
+	}
+	fail := func(message string) {
+		rows = append(rows, ImportRow{Row: start, Error: message})
+		reset()
+	}
+
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			if len(options) > 0 {
+				blankAfter = true
+			}
+			continue
+		}
+
+		if m := aikenAnswer.FindStringSubmatch(line); m != nil {
+			if len(stem) == 0 {
+				rows = append(rows, ImportRow{Row: i + 1, Error: "ANSWER line without a question"})
+				continue
+			}
+			idx := int(strings.ToUpper(m[1])[0] - 'A')
+			if len(options) < 2 {
+				fail("a question needs at least two options before ANSWER")
+				continue
+			}
+			if idx < 0 || idx >= len(options) {
+				fail(fmt.Sprintf("ANSWER %s does not match any of the %d options", strings.ToUpper(m[1]), len(options)))
+				continue
+			}
+			rows = append(rows, ImportRow{Row: start, Entry: &models.QuestionBankEntry{
+				Type:          models.MultipleChoice,
+				Text:          strings.Join(stem, "\n"),
+				Options:       options,
+				CorrectAnswer: options[idx],
+			}})
+			reset()
+			continue
+		}
+
+		if len(stem) > 0 {
+			if m := aikenOption.FindStringSubmatch(line); m != nil && int(strings.ToUpper(m[1])[0]-'A') == len(options) {
+				options = append(options, strings.TrimSpace(m[2]))
+				blankAfter = false
+				continue
+			}
+		}
+		switch {
+		case len(stem) == 0:
+			start = i + 1
+			stem = []string{line}
+		case len(options) == 0:
+			stem = append(stem, line)
+		case blankAfter:
+			// Text after options and a blank line starts the next question
+			fail("missing ANSWER line")
+			start = i + 1
+			stem = []string{line}
+		default:
+			options[len(options)-1] += " " + line
+		}
+	}
+	if len(stem) > 0 {
+		fail("missing ANSWER line")
+	}
+	return rows
+}
+
+// readGIFT parses the Moodle GIFT format. Questions are separated by blank lines; //
+// comments are dropped and $CATEGORY sets the category (and sub-category) of the questions
+// that follow. Multiple choice, true/false, short answer, numeric, missing word and essay
+// questions are supported; feedback is parsed but not kept, as entries have no field for it.
+func readGIFT(text string) []ImportRow {
+	var rows []ImportRow
+	category, subCategory := "", ""
+
+	var block []string
+	start := 0
+	flush := func() {
+		if len(block) == 0 {
+			return
+		}
+		question := strings.Join(block, "\n")
+		block = nil
+
+		if strings.HasPrefix(question, "$CATEGORY:") {
+			category, subCategory = giftCategory(strings.TrimSpace(strings.TrimPrefix(question, "$CATEGORY:")))
+			return
+		}
+		entry, err := giftEntry(question)
+		if err != nil {
+			rows = append(rows, ImportRow{Row: start, Error: err.Error()})
+			return
+		}
+		entry.Category = category
+		entry.SubCategory = subCategory
+		rows = append(rows, ImportRow{Row: start, Entry: entry})
+	}
+
+	for i, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "//") {
+			continue
+		}
+		if trimmed == "" {
+			flush()
+			continue
+		}
+		if len(block) == 0 {
+			start = i + 1
+		}
+		block = append(block, trimmed)
+	}
+	flush()
+	return rows
+}
+
+// giftCategory maps a Moodle category path such as "$course$/top/Aptitude/Logic" to a
+// category and sub-category.
+func giftCategory(path string) (string, string) {
+	var parts []string
+	for _, part := range strings.Split(path, "/") {
+		part = strings.TrimSpace(part)
+		if part == "" || part == "top" || (strings.HasPrefix(part, "$") && strings.HasSuffix(part, "$")) {
+			continue
+		}
+		parts = append(parts, part)
+	}
+	switch len(parts) {
+	case 0:
+		return "", ""
+	case 1:
+		return parts[0], ""
+	}
+	return parts[0], strings.Join(parts[1:], "/")
+}
+
+// giftAnswer is one answer of a GIFT answer block
+type giftAnswer struct {
+	correct bool   // Marked with = rather than ~
+	weight  *int   // The %n% credit, when given
+	text    string // Without the weight and feedback
+}
+
+func giftEntry(question string) (*models.QuestionBankEntry, error) {
+	entry := &models.QuestionBankEntry{}
+
+	if strings.HasPrefix(question, "::") {
+		end := giftIndex(question[2:], "::")
+		if end < 0 {
+			return nil, errors.New("unterminated ::title::")
+		}
+		question = strings.TrimSpace(question[end+4:])
+	}
+
+	open := giftIndex(question, "{")
+	if open < 0 {
+		return nil, errors.New("no answer block; descriptions are not imported")
+	}
+	closing := giftIndex(question[open:], "}")
+	if closing < 0 {
+		return nil, errors.New("unterminated answer block")
+	}
+	closing += open
+	before := strings.TrimSpace(question[:open])
+	after := strings.TrimSpace(question[closing+1:])
+	answer := strings.TrimSpace(question[open+1 : closing])
+
+	stem := before
+	if after != "" {
+		// Missing word: the answer block stands for a gap in the sentence
+		stem = strings.TrimSpace(before + " " + questionBlank + " " + after)
+	}
+	stem = giftUnescape(giftFormat.ReplaceAllString(stem, ""))
+	if stem == "" {
+		return nil, errors.New("question text is empty")
+	}
+	entry.Text = stem
+
+	// General feedback follows ####
+	if idx := giftIndex(answer, "####"); idx >= 0 {
+		answer = strings.TrimSpace(answer[:idx])
+	}
+
+	switch {
+	case answer == "":
+		entry.Type = models.Subjective
+		return entry, nil
+	case strings.HasPrefix(answer, "#"):
+		value, err := giftNumericAnswer(strings.TrimSpace(answer[1:]))
+		if err != nil {
+			return nil, err
+		}
+		entry.Type = models.Subjective
+		entry.CorrectAnswer = value
+		return entry, nil
+	}
+
+	if tf := strings.ToUpper(strings.TrimSpace(giftCut(answer, "#"))); tf == "T" || tf == "TRUE" || tf == "F" || tf == "FALSE" {
+		entry.Type = models.MultipleChoice
+		entry.Options = []string{"True", "False"}
+		entry.CorrectAnswer = "False"
+		if tf[0] == 'T' {
+			entry.CorrectAnswer = "True"
+		}
+		return entry, nil
+	}
+
+	if giftIndex(answer, "->") >= 0 {
+		return nil, errors.New("matching questions are not supported")
+	}
+
+	answers, err := giftAnswers(answer)
+	if err != nil {
+		return nil, err
+	}
+	hasWrong := false
+	for _, a := range answers {
+		if !a.correct {
+			hasWrong = true
+		}
+	}
+
+	if !hasWrong {
+		// Short answer: every listed answer is accepted; the first is kept as the reference
+		entry.Type = models.Subjective
+		entry.CorrectAnswer = answers[0].text
+		return entry, nil
+	}
+
+	entry.Type = models.MultipleChoice
+	var correct []string
+	for _, a := range answers {
+		entry.Options = append(entry.Options, a.text)
+		if a.correct || (a.weight != nil && *a.weight >= 100) {
+			correct = append(correct, a.text)
+		} else if a.weight != nil && *a.weight > 0 {
+			return nil, errors.New("partial-credit answers are not supported")
+		}
+	}
+	switch len(correct) {
+	case 0:
+		return nil, errors.New("multiple choice question has no correct answer")
+	case 1:
+		entry.CorrectAnswer = correct[0]
+	default:
+		return nil, errors.New("multiple-answer questions are not supported")
+	}
+	return entry, nil
+}
+
+// giftAnswers splits an answer block at each unescaped = or ~.
+func giftAnswers(block string) ([]giftAnswer, error) {
+	var answers []giftAnswer
+	var current *giftAnswer
+	var text strings.Builder
+	finish := func() error {
+		if current == nil {
+			if strings.TrimSpace(text.String()) != "" {
+				return errors.New("answers must start with = or ~")
+			}
+			return nil
+		}
+		raw := strings.TrimSpace(giftCut(text.String(), "#"))
+		if strings.HasPrefix(raw, "%") {
+			end := strings.Index(raw[1:], "%")
+			if end < 0 {
+				return fmt.Errorf("unterminated weight in %q", raw)
+			}
+			weight, err := strconv.ParseFloat(raw[1:end+1], 64)
+			if err != nil {
+				return fmt.Errorf("invalid weight in %q", raw)
+			}
+			w := int(weight)
+			current.weight = &w
+			raw = strings.TrimSpace(raw[end+2:])
+		}
+		current.text = giftUnescape(giftFormat.ReplaceAllString(raw, ""))
+		if current.text == "" {
+			return errors.New("empty answer")
+		}
+		answers = append(answers, *current)
+		return nil
+	}
+
+	escaped := false
+	for _, r := range block {
+		switch {
+		case escaped:
+			text.WriteRune('\\')
+			text.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '=' || r == '~':
+			if err := finish(); err != nil {
+				return nil, err
+			}
+			current = &giftAnswer{correct: r == '='}
+			text.Reset()
+		default:
+			text.WriteRune(r)
+		}
+	}
+	if err := finish(); err != nil {
+		return nil, err
+	}
+	if len(answers) == 0 {
+		return nil, errors.New("answer block has no answers")
+	}
+	return answers, nil
+}
+
+// giftNumericAnswer reads "3.14:0.01", "1..5" or "=3.14:0.01 =%50%3:1" and keeps the
+// full-credit answer as a reference for graders, e.g. "3.14 (±0.01)".
+func giftNumericAnswer(block string) (string, error) {
+	if strings.HasPrefix(block, "=") {
+		answers, err := giftAnswers(block)
+		if err != nil {
+			return "", err
+		}
+		block = answers[0].text
+		for _, a := range answers {
+			if a.weight == nil || *a.weight >= 100 {
+				block = a.text
+				break
+			}
+		}
+	}
+	block = strings.TrimSpace(giftCut(block, "#"))
+
+	if low, high, ok := strings.Cut(block, ".."); ok {
+		if _, err := strconv.ParseFloat(strings.TrimSpace(low), 64); err != nil {
+			return "", fmt.Errorf("invalid numeric range %q", block)
+		}
+		if _, err := strconv.ParseFloat(strings.TrimSpace(high), 64); err != nil {
+			return "", fmt.Errorf("invalid numeric range %q", block)
+		}
+		return fmt.Sprintf("%s to %s", strings.TrimSpace(low), strings.TrimSpace(high)), nil
+	}
+	value, tolerance, _ := strings.Cut(block, ":")
+	value, tolerance = strings.TrimSpace(value), strings.TrimSpace(tolerance)
+	if _, err := strconv.ParseFloat(value, 64); err != nil {
+		return "", fmt.Errorf("invalid numeric answer %q", block)
+	}
+	if tolerance == "" {
+		return value, nil
+	}
+	if t, err := strconv.ParseFloat(tolerance, 64); err != nil {
+		return "", fmt.Errorf("invalid tolerance in %q", block)
+	} else if t == 0 {
+		return value, nil
+	}
+	return fmt.Sprintf("%s (±%s)", value, tolerance), nil
+}
+
+// giftIndex finds the first occurrence of sep not preceded by a backslash.
+func giftIndex(s, sep string) int {
+	for i := 0; i+len(sep) <= len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			continue
+		}
+		if s[i:i+len(sep)] == sep {
+			return i
+		}
+	}
+	return -1
+}
+
+// giftCut drops everything from the first unescaped sep, such as an answer's #feedback.
+func giftCut(s, sep string) string {
+	if idx := giftIndex(s, sep); idx >= 0 {
+		return s[:idx]
+	}
+	return s
+}
+
+var giftEscapes = strings.NewReplacer(`\~`, "~", `\=`, "=", `\#`, "#", `\{`, "{", `\}`, "}", `\:`, ":", `\n`, "\n", `\\`, `\`)
+
+func giftUnescape(s string) string {
+	return strings.TrimSpace(giftEscapes.Replace(s))
+}
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+
+	"hireit-backend/models"
+)
+
+// DiffQuestionVersions lists the authored fields that differ between two versions of an
+// entry, using the same field names as the API.
+func DiffQuestionVersions(from, to *models.QuestionBankVersion) (*models.QuestionVersionDiff, error) {
+	fromFields, err := questionFields(from.Content)
+	if err != nil {
+		return nil, err
+	}
+	toFields, err := questionFields(to.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(fromFields)+len(toFields))
+	for name := range fromFields {
+		names = append(names, name)
+	}
+	for name := range toFields {
+		if _, ok := fromFields[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	diff := &models.QuestionVersionDiff{
+		EntryID:     to.EntryID,
+		FromVersion: from.Version,
+		ToVersion:   to.Version,
+		Changes:     []models.QuestionFieldChange{},
+	}
+	for _, name := range names {
+		if !reflect.DeepEqual(fromFields[name], toFields[name]) {
+			diff.Changes = append(diff.Changes, models.QuestionFieldChange{Field: name, From: fromFields[name], To: toFields[name]})
+		}
+	}
+	return diff, nil
+}
+
+func questionFields(content models.QuestionBankEntry) (map[string]interface{}, error) {
+	raw, err := json.Marshal(content)
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+	// Identity and bookkeeping, not content
+	delete(fields, "id")
+	delete(fields, "version")
+	return fields, nil
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"hireit-backend/models"
+)
+
+var (
+	ErrAttemptInProgress = errors.New("an attempt is already in progress")
+	ErrNoAttemptsLeft    = errors.New("no attempts remaining for this assessment")
+	ErrRetakeCooldown    = errors.New("retake cooldown has not elapsed")
+)
+
+func effectiveRetakePolicy(assessment *models.Assessment) models.RetakePolicy {
+	policy := models.RetakePolicy{MaxAttempts: 1, ScoringPolicy: models.ScoringPolicyLatest}
+	if assessment == nil || assessment.RetakePolicy == nil {
+		return policy
+	}
+
+	if assessment.RetakePolicy.MaxAttempts > 1 {
+		policy.MaxAttempts = assessment.RetakePolicy.MaxAttempts
+	}
+	if assessment.RetakePolicy.CooldownMinutes > 0 {
+		policy.CooldownMinutes = assessment.RetakePolicy.CooldownMinutes
+	}
+	if assessment.RetakePolicy.ScoringPolicy == models.ScoringPolicyBest {
+		policy.ScoringPolicy = models.ScoringPolicyBest
+	}
+
+	return policy
+}
+
+// attemptNumberOf treats submissions created before retakes existed as the first attempt.
+func attemptNumberOf(submission models.Submission) int {
+	if submission.AttemptNumber < 1 {
+		return 1
+	}
+	return submission.AttemptNumber
+}
+
+// checkRetakeEligibility decides whether a new attempt may start after the latest one,
+// given how many attempts the candidate has used. Deleted attempts do not count.
+// It returns the time a retake becomes available when the cooldown is still running.
+func checkRetakeEligibility(policy models.RetakePolicy, latest *models.Submission, attemptsUsed int, now time.Time) (*time.Time, error) {
+	if latest == nil {
+		return nil, nil
+	}
+	if latest.Status != "completed" {
+		return nil, ErrAttemptInProgress
+	}
+	if attemptsUsed >= policy.MaxAttempts {
+		return nil, ErrNoAttemptsLeft
+	}
+
+	if policy.CooldownMinutes > 0 {
+		eligibleAt := latest.SubmittedAt.Add(time.Duration(policy.CooldownMinutes) * time.Minute)
+		if now.Before(eligibleAt) {
+			return &eligibleAt, fmt.Errorf("%w: available at %s", ErrRetakeCooldown, eligibleAt.Format(time.RFC3339))
+		}
+	}
+
+	return nil, nil
+}
+
+// selectCountedAttempts picks one submission per candidate according to the scoring policy.
+// Completed attempts always win over unfinished ones; "best" prefers the highest score and
+// "latest" the most recent attempt. AttemptCount is filled in on every returned submission.
+func selectCountedAttempts(subs []models.Submission, policy models.RetakePolicy) []models.Submission {
+	byCandidate := make(map[string][]models.Submission)
+	order := make([]string, 0)
+	for _, sub := range subs {
+		key := sub.CandidateID.Hex()
+		if _, exists := byCandidate[key]; !exists {
+			order = append(order, key)
+		}
+		byCandidate[key] = append(byCandidate[key], sub)
+	}
+
+	counted := make([]models.Submission, 0, len(order))
+	for _, key := range order {
+		attempts := byCandidate[key]
+		chosen := attempts[0]
+		for _, attempt := range attempts[1:] {
+			if countedAttemptBeats(attempt, chosen, policy) {
+				chosen = attempt
+			}
+		}
+		chosen.AttemptCount = len(attempts)
+		counted = append(counted, chosen)
+	}
+
+	sort.SliceStable(counted, func(i, j int) bool {
+		return counted[i].SubmittedAt.After(counted[j].SubmittedAt)
+	})
+
+	return counted
+}
+
+func countedAttemptBeats(candidate, current models.Submission, policy models.RetakePolicy) bool {
+	candidateDone := candidate.Status == "completed"
+	currentDone := current.Status == "completed"
+	if candidateDone != currentDone {
+		return candidateDone
+	}
+
+	if policy.ScoringPolicy == models.ScoringPolicyBest && candidateDone && candidate.Score != current.Score {
+		return candidate.Score > current.Score
+	}
+
+	return attemptNumberOf(candidate) > attemptNumberOf(current)
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"hireit-backend/models"
 	"hireit-backend/repositories"
+	"sort"
 	"strings"
 	"time"
 
@@ -15,13 +16,20 @@ import (
 )
 
 type SubmissionService interface {
-	GetSubmissions(ctx context.Context, assessmentID string) ([]models.Submission, error)
+	GetSubmissions(ctx context.Context, assessmentID string, allAttempts bool) ([]models.Submission, error)
 	GetCandidateResult(ctx context.Context, assessmentID, candidateID string) (*models.Submission, error)
-	SubmitAssessment(ctx context.Context, assessmentID, candidateID string, answers []models.Answer, violations []models.Violation, faceSnapshots *models.FaceSnapshots) (*models.Submission, error)
-	SaveProgress(ctx context.Context, assessmentID, candidateID string, answers []models.Answer, violations []models.Violation) error
+	SubmitAssessment(ctx context.Context, assessmentID, candidateID string, answers []models.Answer, violations []models.Violation, events []models.AnswerEvent, faceSnapshots *models.FaceSnapshots) (*models.Submission, error)
+	SaveProgress(ctx context.Context, assessmentID, candidateID string, answers []models.Answer, violations []models.Violation, events []models.AnswerEvent, expectedVersion *int64) (int64, error)
+	SaveAnswer(ctx context.Context, assessmentID, candidateID string, answer models.Answer, expectedVersion *int64) (int64, error)
 	GetSubmissionsByCandidate(ctx context.Context, candidateID string) ([]models.Submission, error)
 	GetSubmissionsByInterviewer(ctx context.Context, interviewerID string) ([]models.Submission, error)
 	GetOrGenerateQuestions(ctx context.Context, assessmentID, candidateID string) ([]models.Question, error)
+	StartRetake(ctx context.Context, assessmentID, candidateID string) (*models.Submission, error)
+	GetAttemptHistory(ctx context.Context, assessmentID, candidateID string) (*models.AttemptHistory, error)
+	ApplyCandidateTiming(ctx context.Context, assessment *models.Assessment, candidateID string)
+	GetQuestionTimeStats(ctx context.Context, assessmentID string) (*models.AssessmentTimeStats, error)
+	NextAdaptiveQuestion(ctx context.Context, assessmentID, candidateID string) (*models.AdaptiveStep, error)
+	AnswerAdaptiveQuestion(ctx context.Context, assessmentID, candidateID, questionID, value string) (*models.AdaptiveStep, error)
 }
 
 type submissionService struct {
@@ -29,6 +37,8 @@ type submissionService struct {
 	assessmentRepo repositories.AssessmentRepository
 	userRepo       repositories.UserRepository
 	qbRepo         repositories.QuestionBankRepository
+	invitationRepo repositories.InvitationRepository
+	accommRepo     repositories.AccommodationRepository
 	auditService   AuditLogService
 }
 
@@ -57,40 +67,64 @@ func shouldReuseGeneratedQuestions(submission *models.Submission, assessment *mo
 	return false
 }
 
-func NewSubmissionService(repo repositories.SubmissionRepository, assessmentRepo repositories.AssessmentRepository, userRepo repositories.UserRepository, qbRepo repositories.QuestionBankRepository, auditService AuditLogService) SubmissionService {
+func NewSubmissionService(repo repositories.SubmissionRepository, assessmentRepo repositories.AssessmentRepository, userRepo repositories.UserRepository, qbRepo repositories.QuestionBankRepository, invitationRepo repositories.InvitationRepository, accommRepo repositories.AccommodationRepository, auditService AuditLogService) SubmissionService {
 	return &submissionService{
 		repo:           repo,
 		assessmentRepo: assessmentRepo,
 		userRepo:       userRepo,
 		qbRepo:         qbRepo,
+		invitationRepo: invitationRepo,
+		accommRepo:     accommRepo,
 		auditService:   auditService,
 	}
 }
 
-func (r *submissionService) GetSubmissions(ctx context.Context, assessmentID string) ([]models.Submission, error) {
+// advanceInvitation moves the candidate's invitation for this assessment forward so
+// interviewers can see who opened, started or completed it. Candidates without an
+// invitation are unaffected.
+func (s *submissionService) advanceInvitation(ctx context.Context, aID, cID primitive.ObjectID, status string, fromStatuses ...string) {
+	if s.invitationRepo == nil {
+		return
+	}
+
+	now := time.Now()
+	set := bson.M{"status": status, "updated_at": now}
+	switch status {
+	case models.InvitationStatusStarted:
+		set["started_at"] = now
+	case models.InvitationStatusCompleted:
+		set["completed_at"] = now
+	}
+
+	_, err := s.invitationRepo.UpdateMany(ctx, bson.M{
+		"assessment_id": aID,
+		"candidate_id":  cID,
+		"status":        bson.M{"$in": fromStatuses},
+		"deleted_at":    nil,
+	}, bson.M{"$set": set})
+	if err != nil {
+		fmt.Printf("Warning: Failed to update invitation status to %s: %v\n", status, err)
+	}
+}
+
+func (r *submissionService) GetSubmissions(ctx context.Context, assessmentID string, allAttempts bool) ([]models.Submission, error) {
 	objID, _ := primitive.ObjectIDFromHex(assessmentID)
 	// Check if assessment exists and not deleted
-	_, err := r.assessmentRepo.FindByID(ctx, objID)
+	assessment, err := r.assessmentRepo.FindByID(ctx, objID)
 	if err != nil {
 		return nil, errors.New("assessment not found or deleted")
 	}
 
-	subs, err := r.repo.FindAll(ctx, bson.M{"assessment_id": objID, "deleted_at": nil}, options.Find().SetSort(bson.D{{Key: "submitted_at", Value: -1}}))
+	subs, err := r.repo.FindAll(ctx, bson.M{"assessment_id": objID, "deleted_at": nil}, options.Find().SetSort(bson.D{{Key: "submitted_at", Value: -1}, {Key: "attempt_number", Value: -1}}))
 	if err != nil {
 		return nil, err
 	}
-
-	// Deduplicate by candidate_id
-	seen := make(map[string]bool)
-	var uniqueSubs []models.Submission
-	for _, s := range subs {
-		key := s.CandidateID.Hex()
-		if !seen[key] {
-			uniqueSubs = append(uniqueSubs, s)
-			seen[key] = true
-		}
+	if allAttempts {
+		return subs, nil
 	}
-	return uniqueSubs, nil
+
+	// One row per candidate: the attempt that counts under the assessment's retake policy
+	return selectCountedAttempts(subs, effectiveRetakePolicy(assessment)), nil
 }
 
 func (r *submissionService) GetCandidateResult(ctx context.Context, assessmentID, candidateID string) (*models.Submission, error) {
@@ -103,31 +137,49 @@ func (r *submissionService) GetCandidateResult(ctx context.Context, assessmentID
 		return nil, errors.New("assessment not found or deleted")
 	}
 
-	return r.repo.FindOne(ctx, bson.M{"assessment_id": aID, "candidate_id": cID, "deleted_at": nil})
+	submission, err := r.repo.FindLatestAttempt(ctx, aID, cID)
+	if err != nil {
+		return nil, err
+	}
+	if submission.DeletedAt != nil {
+		return nil, errors.New("submission deleted")
+	}
+	return submission, nil
 }
 
-func (s *submissionService) SaveProgress(ctx context.Context, assessmentID, candidateID string, answers []models.Answer, violations []models.Violation) error {
+// SaveProgress replaces the saved answers and violations of the in-progress attempt and
+// folds any answer events into its timeline.
+// It returns the submission's new version; on ErrStaleSubmission or ErrSubmissionCompleted
+// the returned version is the current one.
+func (s *submissionService) SaveProgress(ctx context.Context, assessmentID, candidateID string, answers []models.Answer, violations []models.Violation, events []models.AnswerEvent, expectedVersion *int64) (int64, error) {
 	aID, _ := primitive.ObjectIDFromHex(assessmentID)
 	cID, _ := primitive.ObjectIDFromHex(candidateID)
 
-	submission, err := s.repo.FindOne(ctx, bson.M{"assessment_id": aID, "candidate_id": cID})
+	submission, err := s.repo.FindLatestAttempt(ctx, aID, cID)
 	if err != nil {
+		// Deleted attempts keep their numbers, so the new one may not be attempt 1
+		attemptNumber, err := s.repo.NextAttemptNumber(ctx, aID, cID)
+		if err != nil {
+			return 0, err
+		}
+
 		// Fetch user and assessment details for denormalized submission
 		user, _ := s.userRepo.FindByID(ctx, cID)
 		assessment, _ := s.assessmentRepo.FindByID(ctx, aID)
 
 		// Create new in-progress submission
 		submission = &models.Submission{
-			ID:           primitive.NewObjectID(),
-			AssessmentID: aID,
-			CandidateID:  cID,
-			CreatedBy:    cID,
-			CreatedAt:    time.Now(),
-			StartedAt:    time.Now(), // Initialize StartedAt
-			Answers:      answers,
-			Violations:   violations,
-			Status:       "in_progress",
-			UpdatedAt:    time.Now(),
+			ID:            primitive.NewObjectID(),
+			AssessmentID:  aID,
+			CandidateID:   cID,
+			AttemptNumber: attemptNumber,
+			CreatedBy:     cID,
+			CreatedAt:     time.Now(),
+			StartedAt:     time.Now(), // Initialize StartedAt
+			Answers:       answers,
+			Violations:    violations,
+			Status:        "in_progress",
+			UpdatedAt:     time.Now(),
 		}
 		if user != nil {
 			submission.CandidateName = user.Name
@@ -138,15 +190,40 @@ func (s *submissionService) SaveProgress(ctx context.Context, assessmentID, cand
 		if assessment != nil {
 			submission.MinPassingScore = assessment.PassingScore
 		}
+		accommodation := resolveAccommodation(ctx, s.accommRepo, aID, cID)
+		if err := checkAssessmentWindow(assessment, accommodation, time.Now()); err != nil {
+			return 0, err
+		}
+		applyAccommodation(submission, assessment, accommodation)
+		markWaivedViolations(submission.Violations, submission.Accommodation)
+		applyAnswerEvents(submission, events)
+
 		_, err = s.repo.Create(ctx, submission)
-		return err
+		if err == nil {
+			s.advanceInvitation(ctx, aID, cID, models.InvitationStatusStarted, models.InvitationStatusOpened)
+		}
+		return submission.Version, err
+	}
+
+	version, err := checkExpectedVersion(submission, expectedVersion)
+	if err != nil {
+		return version, err
 	}
 
 	if submission.StartedAt.IsZero() {
 		submission.StartedAt = time.Now()
 	}
 
-	submission.Answers = answers
+	applyAnswerEvents(submission, events)
+	if deadlinePassed(submission, time.Now()) {
+		return submission.Version, s.autoSubmit(ctx, submission, violations)
+	}
+
+	if submission.Adaptive == nil {
+		// Adaptive answers are recorded item by item on the server
+		submission.Answers = answers
+	}
+	markWaivedViolations(violations, submission.Accommodation)
 	if violations != nil {
 		// Preserve existing video URLs if they were already updated by AddVideoEvidence
 		// This prevents the "auto-save" from overwriting the URL back to base64
@@ -168,18 +245,65 @@ func (s *submissionService) SaveProgress(ctx context.Context, assessmentID, cand
 		submission.Violations = violations
 	}
 	submission.UpdatedAt = time.Now()
-	return s.repo.Update(ctx, submission.ID, submission)
+
+	updated, err := s.repo.UpdateIfVersion(ctx, submission, version)
+	if err != nil {
+		return version, err
+	}
+	if !updated {
+		return s.rejectedWrite(ctx, submission.ID)
+	}
+	return submission.Version, nil
+}
+
+// SaveAnswer upserts a single answer on the in-progress attempt without resending the
+// rest of the submission. Versioning behaves as in SaveProgress.
+func (s *submissionService) SaveAnswer(ctx context.Context, assessmentID, candidateID string, answer models.Answer, expectedVersion *int64) (int64, error) {
+	aID, _ := primitive.ObjectIDFromHex(assessmentID)
+	cID, _ := primitive.ObjectIDFromHex(candidateID)
+
+	submission, err := s.repo.FindLatestAttempt(ctx, aID, cID)
+	if err != nil {
+		// No attempt yet: the first save creates it, as SaveProgress does.
+		return s.SaveProgress(ctx, assessmentID, candidateID, []models.Answer{answer}, nil, nil, nil)
+	}
+
+	version, err := checkExpectedVersion(submission, expectedVersion)
+	if err != nil {
+		return version, err
+	}
+	if submission.Adaptive != nil {
+		return version, ErrAdaptiveAnswerRoute
+	}
+	if deadlinePassed(submission, time.Now()) {
+		return submission.Version, s.autoSubmit(ctx, submission, nil)
+	}
+
+	answer.IsCorrect = false
+	answer.Points = 0
+	updated, err := s.repo.UpsertAnswer(ctx, submission.ID, version, answer)
+	if err != nil {
+		return version, err
+	}
+	if !updated {
+		return s.rejectedWrite(ctx, submission.ID)
+	}
+	return version + 1, nil
 }
 
-func (s *submissionService) SubmitAssessment(ctx context.Context, assessmentID, candidateID string, answers []models.Answer, violations []models.Violation, faceSnapshots *models.FaceSnapshots) (*models.Submission, error) {
+func (s *submissionService) SubmitAssessment(ctx context.Context, assessmentID, candidateID string, answers []models.Answer, violations []models.Violation, events []models.AnswerEvent, faceSnapshots *models.FaceSnapshots) (*models.Submission, error) {
 	aID, _ := primitive.ObjectIDFromHex(assessmentID)
 	cID, _ := primitive.ObjectIDFromHex(candidateID)
 
-	submission, err := s.repo.FindOne(ctx, bson.M{"assessment_id": aID, "candidate_id": cID})
+	submission, err := s.repo.FindLatestAttempt(ctx, aID, cID)
 	if err != nil {
 		s.auditService.RecordAction(ctx, cID, "", "SUBMIT_ASSESSMENT", "SUBMISSION", primitive.NilObjectID, "ERROR", "Submission doc not found", err.Error(), nil)
 		return nil, errors.New("submission not found")
 	}
+	if submission.Status == "completed" {
+		// Repeated submits (e.g. the client's own submit after a server auto-submit) get the recorded result.
+		return submission, nil
+	}
 
 	// Use denormalized PassingScore if available, otherwise fetch assessment
 	passingScore := submission.MinPassingScore
@@ -191,27 +315,24 @@ func (s *submissionService) SubmitAssessment(ctx context.Context, assessmentID,
 		passingScore = assessment.PassingScore
 	}
 
-	// Calculate Score using the dynamically generated questions locked to this submission
-	totalScore := 0
-	questionMap := make(map[string]models.Question)
-	for _, q := range submission.GeneratedQuestions {
-		questionMap[q.ID.Hex()] = q
+	if submission.Adaptive != nil {
+		// Ending an adaptive attempt early scores the items answered so far
+		answers = submission.Answers
 	}
-
-	for i := range answers {
-		q, ok := questionMap[answers[i].QuestionID.Hex()]
-		if ok {
-			if q.Type == models.MultipleChoice && q.CorrectAnswer == answers[i].Value {
-				answers[i].IsCorrect = true
-				answers[i].Points = q.Points
-				totalScore += q.Points
-			} else {
-				answers[i].IsCorrect = false
-				answers[i].Points = 0
-			}
-		}
+	// A submit after the deadline and grace period is accepted but flagged, and like an
+	// auto-submit it only grades the answers saved in time; late answers and their
+	// events are discarded
+	late := deadlinePassed(submission, time.Now())
+	if late {
+		answers = submission.Answers
+		events = nil
 	}
 
+	// Calculate Score using the dynamically generated questions locked to this submission
+	totalScore := gradeAnswers(submission.GeneratedQuestions, answers)
+	if submission.Adaptive != nil {
+		totalScore = finishAdaptive(submission.Adaptive, models.AdaptiveStopSubmitted)
+	}
 	passed := totalScore >= passingScore
 
 	if submission.StartedAt.IsZero() {
@@ -219,9 +340,12 @@ func (s *submissionService) SubmitAssessment(ctx context.Context, assessmentID,
 	}
 
 	submission.Answers = answers
+	markWaivedViolations(violations, submission.Accommodation)
 	if violations != nil {
 		submission.Violations = violations
 	}
+	applyAnswerEvents(submission, events)
+	submission.SubmittedLate = late
 	if faceSnapshots != nil {
 		submission.FaceSnapshots = faceSnapshots
 
@@ -274,16 +398,112 @@ func (s *submissionService) SubmitAssessment(ctx context.Context, assessmentID,
 	submission.SubmittedAt = time.Now()
 	submission.UpdatedAt = time.Now()
 
-	err = s.repo.Update(ctx, submission.ID, submission)
+	err = s.finalizeSubmission(ctx, submission)
+	if errors.Is(err, ErrSubmissionCompleted) {
+		// Another request completed the attempt first; return what was recorded.
+		return s.repo.FindByID(ctx, submission.ID)
+	}
 	if err != nil {
 		fmt.Printf("[CRITICAL ERROR] SubmitAssessment update failed: %v\n", err)
 		s.auditService.RecordAction(ctx, cID, submission.CandidateEmail, "SUBMIT_ASSESSMENT", "SUBMISSION", submission.ID, "ERROR", "Final DB update failed", err.Error(), nil)
 	} else {
 		s.auditService.RecordAction(ctx, cID, submission.CandidateEmail, "SUBMIT_ASSESSMENT", "SUBMISSION", submission.ID, "SUCCESS", "Assessment submitted successfully", "", nil)
+		s.advanceInvitation(ctx, aID, cID, models.InvitationStatusCompleted, models.InvitationStatusOpened, models.InvitationStatusStarted)
 	}
 	return submission, err
 }
 
+// finalizeSubmission writes the completed submission. A progress save racing the final
+// submit only bumps the version, so the write is retried against the fresh version: the
+// submitted answers are authoritative.
+func (s *submissionService) finalizeSubmission(ctx context.Context, submission *models.Submission) error {
+	expected := submission.Version
+	for attempt := 0; attempt < 3; attempt++ {
+		updated, err := s.repo.UpdateIfVersion(ctx, submission, expected)
+		if err != nil || updated {
+			return err
+		}
+		if expected, err = s.rejectedWrite(ctx, submission.ID); !errors.Is(err, ErrStaleSubmission) {
+			return err
+		}
+	}
+	return ErrStaleSubmission
+}
+
+// gradeAnswers scores answers against the question set locked to the submission,
+// filling in IsCorrect and Points on each answer.
+func gradeAnswers(questions []models.Question, answers []models.Answer) int {
+	totalScore := 0
+	questionMap := make(map[string]models.Question)
+	for _, q := range questions {
+		questionMap[q.ID.Hex()] = q
+	}
+
+	for i := range answers {
+		q, ok := questionMap[answers[i].QuestionID.Hex()]
+		if ok {
+			if q.Type == models.MultipleChoice && q.CorrectAnswer == answers[i].Value {
+				answers[i].IsCorrect = true
+				answers[i].Points = q.Points
+				totalScore += q.Points
+			} else {
+				answers[i].IsCorrect = false
+				answers[i].Points = 0
+			}
+		}
+	}
+
+	return totalScore
+}
+
+// autoSubmit closes an attempt whose deadline has passed, grading the last saved
+// answers as of the deadline. Answers sent after the deadline are discarded; only late
+// violations are kept. It always returns ErrDeadlinePassed unless the update fails.
+func (s *submissionService) autoSubmit(ctx context.Context, submission *models.Submission, violations []models.Violation) error {
+	markWaivedViolations(violations, submission.Accommodation)
+	if violations != nil {
+		submission.Violations = violations
+	}
+
+	submission.Score = gradeAnswers(submission.GeneratedQuestions, submission.Answers)
+	if submission.Adaptive != nil {
+		submission.Score = finishAdaptive(submission.Adaptive, models.AdaptiveStopSubmitted)
+	}
+	submission.Passed = submission.Score >= submission.MinPassingScore
+	submission.Status = "completed"
+	submission.AutoSubmitted = true
+	submission.SubmittedAt = *submission.DeadlineAt
+	submission.UpdatedAt = time.Now()
+
+	if err := s.finalizeSubmission(ctx, submission); err != nil {
+		if errors.Is(err, ErrSubmissionCompleted) {
+			return ErrDeadlinePassed
+		}
+		return err
+	}
+
+	s.auditService.RecordAction(ctx, submission.CandidateID, submission.CandidateEmail, "AUTO_SUBMIT_ASSESSMENT", "SUBMISSION", submission.ID, "SUCCESS", "Attempt auto-submitted after deadline", "", nil)
+	s.advanceInvitation(ctx, submission.AssessmentID, submission.CandidateID, models.InvitationStatusCompleted, models.InvitationStatusOpened, models.InvitationStatusStarted)
+	return ErrDeadlinePassed
+}
+
+// ApplyCandidateTiming replaces the assessment's duration with the candidate's effective
+// duration so the client timer and auto-submit honour any accommodation.
+func (s *submissionService) ApplyCandidateTiming(ctx context.Context, assessment *models.Assessment, candidateID string) {
+	cID, _ := primitive.ObjectIDFromHex(candidateID)
+
+	submission, err := s.repo.FindLatestAttempt(ctx, assessment.ID, cID)
+	if err != nil {
+		return
+	}
+
+	assessment.DeadlineAt = submission.DeadlineAt
+	if submission.Accommodation != nil {
+		assessment.Duration = submission.Accommodation.EffectiveDuration
+		assessment.WaivedChecks = submission.Accommodation.DisabledProctoringChecks
+	}
+}
+
 func (s *submissionService) GetSubmissionsByCandidate(ctx context.Context, candidateID string) ([]models.Submission, error) {
 	cID, err := primitive.ObjectIDFromHex(candidateID)
 	if err != nil {
@@ -322,31 +542,38 @@ func (s *submissionService) GetSubmissionsByInterviewer(ctx context.Context, int
 		return nil, err
 	}
 
-	// 3. Deduplicate by candidate_id and assessment_id
-	seen := make(map[string]bool)
-	var uniqueSubs []models.Submission
+	// 3. Keep the counted attempt per candidate, using each assessment's retake policy
+	subsByAssessment := make(map[primitive.ObjectID][]models.Submission)
 	for _, sub := range subs {
-		key := fmt.Sprintf("%s_%s", sub.CandidateID.Hex(), sub.AssessmentID.Hex())
-		if !seen[key] {
-			uniqueSubs = append(uniqueSubs, sub)
-			seen[key] = true
-		}
+		subsByAssessment[sub.AssessmentID] = append(subsByAssessment[sub.AssessmentID], sub)
+	}
+
+	countedSubs := make([]models.Submission, 0, len(subs))
+	for _, a := range assessments {
+		countedSubs = append(countedSubs, selectCountedAttempts(subsByAssessment[a.ID], effectiveRetakePolicy(&a))...)
 	}
-	return uniqueSubs, nil
+	sort.SliceStable(countedSubs, func(i, j int) bool {
+		return countedSubs[i].SubmittedAt.After(countedSubs[j].SubmittedAt)
+	})
+	return countedSubs, nil
 }
 
 func (s *submissionService) GetOrGenerateQuestions(ctx context.Context, assessmentID, candidateID string) ([]models.Question, error) {
 	aID, _ := primitive.ObjectIDFromHex(assessmentID)
 	cID, _ := primitive.ObjectIDFromHex(candidateID)
 
-	// 1. Check if submission already exists
-	submission, err := s.repo.FindOne(ctx, bson.M{"assessment_id": aID, "candidate_id": cID})
+	// 1. Check if submission already exists (latest attempt)
+	submission, err := s.repo.FindLatestAttempt(ctx, aID, cID)
 
 	// 2. Fetch assessment rules
 	assessment, err := s.assessmentRepo.FindByID(ctx, aID)
 	if err != nil {
 		return nil, errors.New("assessment not found")
 	}
+	if isAdaptive(assessment) {
+		// Adaptive items are delivered one at a time by NextAdaptiveQuestion
+		return []models.Question{}, nil
+	}
 
 	// Reuse the locked question set unless the assessment changed before the candidate started.
 	if shouldReuseGeneratedQuestions(submission, assessment) {
@@ -361,11 +588,17 @@ func (s *submissionService) GetOrGenerateQuestions(ctx context.Context, assessme
 
 	// 4. Save/Update Submission with Locked Questions
 	if submission == nil {
+		attemptNumber, err := s.repo.NextAttemptNumber(ctx, aID, cID)
+		if err != nil {
+			return nil, err
+		}
+
 		// Create placeholder submission to lock questions
 		user, _ := s.userRepo.FindByID(ctx, cID)
 		submission = &models.Submission{
 			AssessmentID:           aID,
 			CandidateID:            cID,
+			AttemptNumber:          attemptNumber,
 			GeneratedQuestions:     generatedQuestions,
 			QuestionSetGeneratedAt: time.Now(),
 			QuestionSetVersion:     assessment.UpdatedAt,
@@ -383,7 +616,16 @@ func (s *submissionService) GetOrGenerateQuestions(ctx context.Context, assessme
 		if assessment != nil {
 			submission.MinPassingScore = assessment.PassingScore
 		}
+		accommodation := resolveAccommodation(ctx, s.accommRepo, aID, cID)
+		if err := checkAssessmentWindow(assessment, accommodation, time.Now()); err != nil {
+			return nil, err
+		}
+		applyAccommodation(submission, assessment, accommodation)
+
 		_, err = s.repo.Create(ctx, submission)
+		if err == nil {
+			s.advanceInvitation(ctx, aID, cID, models.InvitationStatusStarted, models.InvitationStatusOpened)
+		}
 	} else {
 		submission.GeneratedQuestions = generatedQuestions
 		submission.QuestionSetGeneratedAt = time.Now()
@@ -391,6 +633,9 @@ func (s *submissionService) GetOrGenerateQuestions(ctx context.Context, assessme
 		if submission.StartedAt.IsZero() {
 			submission.StartedAt = time.Now()
 		}
+		if submission.DeadlineAt == nil {
+			applyAccommodation(submission, assessment, resolveAccommodation(ctx, s.accommRepo, aID, cID))
+		}
 		submission.UpdatedAt = time.Now()
 		err = s.repo.Update(ctx, submission.ID, submission)
 	}
@@ -401,3 +646,124 @@ func (s *submissionService) GetOrGenerateQuestions(ctx context.Context, assessme
 
 	return generatedQuestions, nil
 }
+
+// StartRetake opens a new attempt with a freshly sampled question set, provided the
+// assessment's retake policy allows it. Earlier attempts are kept untouched.
+func (s *submissionService) StartRetake(ctx context.Context, assessmentID, candidateID string) (*models.Submission, error) {
+	aID, _ := primitive.ObjectIDFromHex(assessmentID)
+	cID, _ := primitive.ObjectIDFromHex(candidateID)
+
+	assessment, err := s.assessmentRepo.FindByID(ctx, aID)
+	if err != nil || assessment.DeletedAt != nil {
+		return nil, errors.New("assessment not found")
+	}
+
+	latest, err := s.repo.FindLatestAttempt(ctx, aID, cID)
+	if err != nil {
+		// No earlier attempt: the regular start flow creates attempt 1
+		return nil, errors.New("no previous attempt to retake")
+	}
+
+	attemptsUsed, err := s.repo.CountAttempts(ctx, aID, cID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := checkRetakeEligibility(effectiveRetakePolicy(assessment), latest, attemptsUsed, time.Now()); err != nil {
+		return nil, err
+	}
+	attemptNumber, err := s.repo.NextAttemptNumber(ctx, aID, cID)
+	if err != nil {
+		return nil, err
+	}
+
+	accommodation := resolveAccommodation(ctx, s.accommRepo, aID, cID)
+	if err := checkAssessmentWindow(assessment, accommodation, time.Now()); err != nil {
+		return nil, err
+	}
+
+	var generatedQuestions []models.Question
+	if !isAdaptive(assessment) {
+		generatedQuestions, err = sampleQuestionsForRules(ctx, s.qbRepo, assessment.QuestionRules)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sample questions: %v", err)
+		}
+	}
+
+	now := time.Now()
+	submission := &models.Submission{
+		AssessmentID:           aID,
+		CandidateID:            cID,
+		CandidateName:          latest.CandidateName,
+		CandidateEmail:         latest.CandidateEmail,
+		CandidatePhone:         latest.CandidatePhone,
+		IsDemo:                 latest.IsDemo,
+		AttemptNumber:          attemptNumber,
+		GeneratedQuestions:     generatedQuestions,
+		QuestionSetGeneratedAt: now,
+		QuestionSetVersion:     assessment.UpdatedAt,
+		MinPassingScore:        assessment.PassingScore,
+		Status:                 "in_progress",
+		CreatedBy:              cID,
+		CreatedAt:              now,
+		StartedAt:              now,
+		UpdatedAt:              now,
+	}
+	if isAdaptive(assessment) {
+		submission.Adaptive = newAdaptiveState()
+	}
+	applyAccommodation(submission, assessment, accommodation)
+
+	// The unique (assessment, candidate, attempt) index rejects concurrent retakes of the same attempt
+	if _, err := s.repo.Create(ctx, submission); err != nil {
+		return nil, err
+	}
+
+	s.auditService.RecordAction(ctx, cID, submission.CandidateEmail, "START_RETAKE", "SUBMISSION", submission.ID, "SUCCESS", fmt.Sprintf("Attempt %d started", submission.AttemptNumber), "", nil)
+	return submission, nil
+}
+
+func (s *submissionService) GetAttemptHistory(ctx context.Context, assessmentID, candidateID string) (*models.AttemptHistory, error) {
+	aID, _ := primitive.ObjectIDFromHex(assessmentID)
+	cID, _ := primitive.ObjectIDFromHex(candidateID)
+
+	assessment, err := s.assessmentRepo.FindByID(ctx, aID)
+	if err != nil || assessment.DeletedAt != nil {
+		return nil, errors.New("assessment not found")
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "attempt_number", Value: 1}, {Key: "created_at", Value: 1}}).
+		SetProjection(bson.M{"generated_questions": 0, "shuffled_options": 0, "face_snapshots": 0})
+	attempts, err := s.repo.FindAll(ctx, bson.M{"assessment_id": aID, "candidate_id": cID, "deleted_at": nil}, opts)
+	if err != nil {
+		return nil, err
+	}
+	if attempts == nil {
+		attempts = []models.Submission{}
+	}
+
+	policy := effectiveRetakePolicy(assessment)
+	history := &models.AttemptHistory{
+		Policy:            policy,
+		Attempts:          attempts,
+		AttemptsRemaining: policy.MaxAttempts,
+	}
+
+	if len(attempts) > 0 {
+		latest := attempts[len(attempts)-1]
+		history.AttemptsRemaining = policy.MaxAttempts - len(attempts)
+		if history.AttemptsRemaining < 0 {
+			history.AttemptsRemaining = 0
+		}
+
+		nextAttemptAt, err := checkRetakeEligibility(policy, &latest, len(attempts), time.Now())
+		history.CanRetake = err == nil
+		history.NextAttemptAt = nextAttemptAt
+
+		if counted := selectCountedAttempts(attempts, policy); len(counted) > 0 {
+			history.CountedAttempt = attemptNumberOf(counted[0])
+		}
+	}
+
+	return history, nil
+}
@@ -0,0 +1,266 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ErrExpressionUndefined is returned when a well-formed formula has no finite value for
+// the given variables, e.g. on division by zero.
+var ErrExpressionUndefined = errors.New("formula is undefined for these values")
+
+// EvalExpression evaluates an arithmetic formula such as "a * (b + 2) / 3" against the
+// given variables. It supports + - * / % ^, comparisons (yielding 1 or 0), parentheses
+// and the functions sqrt, abs, round, floor, ceil, min, max and pow.
+func EvalExpression(expr string, vars map[string]float64) (float64, error) {
+	p := &expressionParser{input: expr, vars: vars}
+	p.next()
+	value, err := p.comparison()
+	if err != nil {
+		return 0, err
+	}
+	if p.token != "" {
+		return 0, fmt.Errorf("unexpected %q in %q", p.token, expr)
+	}
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, ErrExpressionUndefined
+	}
+	return value, nil
+}
+
+type expressionParser struct {
+	input string
+	pos   int
+	token string
+	vars  map[string]float64
+}
+
+// next advances to the following token; an empty token marks the end of input.
+func (p *expressionParser) next() {
+	for p.pos < len(p.input) && unicode.IsSpace(rune(p.input[p.pos])) {
+		p.pos++
+	}
+	if p.pos >= len(p.input) {
+		p.token = ""
+		return
+	}
+
+	start := p.pos
+	c := rune(p.input[p.pos])
+	switch {
+	case unicode.IsDigit(c) || c == '.':
+		for p.pos < len(p.input) && (unicode.IsDigit(rune(p.input[p.pos])) || p.input[p.pos] == '.') {
+			p.pos++
+		}
+	case unicode.IsLetter(c) || c == '_':
+		for p.pos < len(p.input) && (unicode.IsLetter(rune(p.input[p.pos])) || unicode.IsDigit(rune(p.input[p.pos])) || p.input[p.pos] == '_') {
+			p.pos++
+		}
+	default:
+		p.pos++
+		if p.pos < len(p.input) && p.input[p.pos] == '=' && strings.ContainsRune("<>=!", c) {
+			p.pos++
+		}
+	}
+	p.token = p.input[start:p.pos]
+}
+
+func (p *expressionParser) comparison() (float64, error) {
+	left, err := p.additive()
+	if err != nil {
+		return 0, err
+	}
+
+	op := p.token
+	switch op {
+	case "==", "!=", "<", "<=", ">", ">=":
+	default:
+		return left, nil
+	}
+	p.next()
+	right, err := p.additive()
+	if err != nil {
+		return 0, err
+	}
+
+	var result bool
+	switch op {
+	case "==":
+		result = math.Abs(left-right) < 1e-9
+	case "!=":
+		result = math.Abs(left-right) >= 1e-9
+	case "<":
+		result = left < right
+	case "<=":
+		result = left <= right
+	case ">":
+		result = left > right
+	case ">=":
+		result = left >= right
+	}
+	if result {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+func (p *expressionParser) additive() (float64, error) {
+	value, err := p.multiplicative()
+	if err != nil {
+		return 0, err
+	}
+	for p.token == "+" || p.token == "-" {
+		op := p.token
+		p.next()
+		right, err := p.multiplicative()
+		if err != nil {
+			return 0, err
+		}
+		if op == "+" {
+			value += right
+		} else {
+			value -= right
+		}
+	}
+	return value, nil
+}
+
+func (p *expressionParser) multiplicative() (float64, error) {
+	value, err := p.unary()
+	if err != nil {
+		return 0, err
+	}
+	for p.token == "*" || p.token == "/" || p.token == "%" {
+		op := p.token
+		p.next()
+		right, err := p.unary()
+		if err != nil {
+			return 0, err
+		}
+		if op != "*" && right == 0 {
+			return 0, ErrExpressionUndefined
+		}
+		switch op {
+		case "*":
+			value *= right
+		case "/":
+			value /= right
+		case "%":
+			value = math.Mod(value, right)
+		}
+	}
+	return value, nil
+}
+
+func (p *expressionParser) unary() (float64, error) {
+	if p.token == "-" {
+		p.next()
+		value, err := p.unary()
+		return -value, err
+	}
+	return p.power()
+}
+
+// power is right-associative and binds tighter than unary minus on its left, so -2^2 is -4.
+func (p *expressionParser) power() (float64, error) {
+	base, err := p.primary()
+	if err != nil {
+		return 0, err
+	}
+	if p.token != "^" {
+		return base, nil
+	}
+	p.next()
+	exponent, err := p.unary()
+	if err != nil {
+		return 0, err
+	}
+	return math.Pow(base, exponent), nil
+}
+
+func (p *expressionParser) primary() (float64, error) {
+	token := p.token
+	switch {
+	case token == "":
+		return 0, errors.New("unexpected end of formula")
+	case token == "(":
+		p.next()
+		value, err := p.comparison()
+		if err != nil {
+			return 0, err
+		}
+		if p.token != ")" {
+			return 0, errors.New("missing closing parenthesis")
+		}
+		p.next()
+		return value, nil
+	case unicode.IsDigit(rune(token[0])) || token[0] == '.':
+		p.next()
+		value, err := strconv.ParseFloat(token, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid number %q", token)
+		}
+		return value, nil
+	case unicode.IsLetter(rune(token[0])) || token[0] == '_':
+		p.next()
+		if p.token == "(" {
+			return p.call(token)
+		}
+		value, ok := p.vars[token]
+		if !ok {
+			return 0, fmt.Errorf("unknown variable %q", token)
+		}
+		return value, nil
+	}
+	return 0, fmt.Errorf("unexpected %q", token)
+}
+
+func (p *expressionParser) call(name string) (float64, error) {
+	p.next() // consume "("
+	var args []float64
+	for p.token != ")" {
+		value, err := p.comparison()
+		if err != nil {
+			return 0, err
+		}
+		args = append(args, value)
+		if p.token == "," {
+			p.next()
+		} else if p.token != ")" {
+			return 0, fmt.Errorf("expected , or ) in call to %s", name)
+		}
+	}
+	p.next()
+
+	arity := map[string]int{"sqrt": 1, "abs": 1, "round": 1, "floor": 1, "ceil": 1, "min": 2, "max": 2, "pow": 2}
+	want, ok := arity[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown function %q", name)
+	}
+	if len(args) != want {
+		return 0, fmt.Errorf("%s takes %d argument(s)", name, want)
+	}
+
+	switch name {
+	case "sqrt":
+		return math.Sqrt(args[0]), nil
+	case "abs":
+		return math.Abs(args[0]), nil
+	case "round":
+		return math.Round(args[0]), nil
+	case "floor":
+		return math.Floor(args[0]), nil
+	case "ceil":
+		return math.Ceil(args[0]), nil
+	case "min":
+		return math.Min(args[0], args[1]), nil
+	case "max":
+		return math.Max(args[0], args[1]), nil
+	default:
+		return math.Pow(args[0], args[1]), nil
+	}
+}
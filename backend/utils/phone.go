@@ -16,3 +16,8 @@ func NormalizePhone(input string) string {
 		return cleaned
 	}
 }
+
+// IsValidPhone reports whether the input normalizes to a 10-digit mobile number
+func IsValidPhone(input string) bool {
+	return len(NormalizePhone(input)) == 10
+}
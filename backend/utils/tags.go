@@ -0,0 +1,44 @@
+package utils
+
+import "strings"
+
+func normalizeTag(tag string) string {
+	return strings.Join(strings.Fields(strings.ToLower(tag)), " ")
+}
+
+// NormalizeTags lowercases tags, collapses their whitespace and drops empty and repeated
+// ones, keeping the first occurrence's position.
+func NormalizeTags(tags []string) []string {
+	var normalized []string
+	seen := map[string]bool{}
+	for _, tag := range tags {
+		tag = normalizeTag(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
+
+// NormalizeSkillPath normalises each "/"-separated segment of a skill path like a tag,
+// e.g. " Go / Concurrency " becomes "go/concurrency".
+func NormalizeSkillPath(path string) string {
+	var segments []string
+	for _, segment := range strings.Split(path, "/") {
+		if segment = normalizeTag(segment); segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// NormalizeSkillPaths normalises skill paths and drops empty and repeated ones.
+func NormalizeSkillPaths(paths []string) []string {
+	normalized := make([]string, len(paths))
+	for i, path := range paths {
+		normalized[i] = NormalizeSkillPath(path)
+	}
+	return NormalizeTags(normalized)
+}
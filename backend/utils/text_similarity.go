@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+)
+
+// NormalizeText lowercases text and reduces it to words separated by single spaces, so
+// punctuation, casing and whitespace differences do not matter when comparing.
+func NormalizeText(text string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+// Shingles returns the set of k-character sequences of the normalised text. Character
+// shingles tolerate the small rewordings of short texts better than word shingles do.
+// Texts shorter than k characters yield a single shingle of the whole text.
+func Shingles(text string, k int) map[string]struct{} {
+	runes := []rune(NormalizeText(text))
+	shingles := map[string]struct{}{}
+	if len(runes) == 0 {
+		return shingles
+	}
+	if len(runes) < k {
+		shingles[string(runes)] = struct{}{}
+		return shingles
+	}
+	for i := 0; i+k <= len(runes); i++ {
+		shingles[string(runes[i:i+k])] = struct{}{}
+	}
+	return shingles
+}
+
+// JaccardSimilarity is the size of the intersection over the size of the union, from 0
+// for disjoint sets to 1 for equal ones.
+func JaccardSimilarity(a, b map[string]struct{}) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	shared := 0
+	for shingle := range a {
+		if _, ok := b[shingle]; ok {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}